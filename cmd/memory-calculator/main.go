@@ -16,6 +16,9 @@
 //
 //	memory-calculator --total-memory 2G --thread-count 300
 //	memory-calculator --quiet  # outputs only JVM arguments
+//	memory-calculator size-container --heap 3G  # container memory for a target heap
+//	memory-calculator sweep --from 256M --to 8G --step 256M  # calculation across container sizes
+//	memory-calculator count-classes --write /app/.memory-calculator.json  # class counts at build time
 //
 // The calculator automatically detects available memory using this priority:
 //  1. Container cgroups v2: /sys/fs/cgroup/memory.max
@@ -24,9 +27,9 @@
 //
 // Memory allocation algorithm:
 //  1. Head room reservation (configurable percentage)
-//  2. Thread stacks (threads × 1MB each)
+//  2. Thread stacks (platform threads × 1MB each, virtual thread stacks live on the heap)
 //  3. Metaspace (loaded classes × 8KB each)
-//  4. Code cache (240MB for JIT compilation)
+//  4. Code cache (240MB for tiered JIT compilation, scaled by compilation mode and class count)
 //  5. Direct memory (10MB for NIO operations)
 //  6. Heap memory (remaining available memory)
 //
@@ -40,12 +43,17 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
 	"github.com/patbaumgartner/memory-calculator/internal/calculator"
 	"github.com/patbaumgartner/memory-calculator/internal/config"
+	"github.com/patbaumgartner/memory-calculator/internal/count"
 	"github.com/patbaumgartner/memory-calculator/internal/display"
+	"github.com/patbaumgartner/memory-calculator/internal/memory"
 	"github.com/patbaumgartner/memory-calculator/pkg/errors"
 )
 
@@ -62,13 +70,26 @@ func main() {
 	cfg.BuildTime = buildTime
 	cfg.CommitHash = commitHash
 
+	// Dispatch subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "size-container":
+			runSizeContainer(cfg, os.Args[2:])
+			return
+		case "sweep":
+			runSweep(cfg, os.Args[2:])
+			return
+		case "count-classes":
+			runCountClasses(cfg, os.Args[2:])
+			return
+		}
+	}
+
 	// Parse command line flags
 	flag.StringVar(&cfg.TotalMemory, "total-memory", "", "Total memory (e.g., 2G, 512M, 1024MB, 2147483648)")
-	flag.StringVar(&cfg.ThreadCount, "thread-count", cfg.ThreadCount, "JVM thread count")
-	flag.StringVar(&cfg.LoadedClassCount, "loaded-class-count", cfg.LoadedClassCount, "JVM loaded class count")
-	flag.StringVar(&cfg.HeadRoom, "head-room", cfg.HeadRoom, "JVM head room percentage")
-	flag.StringVar(&cfg.Path, "path", cfg.Path, "Application path for JAR scanning and class counting")
-	flag.BoolVar(&cfg.Quiet, "quiet", false, "Only output JVM parameters, no formatting")
+	registerCalculationFlags(flag.CommandLine, cfg)
+	flag.BoolVar(&cfg.Degrade, "degrade", cfg.Degrade,
+		"Shrink code cache, thread count and metaspace margin to fit a minimum heap instead of failing")
 	flag.BoolVar(&cfg.Version, "version", false, "Show version information")
 	flag.BoolVar(&cfg.Help, "help", false, "Show help")
 
@@ -86,41 +107,251 @@ func main() {
 		return
 	}
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
+	prepareEnvironment(flag.CommandLine, cfg)
+
+	// Set total memory if specified
+	if cfg.TotalMemory != "" {
+		_ = os.Setenv("BPL_JVM_TOTAL_MEMORY", cfg.TotalMemory)
+	}
+
+	// Execute memory calculator
+	mc := calculator.Create(cfg.Quiet)
+	result, err := mc.Calculate()
+	if err != nil {
+		handleError(cfg.Quiet, "Memory calculation failed", err)
+	}
+
+	// Display results
+	displayResults(formatter, result, cfg)
+}
+
+// runSizeContainer calculates the container memory required for a target heap
+func runSizeContainer(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("size-container", flag.ExitOnError)
+	heap := fs.String("heap", "", "Target heap size (e.g., 3G, 512M)")
+	registerCalculationFlags(fs, cfg)
+	_ = fs.Parse(args)
+
+	heapSize, err := memory.CreateParser().ParseMemoryString(*heap)
+	if err != nil {
+		if !cfg.Quiet {
+			log.Printf("Configuration error: %v", errors.NewConfigurationError("heap", *heap, "must be a memory size"))
+		}
+		os.Exit(1)
+	}
+
+	prepareEnvironment(fs, cfg)
+
+	mc := calculator.Create(cfg.Quiet)
+	size, err := mc.SizeContainer(calc.Size{Value: heapSize})
+	if err != nil {
+		handleError(cfg.Quiet, "Container sizing failed", err)
+	}
+
+	formatter := display.CreateFormatter()
+	if cfg.Quiet {
+		formatter.DisplayQuietContainerSize(size)
+	} else {
+		formatter.DisplayContainerSize(size)
+	}
+}
+
+// runSweep runs the calculation for a range or list of total memory sizes
+func runSweep(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	from := fs.String("from", "", "Smallest total memory of the range (e.g., 256M)")
+	to := fs.String("to", "", "Largest total memory of the range (e.g., 8G)")
+	step := fs.String("step", "256M", "Step between total memory sizes of the range")
+	list := fs.String("sizes", "", "Comma-separated total memory sizes instead of a range (e.g., 512M,1G,2G)")
+	minHeap := fs.String("min-heap", "64M", "Heap size below which a total memory size is marked")
+	registerCalculationFlags(fs, cfg)
+	fs.BoolVar(&cfg.Degrade, "degrade", cfg.Degrade,
+		"Shrink code cache, thread count and metaspace margin to fit a minimum heap instead of failing")
+	_ = fs.Parse(args)
+
+	sizes, threshold, err := sweepSizes(*from, *to, *step, *list, *minHeap)
+	if err != nil {
 		if !cfg.Quiet {
 			log.Printf("Configuration error: %v", err)
 		}
 		os.Exit(1)
 	}
 
-	// Set environment variables for memory calculator
-	cfg.SetEnvironmentVariables()
+	prepareEnvironment(fs, cfg)
 
-	// Set total memory if specified
-	if cfg.TotalMemory != "" {
-		_ = os.Setenv("BPL_JVM_TOTAL_MEMORY", cfg.TotalMemory)
+	mc := calculator.Create(cfg.Quiet)
+	rows, err := mc.Sweep(sizes, threshold)
+	if err != nil {
+		handleError(cfg.Quiet, "Memory sweep failed", err)
+	}
+
+	formatter := display.CreateFormatter()
+	if cfg.Quiet {
+		formatter.DisplayQuietSweep(rows)
+	} else {
+		formatter.DisplaySweep(rows, threshold)
 	}
+}
 
-	// Set required default environment variables if not already set
-	setDefaultEnvironmentVariables()
+// runCountClasses counts the classes of the application ahead of time, optionally writing them to a lock file
+func runCountClasses(cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("count-classes", flag.ExitOnError)
+	write := fs.String("write", "", "Lock file to record the class counts in (e.g., /app/"+count.LockFile+")")
+	registerCalculationFlags(fs, cfg)
+	_ = fs.Parse(args)
+
+	prepareEnvironment(fs, cfg)
 
-	// Execute memory calculator
 	mc := calculator.Create(cfg.Quiet)
-	props, err := mc.Execute()
+	l, err := mc.CountClasses()
 	if err != nil {
-		handleError(cfg.Quiet, "Memory calculation failed", err)
+		handleError(cfg.Quiet, "Class counting failed", err)
+	}
+	if *write != "" {
+		if err := l.Write(*write); err != nil {
+			handleError(cfg.Quiet, "Class counting failed", err)
+		}
 	}
 
-	// Display results
-	displayResults(formatter, props, cfg)
+	formatter := display.CreateFormatter()
+	if cfg.Quiet {
+		formatter.DisplayQuietClassCounts(l)
+	} else {
+		formatter.DisplayClassCounts(l, *write)
+	}
+}
+
+// sweepSizes parses the total memory sizes and the minimum heap of a sweep
+func sweepSizes(from, to, step, list, minHeap string) ([]calc.Size, calc.Size, error) {
+	p := memory.CreateParser()
+
+	threshold, err := p.ParseMemoryString(minHeap)
+	if err != nil {
+		return nil, calc.Size{}, errors.NewConfigurationError("min-heap", minHeap, "must be a memory size")
+	}
+
+	if list != "" {
+		var sizes []calc.Size
+		for _, s := range strings.Split(list, ",") {
+			v, parseErr := p.ParseMemoryString(strings.TrimSpace(s))
+			if parseErr != nil {
+				return nil, calc.Size{}, errors.NewConfigurationError("sizes", list, "must be comma-separated memory sizes")
+			}
+			sizes = append(sizes, calc.Size{Value: v})
+		}
+		return sizes, calc.Size{Value: threshold}, nil
+	}
+
+	bounds := make([]int64, 3)
+	for i, b := range []struct{ name, value string }{{"from", from}, {"to", to}, {"step", step}} {
+		if bounds[i], err = p.ParseMemoryString(b.value); err != nil {
+			return nil, calc.Size{}, errors.NewConfigurationError(b.name, b.value, "must be a memory size")
+		}
+	}
+
+	sizes, err := calculator.SweepSizes(
+		calc.Size{Value: bounds[0]}, calc.Size{Value: bounds[1]}, calc.Size{Value: bounds[2]})
+	if err != nil {
+		return nil, calc.Size{}, errors.NewConfigurationError(
+			"from/to/step", fmt.Sprintf("%s/%s/%s", from, to, step), err.Error())
+	}
+	return sizes, calc.Size{Value: threshold}, nil
+}
+
+// registerCalculationFlags registers the flags shared by all commands that configure the calculation
+func registerCalculationFlags(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ThreadCount, "thread-count", cfg.ThreadCount, "JVM platform thread count")
+	fs.StringVar(&cfg.VirtualThreadCount, "virtual-thread-count", cfg.VirtualThreadCount,
+		"Expected concurrent virtual threads (Java 21+)")
+	fs.StringVar(&cfg.LoadedClassCount, "loaded-class-count", cfg.LoadedClassCount, "JVM loaded class count")
+	fs.StringVar(&cfg.ClassList, "class-list", cfg.ClassList,
+		"-Xlog:class+load output or -XX:DumpLoadedClassList classlist of a previous run to take the class count from")
+	fs.StringVar(&cfg.ClassListMargin, "class-list-margin", cfg.ClassListMargin,
+		"Percentage added to the classes of the class list (default 10)")
+	fs.StringVar(&cfg.HeadRoom, "head-room", cfg.HeadRoom,
+		"JVM head room as percentage or size (e.g., 10, 300M, 10%,min=128M,max=2G)")
+	fs.StringVar(&cfg.Path, "path", cfg.Path, "Application path for JAR scanning and class counting")
+	fs.StringVar(&cfg.NestedJarDepth, "nested-jar-depth", cfg.NestedJarDepth,
+		"Levels of nested jars to count classes in (default 3)")
+	fs.StringVar(&cfg.ClassCountMode, "class-count-mode", cfg.ClassCountMode,
+		"Count every class file (raw) or each fully-qualified class name once (unique)")
+	fs.StringVar(&cfg.ScanWorkers, "scan-workers", cfg.ScanWorkers,
+		"Number of jars read concurrently when counting classes (default: GOMAXPROCS)")
+	fs.StringVar(&cfg.CachePath, "cache-path", cfg.CachePath,
+		"File caching the class counts of unchanged jars (default: $TMPDIR/"+count.DefaultCacheFile+")")
+	fs.BoolVar(&cfg.CacheHash, "cache-hash", cfg.CacheHash, "Also compare jar content digests to detect changed jars")
+	fs.BoolVar(&cfg.NoCache, "no-cache", cfg.NoCache, "Scan all jars instead of using the class count cache")
+	fs.StringVar(&cfg.LockFile, "lock-file", cfg.LockFile,
+		"Class counts recorded by count-classes --write (default: <path>/"+count.LockFile+")")
+	fs.BoolVar(&cfg.TrustLock, "trust-lock", cfg.TrustLock, "Use the lock file without comparing its fingerprint")
+	fs.StringVar(&cfg.JavaHome, "java-home", cfg.JavaHome, "Java home used to detect the JVM version and vendor")
+	fs.BoolVar(&cfg.SegmentedCodeCache, "segmented-code-cache", cfg.SegmentedCodeCache,
+		"Split the code cache into explicitly sized code heaps (Java 9+)")
+	fs.StringVar(&cfg.DirectMemoryRules, "direct-memory-rules", cfg.DirectMemoryRules,
+		"Direct memory rules for detected libraries (e.g., netty-buffer=15%,min=128M;kafka-clients=64M)")
+	fs.StringVar(&cfg.NativeAgentRules, "native-agent-rules", cfg.NativeAgentRules,
+		"Native memory reserved for detected agents (e.g., dynatrace=256M;my-agent=16M)")
+	fs.StringVar(&cfg.Profile, "profile", cfg.Profile,
+		"Preset of calculation parameters (spring-boot, quarkus, micronaut, batch, low-latency or custom)")
+	fs.StringVar(&cfg.ConfigFile, "config-file", cfg.ConfigFile, "JSON configuration file defining custom profiles")
+	fs.StringVar(&cfg.ClassLoadFactor, "class-load-factor", cfg.ClassLoadFactor,
+		"Share of the counted classes expected to be loaded (default 0.35)")
+	fs.StringVar(&cfg.CodeCacheSize, "code-cache-size", cfg.CodeCacheSize, "Reserved code cache size (e.g., 128M)")
+	fs.StringVar(&cfg.DirectMemorySize, "direct-memory-size", cfg.DirectMemorySize, "Direct memory size (e.g., 64M)")
+	fs.StringVar(&cfg.ClassSize, "class-size", cfg.ClassSize, "Metaspace per loaded class (default 5800)")
+	fs.StringVar(&cfg.ClassOverhead, "class-overhead", cfg.ClassOverhead,
+		"Metaspace of the JVM independent of the loaded classes (default 14000000)")
+	fs.StringVar(&cfg.LanguageWeights, "language-weights", cfg.LanguageWeights,
+		"Classes per counted script entry (e.g., groovy=3,clj=4,kts=2)")
+	fs.StringVar(&cfg.CommandLine, "command-line", cfg.CommandLine,
+		"Java command line of the application whose JVM options are honored (e.g., \"java -Xss512K -jar app.jar\")")
+	fs.StringVar(&cfg.MergePolicy, "merge-policy", cfg.MergePolicy,
+		"Policy for memory flags the user already configured (respect-user, override, fail-on-conflict)")
+	fs.StringVar(&cfg.OutputVariable, "output-variable", cfg.OutputVariable,
+		"Environment variable receiving the calculated options "+
+			"(JAVA_TOOL_OPTIONS, JDK_JAVA_OPTIONS, JAVA_OPTS, _JAVA_OPTIONS)")
+	fs.StringVar(&cfg.InitialHeap, "initial-heap", cfg.InitialHeap,
+		"Initial heap as 'max' or a share of the heap (e.g., 25%, 25%,min=256M)")
+	fs.StringVar(&cfg.JVMOptions, "jvm-options", cfg.JVMOptions,
+		"Additional JVM options (e.g., -XX:+ExitOnOutOfMemoryError)")
+	fs.BoolVar(&cfg.Quiet, "quiet", false, "Only output JVM parameters, no formatting")
 }
 
-// setDefaultEnvironmentVariables sets required default environment variables if not already set
-func setDefaultEnvironmentVariables() {
-	if os.Getenv("BPI_JVM_CLASS_COUNT") == "" {
-		_ = os.Setenv("BPI_JVM_CLASS_COUNT", "1000")
+// prepareEnvironment applies the selected profile and the configuration file, validates the configuration and
+// exports it for the memory calculator. Flags set explicitly on fs take precedence over both.
+func prepareEnvironment(fs *flag.FlagSet, cfg *config.Config) {
+	// Apply the selected profile to the settings that were not configured explicitly
+	p, err := cfg.ResolveProfile()
+	if err != nil {
+		if !cfg.Quiet {
+			log.Printf("Configuration error: %v", errors.NewConfigurationError("profile", cfg.Profile, err.Error()))
+		}
+		os.Exit(1)
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if p != nil {
+		cfg.ApplyProfile(*p, set)
 	}
+
+	// Apply the settings of the configuration file, which take precedence over the profile
+	if err := cfg.ApplyConfigFile(set); err != nil {
+		if !cfg.Quiet {
+			log.Printf("Configuration error: %v", errors.NewConfigurationError("config-file", cfg.ConfigFile, err.Error()))
+		}
+		os.Exit(1)
+	}
+
+	// Validate configuration
+	if err := cfg.Validate(); err != nil {
+		if !cfg.Quiet {
+			log.Printf("Configuration error: %v", err)
+		}
+		os.Exit(1)
+	}
+
+	// Set environment variables for memory calculator
+	cfg.SetEnvironmentVariables()
 }
 
 // handleError handles and logs errors consistently
@@ -133,10 +364,10 @@ func handleError(quiet bool, message string, err error) {
 }
 
 // displayResults displays the calculation results based on quiet flag
-func displayResults(formatter *display.Formatter, props map[string]string, cfg *config.Config) {
+func displayResults(formatter *display.Formatter, result *calculator.Result, cfg *config.Config) {
 	if cfg.Quiet {
-		formatter.DisplayQuietResults(props)
+		formatter.DisplayQuietResults(result.Properties)
 	} else {
-		formatter.DisplayResults(props, 0, cfg) // Let formatter get memory from props
+		formatter.DisplayCalculation(result, cfg)
 	}
 }
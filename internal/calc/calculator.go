@@ -13,13 +13,18 @@
 //   - Head room reservation (configurable safety margin)
 //
 // Memory allocation follows this priority order:
-//  1. Head room (percentage-based reservation)
+//  1. Head room (percentage or absolute size, optionally bounded)
 //  2. Thread stacks (threads × stack size)
-//  3. Metaspace (classes × overhead per class)
-//  4. Code cache (fixed 240MB for optimal JIT performance)
-//  5. Direct memory (fixed 10MB for NIO operations)
+//  3. Metaspace (classes not loaded from CDS archives × overhead per class) and the mapped CDS archives
+//  4. Code cache (240MB for tiered compilation, scaled by compilation mode and class count)
+//  5. Direct memory (10MB for NIO operations, raised for detected off-heap heavy libraries) and the native memory
+//     of detected agents
 //  6. Heap (all remaining memory)
 //
+// In degrade mode, regions that were not configured by the user are shrunk when they leave no
+// room for a minimum heap: first the code cache, then the thread count, then the metaspace
+// safety margin. Every reduction is recorded in MemoryRegions.Reductions.
+//
 // All calculations are performed with 64-bit precision to handle large memory values
 // and ensure accuracy across different deployment scenarios.
 package calc
@@ -60,11 +65,11 @@ const (
 //
 // Memory Allocation Strategy:
 //  1. Parse existing JVM flags to detect overrides
-//  2. Calculate head room reservation (percentage-based)
+//  2. Calculate head room reservation (percentage or absolute size, optionally bounded)
 //  3. Allocate thread stack memory (threads × stack size)
 //  4. Calculate metaspace size (classes × overhead + base)
-//  5. Reserve code cache memory (240MB for JIT optimization)
-//  6. Reserve direct memory (10MB for NIO operations)
+//  5. Reserve code cache memory (sized by compilation mode and loaded class count)
+//  6. Reserve direct memory (10MB, or sized by the rules of detected off-heap heavy libraries)
 //  7. Allocate remaining memory to heap
 //
 // Thread Safety:
@@ -101,6 +106,11 @@ type Calculator struct {
 	// Valid range: 0-99 (percentage). Default: 0 (no head room).
 	HeadRoom int
 
+	// HeadRoomSize specifies the head room as a percentage or an absolute size, optionally bounded by
+	// a minimum and a maximum (see ParseHeadRoom). It takes precedence over HeadRoom when set.
+	// Default: nil (HeadRoom percentage applies).
+	HeadRoomSize *RelativeSize
+
 	// LoadedClassCount represents the estimated number of classes that will be loaded
 	// by the application during runtime. This value is used to calculate the required
 	// metaspace size for storing class metadata. If not specified, the calculator
@@ -108,18 +118,92 @@ type Calculator struct {
 	// Minimum recommended: 1000 classes. Typical range: 10,000-100,000 classes.
 	LoadedClassCount int
 
-	// ThreadCount specifies the expected number of threads the JVM application will create.
-	// This includes both application threads and JVM internal threads. Each thread requires
-	// stack memory allocation (default 1MB per thread on most platforms).
+	// LoadedClassCountProvenance records whether LoadedClassCount was configured by the user, measured in a class
+	// list of a previous run or calculated from the counted classes. Default: Unknown.
+	LoadedClassCountProvenance Provenance
+
+	// ClassSize is the metaspace footprint of a single loaded class.
+	// Default: ClassSize (5,800 bytes) when zero.
+	ClassSize Size
+
+	// SharedClassCount is the number of loaded classes that are loaded from class data sharing (CDS) archives. They
+	// are mapped from the archives instead of being allocated in metaspace. Default: 0 (no CDS archive).
+	SharedClassCount int
+
+	// SharedArchiveSize is the size of the CDS archives mapped into the JVM process. It is reserved next to
+	// metaspace. Default: 0 (no CDS archive).
+	SharedArchiveSize Size
+
+	// ClassOverhead is the metaspace footprint of the JVM itself, independent of the loaded classes.
+	// Default: ClassOverhead (14,000,000 bytes) when zero.
+	ClassOverhead Size
+
+	// ThreadCount specifies the expected number of platform threads the JVM application will create.
+	// This includes application threads, virtual thread carriers and JVM internal threads. Each
+	// platform thread requires native stack memory (default 1MB per thread on most platforms).
 	// Minimum: 1 thread. Typical range: 50-1000 threads depending on application type.
 	ThreadCount int
 
+	// ThreadCountProvenance records whether ThreadCount was configured by the user. Degrade mode
+	// only reduces a thread count that was not. Default: Unknown (treated as not user configured).
+	ThreadCountProvenance Provenance
+
+	// Degrade enables degrade mode: when the regions leave no room for MinHeap, regions that were
+	// not configured by the user are shrunk in a fixed order (see degrade) instead of failing.
+	// Default: false.
+	Degrade bool
+
+	// MinHeap is the smallest heap degrade mode makes room for.
+	// Default: DefaultMinHeap (64M) when zero.
+	MinHeap Size
+
+	// VirtualThreadCount specifies the expected number of concurrently live virtual threads (Java 21+).
+	// Virtual thread stacks live on the heap, so they are reserved inside the heap rather than in
+	// the native stack region. Default: 0 (no virtual threads).
+	VirtualThreadCount int
+
+	// VirtualThreadStack is the estimated heap footprint of a single virtual thread stack.
+	// Default: DefaultVirtualThreadStack (16K) when zero.
+	VirtualThreadStack Size
+
+	// SegmentedCodeCache requests the reserved code cache to be split into explicitly sized
+	// non-nmethod, profiled and non-profiled code heaps (Java 9+). Default: false.
+	SegmentedCodeCache bool
+
+	// ReservedCodeCache replaces the reserved code cache sized for the compilation mode and the loaded classes, e.g.
+	// with the code cache of a profile. Unlike -XX:ReservedCodeCacheSize it is not user configured, so degrade mode
+	// may still reduce it. Default: zero (sized for the compilation mode).
+	ReservedCodeCache Size
+
+	// DirectMemory replaces the default direct memory, e.g. with the direct memory of a profile. Unlike
+	// -XX:MaxDirectMemorySize it is not user configured, so the direct memory rules may still raise it.
+	// Default: DefaultDirectMemory (10M) when zero.
+	DirectMemory Size
+
+	// DirectMemoryRules holds the direct memory rules of the off-heap heavy libraries detected in the
+	// application. The largest resolved rule raises direct memory above its default unless direct
+	// memory is configured explicitly. Default: none (direct memory stays at 10MB).
+	DirectMemoryRules []DirectMemoryRule
+
+	// NativeAgentRules holds the native memory rules of the agents detected in the JVM options. Their sizes are
+	// reserved as the native agents region. Default: none.
+	NativeAgentRules []NativeAgentRule
+
+	// InitialHeap derives the initial heap (-Xms) from the heap, e.g. 100% to start with the
+	// maximum heap. It is ignored if -Xms is configured. Default: nil (no -Xms is emitted).
+	InitialHeap *RelativeSize
+
 	// TotalMemory represents the total amount of memory available to the JVM process.
 	// This can be automatically detected from container limits (cgroups) or host system
 	// memory, or manually specified. The calculator will distribute this memory across
 	// all JVM memory regions according to the allocation algorithm.
 	// Must be positive and sufficient for minimum JVM requirements.
 	TotalMemory Size
+
+	// JavaVersion is the feature version of the Java runtime the options are calculated for,
+	// e.g. 8, 17 or 21. It selects version-appropriate region defaults (see DefaultsForJavaVersion).
+	// Default: 0 (unknown, current Java defaults apply).
+	JavaVersion int
 }
 
 // Calculate performs comprehensive JVM memory allocation calculations and returns
@@ -133,8 +217,9 @@ type Calculator struct {
 //
 //	flags - A string containing existing JVM flags that may override default calculations.
 //	        Supported flags include -Xmx, -Xms, -XX:MaxMetaspaceSize, -XX:MaxDirectMemorySize,
-//	        -XX:ReservedCodeCacheSize, and -Xss. Flags are parsed using shell-word parsing
-//	        to handle complex quoting and escaping correctly.
+//	        -XX:ReservedCodeCacheSize, -Xss and, for Java 7 and earlier, -XX:MaxPermSize. Flags are
+//	        split like the JVM splits $JAVA_TOOL_OPTIONS, see parser.SplitFlags. @argfiles are not
+//	        expanded.
 //
 // Returns:
 //
@@ -142,14 +227,19 @@ type Calculator struct {
 //	error - Any validation or calculation errors encountered during processing
 //
 // Algorithm Details:
-//  1. Parse existing JVM flags to identify user-specified overrides
-//  2. Validate Calculator configuration (memory limits, counts, percentages)
-//  3. Calculate head room reservation based on total memory percentage
-//  4. Determine thread stack allocation (ThreadCount × stack size)
-//  5. Calculate metaspace size (LoadedClassCount × ClassSize + ClassOverhead)
-//  6. Apply fixed allocations (code cache: 240MB, direct memory: 10MB)
-//  7. Allocate all remaining memory to heap
-//  8. Validate final allocation fits within available memory
+//  1. Start from the region defaults of the targeted Java version and apply the user-specified flags
+//  2. Calculate metaspace size ((LoadedClassCount - SharedClassCount) × ClassSize + ClassOverhead) and reserve
+//     the mapped CDS archives
+//  3. Reserve native memory for the detected native agents
+//  4. Raise direct memory (10MB by default) to the largest size required by the rules of the detected libraries
+//  5. Size the code cache for the compilation mode (-Xint, -XX:TieredStopAtLevel, ...) and loaded class count,
+//     and split it into code heaps if requested
+//  6. Calculate head room reservation from total memory and the headroom specification
+//  7. Estimate the stacks of virtual threads that have to fit into the heap
+//  8. In degrade mode, shrink code cache, thread count and metaspace until a minimum heap fits
+//  9. Validate the allocation fits within available memory and allocate all remaining memory to heap, after
+//     thread stacks (ThreadCount × stack size)
+//  10. Derive the initial heap from the heap if requested
 //
 // Error Conditions:
 //   - Invalid JVM flag syntax in flags parameter
@@ -180,55 +270,88 @@ type Calculator struct {
 //	// Use calculated regions for JVM startup
 //	jvmArgs := regions.ToJVMArgs()
 func (c Calculator) Calculate(flags string) (MemoryRegions, error) {
-	// Initialize default memory regions
+	// Initialize default memory regions for the targeted Java version
+	d := DefaultsForJavaVersion(c.JavaVersion)
 	m := MemoryRegions{
 		DirectMemory:      DefaultDirectMemory,
-		ReservedCodeCache: DefaultReservedCodeCache,
-		Stack:             DefaultStack,
+		ReservedCodeCache: d.ReservedCodeCache,
+		Stack:             d.Stack,
 	}
 
 	// Parse and apply JVM flags
-	if err := c.parseAndApplyFlags(flags, &m); err != nil {
+	if err := c.parseAndApplyFlags(flags, d, &m); err != nil {
 		return MemoryRegions{}, err
 	}
 
-	// Calculate metaspace if not configured
+	// Calculate metaspace if not configured and reserve the mapped CDS archives
 	c.calculateMetaspaceIfNeeded(&m)
+	c.calculateSharedArchive(&m)
+
+	// Reserve native memory for detected agents
+	c.calculateNativeAgents(&m)
+
+	// Size direct memory for detected off-heap heavy libraries
+	c.calculateDirectMemory(&m)
+
+	// Size the code cache for the compilation mode and split it into code heaps if requested
+	c.calculateReservedCodeCache(&m)
+	c.calculateCodeHeaps(&m)
 
 	// Calculate head room
 	c.calculateHeadRoom(&m)
 
+	// Estimate virtual thread stacks that have to fit into the heap
+	c.calculateVirtualThreadStacks(&m)
+
+	// Shrink regions to fit a minimum heap if degrade mode is enabled
+	c.ThreadCount = c.degrade(&m)
+	m.ThreadCount = c.ThreadCount
+
 	// Validate memory constraints and calculate heap
 	if err := c.validateAndCalculateHeap(&m); err != nil {
 		return MemoryRegions{}, err
 	}
 
+	// Derive the initial heap from the heap if requested
+	c.calculateInitialHeap(&m)
+
 	return m, nil
 }
 
 // parseAndApplyFlags parses JVM flags and applies them to memory regions
-func (c Calculator) parseAndApplyFlags(flags string, m *MemoryRegions) error {
-	p, err := parser.ParseFlags(flags)
+func (c Calculator) parseAndApplyFlags(flags string, d JavaDefaults, m *MemoryRegions) error {
+	p, err := parser.SplitFlags(flags)
 	if err != nil {
 		return fmt.Errorf("unable to parse flags\n%w", err)
 	}
 
 	for _, s := range p {
-		if err := c.applyFlagToRegion(s, m); err != nil {
+		if err := c.applyFlagToRegion(s, d, m); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// applyFlagToRegion applies a single flag to the appropriate memory region
-func (c Calculator) applyFlagToRegion(flag string, m *MemoryRegions) error {
-	if matchDirectMemory(flag) {
+// applyFlagToRegion applies a single flag to the appropriate memory region. On Java 7 and earlier, -XX:MaxPermSize
+// configures the class metadata that metaspace holds on later versions.
+func (c Calculator) applyFlagToRegion(flag string, d JavaDefaults, m *MemoryRegions) error {
+	m.CompilationMode = applyCompilationFlag(flag, m.CompilationMode)
+
+	if MatchCodeHeap(flag) {
+		m.userCodeHeaps = true
+		return nil
+	} else if MatchInitialHeap(flag) {
+		m.userInitialHeap = true
+		return nil
+	} else if matchDirectMemory(flag) {
 		return c.setDirectMemory(flag, m)
 	} else if matchHeap(flag) {
 		return c.setHeap(flag, m)
 	} else if matchMetaspace(flag) {
 		return c.setMetaspace(flag, m)
+	} else if !d.Metaspace && MatchPermGen(flag) {
+		return c.setPermGen(flag, m)
 	} else if matchReservedCodeCache(flag) {
 		return c.setReservedCodeCache(flag, m)
 	} else if matchStack(flag) {
@@ -270,6 +393,17 @@ func (c Calculator) setMetaspace(flag string, m *MemoryRegions) error {
 	return nil
 }
 
+// setPermGen parses and sets the permanent generation of Java 7 and earlier as metaspace configuration
+func (c Calculator) setPermGen(flag string, m *MemoryRegions) error {
+	ms, err := ParsePermGen(flag)
+	if err != nil {
+		return fmt.Errorf("unable to parse permanent generation\n%w", err)
+	}
+	ms.Provenance = UserConfigured
+	m.Metaspace = ms
+	return nil
+}
+
 // setReservedCodeCache parses and sets reserved code cache configuration
 func (c Calculator) setReservedCodeCache(flag string, m *MemoryRegions) error {
 	r, err := parseReservedCodeCache(flag)
@@ -296,21 +430,13 @@ func (c Calculator) setStack(flag string, m *MemoryRegions) error {
 func (c Calculator) calculateMetaspaceIfNeeded(m *MemoryRegions) {
 	if m.Metaspace == nil {
 		ms := Metaspace{
-			Value:      ClassOverhead + (int64(c.LoadedClassCount) * ClassSize),
+			Value:      c.classOverhead() + (int64(c.metaspaceClassCount()) * c.classSize()),
 			Provenance: Calculated,
 		}
 		m.Metaspace = &ms
 	}
 }
 
-// calculateHeadRoom calculates the head room based on total memory and percentage
-func (c Calculator) calculateHeadRoom(m *MemoryRegions) {
-	m.HeadRoom = &HeadRoom{
-		Value:      int64((float64(c.HeadRoom) / 100) * float64(c.TotalMemory.Value)),
-		Provenance: Calculated,
-	}
-}
-
 // validateAndCalculateHeap validates memory constraints and calculates heap if needed
 func (c Calculator) validateAndCalculateHeap(m *MemoryRegions) error {
 	// Validate fixed regions
@@ -323,6 +449,11 @@ func (c Calculator) validateAndCalculateHeap(m *MemoryRegions) error {
 		return err
 	}
 
+	// Validate the heap holds the virtual thread stacks
+	if err := c.validateVirtualThreadStacks(m); err != nil {
+		return err
+	}
+
 	// Final validation of all regions
 	return c.validateAllRegions(m)
 }
@@ -105,7 +105,7 @@ func TestCalculatorCalculate(t *testing.T) {
 			expectError: false,
 		},
 		{
-			name: "Flags with unclosed quotes (handled gracefully)",
+			name: "Flags with unclosed quotes (rejected like the JVM)",
 			calculator: Calculator{
 				HeadRoom:         10,
 				LoadedClassCount: 5000,
@@ -113,7 +113,8 @@ func TestCalculatorCalculate(t *testing.T) {
 				TotalMemory:      Size{Value: 2 * Gibi},
 			},
 			flags:       `"unclosed quote`,
-			expectError: false,
+			expectError: true,
+			errorMsg:    "unmatched quote",
 		},
 		{
 			name: "Invalid direct memory format",
@@ -412,3 +413,30 @@ func containsString(s, substr string) bool {
 	}
 	return false
 }
+
+func TestCalculateMetaspaceCoefficients(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 10_000,
+		ThreadCount:      50,
+		ClassSize:        Size{Value: 8 * Kibi},
+		ClassOverhead:    Size{Value: 20 * Mebi},
+		TotalMemory:      Size{Value: 2 * Gibi},
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := 20*Mebi + 10_000*8*Kibi
+	if result.Metaspace.Value != expected {
+		t.Errorf("Expected metaspace %d, got %d", expected, result.Metaspace.Value)
+	}
+	if f := c.MetaspaceFormula(); f != "20971520 + 10000 classes × 8192 = 102891520" {
+		t.Errorf("Unexpected metaspace formula %q", f)
+	}
+
+	if f := (Calculator{LoadedClassCount: 100}).MetaspaceFormula(); f != "14000000 + 100 classes × 5800 = 14580000" {
+		t.Errorf("Expected default coefficients in formula, got %q", f)
+	}
+}
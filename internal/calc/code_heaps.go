@@ -0,0 +1,83 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NonNMethodCodeHeap is the size of the code heap for non-method code such as interpreter and adapter stubs (8M).
+var NonNMethodCodeHeap = Size{Value: 8 * Mebi, Provenance: Default}
+
+// codeHeapFlagPrefixes lists the flags that configure the segmented code cache.
+var codeHeapFlagPrefixes = []string{
+	"-XX:NonNMethodCodeHeapSize=",
+	"-XX:ProfiledCodeHeapSize=",
+	"-XX:NonProfiledCodeHeapSize=",
+	"-XX:-SegmentedCodeCache",
+}
+
+// CodeHeaps represents the segments of a segmented code cache (Java 9+). The segments add up to the reserved code
+// cache size.
+type CodeHeaps struct {
+	// NonNMethod holds non-method code such as the interpreter, stubs and adapters.
+	NonNMethod Size
+
+	// Profiled holds lightly optimized, profiled methods compiled by C1.
+	Profiled Size
+
+	// NonProfiled holds fully optimized, non-profiled methods compiled by C2 or Graal.
+	NonProfiled Size
+}
+
+func (c CodeHeaps) String() string {
+	return strings.Join(c.Flags(), " ")
+}
+
+// Flags returns the JVM flags that configure the code heaps.
+func (c CodeHeaps) Flags() []string {
+	return []string{
+		"-XX:+SegmentedCodeCache",
+		fmt.Sprintf("-XX:NonNMethodCodeHeapSize=%s", c.NonNMethod),
+		fmt.Sprintf("-XX:ProfiledCodeHeapSize=%s", c.Profiled),
+		fmt.Sprintf("-XX:NonProfiledCodeHeapSize=%s", c.NonProfiled),
+	}
+}
+
+// MatchCodeHeap returns true if the string is a flag that configures the segmented code cache.
+func MatchCodeHeap(s string) bool {
+	t := strings.TrimSpace(s)
+	for _, p := range codeHeapFlagPrefixes {
+		if strings.HasPrefix(t, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// calculateCodeHeaps splits the reserved code cache into code heaps if segmentation was requested. Only tiered
+// compilation uses all three code heaps, so other compilation modes are left unsegmented. The JVM refuses to start if
+// the code heaps do not add up to the reserved code cache, so a reserved code cache that is not a multiple of a
+// kibibyte, which the code heaps cannot add up to, is left unsegmented as well.
+func (c Calculator) calculateCodeHeaps(m *MemoryRegions) {
+	if !c.SegmentedCodeCache || m.userCodeHeaps {
+		return
+	}
+	if m.CompilationMode != Tiered && m.CompilationMode != Graal {
+		return
+	}
+	if m.ReservedCodeCache.Value%Kibi != 0 {
+		return
+	}
+
+	rest := m.ReservedCodeCache.Value - NonNMethodCodeHeap.Value
+	if rest < 2*Mebi {
+		return
+	}
+
+	profiled := rest / 2 / Mebi * Mebi
+	m.CodeHeaps = &CodeHeaps{
+		NonNMethod:  Size{Value: NonNMethodCodeHeap.Value, Provenance: Calculated},
+		Profiled:    Size{Value: profiled, Provenance: Calculated},
+		NonProfiled: Size{Value: rest - profiled, Provenance: Calculated},
+	}
+}
@@ -0,0 +1,89 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestCalculateCodeHeaps(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount:   5000,
+		ThreadCount:        100,
+		TotalMemory:        Size{Value: 2 * Gibi},
+		SegmentedCodeCache: true,
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := result.CodeHeaps
+	if h == nil {
+		t.Fatal("Expected code heaps to be calculated")
+	}
+	if sum := h.NonNMethod.Value + h.Profiled.Value + h.NonProfiled.Value; sum != result.ReservedCodeCache.Value {
+		t.Errorf("Expected code heaps to add up to %d, got %d", result.ReservedCodeCache.Value, sum)
+	}
+
+	expected := "-XX:+SegmentedCodeCache -XX:NonNMethodCodeHeapSize=8M -XX:ProfiledCodeHeapSize=116M " +
+		"-XX:NonProfiledCodeHeapSize=116M"
+	if h.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, h.String())
+	}
+}
+
+func TestCalculateCodeHeapsSkipped(t *testing.T) {
+	tests := []struct {
+		name      string
+		segmented bool
+		flags     string
+	}{
+		{"Not requested", false, ""},
+		{"C1 only", true, "-XX:TieredStopAtLevel=1"},
+		{"Interpreted", true, "-Xint"},
+		{"User configured code heaps", true, "-XX:ProfiledCodeHeapSize=100m"},
+		{"Segmentation disabled by user", true, "-XX:-SegmentedCodeCache"},
+		{"Reserved code cache not kibibyte aligned", true, "-XX:ReservedCodeCacheSize=314573300"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Calculator{
+				LoadedClassCount:   5000,
+				ThreadCount:        100,
+				TotalMemory:        Size{Value: 2 * Gibi},
+				SegmentedCodeCache: tt.segmented,
+			}
+
+			result, err := c.Calculate(tt.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.CodeHeaps != nil {
+				t.Errorf("Expected no code heaps, got %s", result.CodeHeaps)
+			}
+		})
+	}
+}
+
+func TestCalculateCodeHeapsForUserReservedCodeCache(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount:   5000,
+		ThreadCount:        100,
+		TotalMemory:        Size{Value: 2 * Gibi},
+		SegmentedCodeCache: true,
+	}
+
+	result, err := c.Calculate("-XX:ReservedCodeCacheSize=301m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := result.CodeHeaps
+	if h == nil {
+		t.Fatal("Expected code heaps to be calculated")
+	}
+	if sum := h.NonNMethod.Value + h.Profiled.Value + h.NonProfiled.Value; sum != 301*Mebi {
+		t.Errorf("Expected code heaps to add up to 301M, got %d", sum)
+	}
+}
@@ -0,0 +1,120 @@
+package calc
+
+import (
+	"strings"
+)
+
+// CompilationMode describes how the JVM compiles bytecode, which determines how much code cache it needs.
+type CompilationMode uint8
+
+const (
+	// Tiered indicates the default tiered compilation with C1 and C2.
+	Tiered CompilationMode = iota
+
+	// C1Only indicates tiered compilation stopped at a C1 level (-XX:TieredStopAtLevel=1..3).
+	C1Only
+
+	// C2Only indicates tiered compilation is disabled (-XX:-TieredCompilation).
+	C2Only
+
+	// Graal indicates the Graal JIT replaces C2 (-XX:+UseJVMCICompiler).
+	Graal
+
+	// Interpreted indicates the JIT is disabled and all code is interpreted (-Xint).
+	Interpreted
+)
+
+const (
+	// CodeCacheReferenceClassCount is the loaded class count the base code cache sizes are meant for. Larger
+	// applications compile more methods, so the code cache grows proportionally beyond this count.
+	CodeCacheReferenceClassCount = 25_000
+
+	// MaxReservedCodeCache is the largest code cache the JVM accepts (2G).
+	MaxReservedCodeCache = 2 * Gibi
+)
+
+func (c CompilationMode) String() string {
+	switch c {
+	case C1Only:
+		return "C1 only"
+	case C2Only:
+		return "C2 only"
+	case Graal:
+		return "Graal JIT"
+	case Interpreted:
+		return "interpreted"
+	default:
+		return "tiered"
+	}
+}
+
+// baseCodeCache returns the code cache a mode needs for an application of CodeCacheReferenceClassCount classes.
+// Tiered compilation uses the JVM default of the targeted Java version.
+func (c CompilationMode) baseCodeCache(defaults JavaDefaults) int64 {
+	switch c {
+	case C1Only, C2Only:
+		return 48 * Mebi
+	case Graal:
+		return 128 * Mebi
+	case Interpreted:
+		return 16 * Mebi
+	default:
+		return defaults.ReservedCodeCache.Value
+	}
+}
+
+// applyCompilationFlag updates the compilation mode for a single JVM flag. Later flags win, as they do for the JVM.
+func applyCompilationFlag(flag string, mode CompilationMode) CompilationMode {
+	switch s := strings.TrimSpace(flag); {
+	case s == "-Xint":
+		return Interpreted
+	case s == "-Xmixed":
+		return Tiered
+	case s == "-XX:-TieredCompilation":
+		return C2Only
+	case s == "-XX:+TieredCompilation":
+		if mode == C2Only {
+			return Tiered
+		}
+	case s == "-XX:+UseJVMCICompiler":
+		if mode != Interpreted {
+			return Graal
+		}
+	case strings.HasPrefix(s, "-XX:TieredStopAtLevel="):
+		switch strings.TrimPrefix(s, "-XX:TieredStopAtLevel=") {
+		case "0":
+			return Interpreted
+		case "1", "2", "3":
+			return C1Only
+		default:
+			if mode == C1Only {
+				return Tiered
+			}
+		}
+	}
+	return mode
+}
+
+// calculateReservedCodeCache sizes the code cache for the compilation mode and loaded class count if it was not
+// configured by the user. Calculator.ReservedCodeCache replaces the sized code cache if set.
+func (c Calculator) calculateReservedCodeCache(m *MemoryRegions) {
+	if m.ReservedCodeCache.Provenance == UserConfigured {
+		return
+	}
+	if c.ReservedCodeCache.Value > 0 {
+		m.ReservedCodeCache = ReservedCodeCache{Value: c.ReservedCodeCache.Value, Provenance: Default}
+		return
+	}
+
+	base := m.CompilationMode.baseCodeCache(DefaultsForJavaVersion(c.JavaVersion))
+	size := base
+	if m.CompilationMode != Interpreted && c.LoadedClassCount > CodeCacheReferenceClassCount {
+		scaled := float64(base) * float64(c.LoadedClassCount) / CodeCacheReferenceClassCount
+		size = min(((int64(scaled)+Mebi-1)/Mebi)*Mebi, MaxReservedCodeCache)
+	}
+
+	if size == m.ReservedCodeCache.Value {
+		return
+	}
+	m.ReservedCodeCache = ReservedCodeCache{Value: size, Provenance: Calculated}
+}
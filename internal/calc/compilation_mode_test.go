@@ -0,0 +1,72 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestCompilationModeDetection(t *testing.T) {
+	tests := []struct {
+		flags    string
+		expected CompilationMode
+	}{
+		{"", Tiered},
+		{"-Xint", Interpreted},
+		{"-XX:TieredStopAtLevel=1", C1Only},
+		{"-XX:TieredStopAtLevel=0", Interpreted},
+		{"-XX:TieredStopAtLevel=4", Tiered},
+		{"-XX:-TieredCompilation", C2Only},
+		{"-XX:-TieredCompilation -XX:+TieredCompilation", Tiered},
+		{"-XX:+UnlockExperimentalVMOptions -XX:+EnableJVMCI -XX:+UseJVMCICompiler", Graal},
+		{"-Xint -Xmixed", Tiered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flags, func(t *testing.T) {
+			c := Calculator{LoadedClassCount: 5000, ThreadCount: 100, TotalMemory: Size{Value: 2 * Gibi}}
+
+			result, err := c.Calculate(tt.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.CompilationMode != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result.CompilationMode)
+			}
+		})
+	}
+}
+
+func TestReservedCodeCacheSizing(t *testing.T) {
+	tests := []struct {
+		name             string
+		flags            string
+		loadedClassCount int
+		expected         int64
+		provenance       Provenance
+	}{
+		{"Tiered default", "", 5000, 240 * Mebi, Default},
+		{"Tiered scaled by classes", "", 50_000, 480 * Mebi, Calculated},
+		{"Tiered capped", "", 1_000_000, MaxReservedCodeCache, Calculated},
+		{"C1 only", "-XX:TieredStopAtLevel=1", 5000, 48 * Mebi, Calculated},
+		{"C2 only", "-XX:-TieredCompilation", 5000, 48 * Mebi, Calculated},
+		{"Graal", "-XX:+UseJVMCICompiler", 5000, 128 * Mebi, Calculated},
+		{"Interpreted ignores classes", "-Xint", 50_000, 16 * Mebi, Calculated},
+		{"User configured", "-Xint -XX:ReservedCodeCacheSize=64m", 5000, 64 * Mebi, UserConfigured},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Calculator{LoadedClassCount: tt.loadedClassCount, ThreadCount: 100, TotalMemory: Size{Value: 16 * Gibi}}
+
+			result, err := c.Calculate(tt.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.ReservedCodeCache.Value != tt.expected {
+				t.Errorf("Expected code cache %d, got %d", tt.expected, result.ReservedCodeCache.Value)
+			}
+			if result.ReservedCodeCache.Provenance != tt.provenance {
+				t.Errorf("Expected provenance %d, got %d", tt.provenance, result.ReservedCodeCache.Provenance)
+			}
+		})
+	}
+}
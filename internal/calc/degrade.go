@@ -0,0 +1,111 @@
+package calc
+
+import (
+	"fmt"
+)
+
+var (
+	// DefaultMinHeap is the smallest heap degrade mode tries to make room for (64MB).
+	DefaultMinHeap = Size{Value: 64 * Mebi, Provenance: Default}
+
+	// MinDegradedCodeCache is the floor degrade mode shrinks the reserved code cache to (32MB).
+	MinDegradedCodeCache = Size{Value: 32 * Mebi, Provenance: Default}
+)
+
+// MinDegradedThreadCount is the floor degrade mode shrinks the platform thread count to.
+const MinDegradedThreadCount = 50
+
+// Reduction records how degrade mode shrank a memory region to make room for the minimum heap.
+type Reduction struct {
+	// Region names the reduced region, e.g. "code cache".
+	Region string
+
+	// From is the size of the region before the reduction.
+	From Size
+
+	// To is the size of the region after the reduction.
+	To Size
+
+	// Detail optionally describes the reduction in the region's own terms, e.g. "250 -> 120 threads".
+	Detail string
+}
+
+func (r Reduction) String() string {
+	s := fmt.Sprintf("%s reduced from %s to %s (-%s)", r.Region, r.From, r.To, Size{Value: r.From.Value - r.To.Value})
+	if r.Detail != "" {
+		s = fmt.Sprintf("%s, %s", s, r.Detail)
+	}
+	return s
+}
+
+// degrade shrinks regions that were not configured by the user until the minimum heap fits into total memory and
+// returns the platform thread count to calculate with. Regions are shrunk in this order, each only as far as needed:
+//  1. Reserved code cache, down to MinDegradedCodeCache
+//  2. Platform thread count, down to MinDegradedThreadCount
+//  3. Metaspace safety margin (the class overhead), down to the per-class size
+func (c Calculator) degrade(m *MemoryRegions) int {
+	threadCount := c.ThreadCount
+	if !c.Degrade {
+		return threadCount
+	}
+
+	minHeap := c.MinHeap
+	if minHeap.Value <= 0 {
+		minHeap = DefaultMinHeap
+	}
+	if m.Heap != nil {
+		minHeap = Size(*m.Heap)
+	}
+
+	deficit := func() int64 {
+		n, err := m.NonHeapRegionsSize(threadCount)
+		if err != nil {
+			return 0
+		}
+		return n.Value + minHeap.Value - c.TotalMemory.Value
+	}
+
+	// 1. Reserved code cache
+	if d := deficit(); d > 0 && m.ReservedCodeCache.Provenance != UserConfigured &&
+		m.ReservedCodeCache.Value > MinDegradedCodeCache.Value {
+
+		from := Size(m.ReservedCodeCache)
+		to := max((from.Value-d)/Mebi*Mebi, MinDegradedCodeCache.Value)
+		m.ReservedCodeCache = ReservedCodeCache{Value: to, Provenance: Calculated}
+		m.Reductions = append(m.Reductions, Reduction{Region: "code cache", From: from, To: Size(m.ReservedCodeCache)})
+
+		// Code heaps have to add up to the reduced code cache
+		if m.CodeHeaps != nil {
+			m.CodeHeaps = nil
+			c.calculateCodeHeaps(m)
+		}
+	}
+
+	// 2. Platform thread count
+	if d := deficit(); d > 0 && c.ThreadCountProvenance != UserConfigured &&
+		threadCount > MinDegradedThreadCount && m.Stack.Value > 0 {
+
+		to := max(threadCount-int((d+m.Stack.Value-1)/m.Stack.Value), MinDegradedThreadCount)
+		m.Reductions = append(m.Reductions, Reduction{
+			Region: "thread stacks",
+			From:   Size{Value: m.Stack.Value * int64(threadCount)},
+			To:     Size{Value: m.Stack.Value * int64(to), Provenance: Calculated},
+			Detail: fmt.Sprintf("%d -> %d threads", threadCount, to),
+		})
+		threadCount = to
+	}
+
+	// 3. Metaspace safety margin
+	if d := deficit(); d > 0 && m.Metaspace != nil && m.Metaspace.Provenance != UserConfigured {
+		from := Size(*m.Metaspace)
+		to := max(from.Value-d, int64(c.metaspaceClassCount())*c.classSize(), from.Value-c.classOverhead())
+		if to < from.Value {
+			m.Metaspace = &Metaspace{Value: to, Provenance: Calculated}
+			m.Reductions = append(m.Reductions, Reduction{
+				Region: "metaspace", From: from, To: Size(*m.Metaspace), Detail: "class overhead margin",
+			})
+		}
+	}
+
+	return threadCount
+}
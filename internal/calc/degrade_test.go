@@ -0,0 +1,139 @@
+package calc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDegradeSidecarContainer(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 8000,
+		ThreadCount:      250,
+		TotalMemory:      Size{Value: 384 * Mebi},
+		Degrade:          true,
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Reductions) != 2 {
+		t.Fatalf("Expected code cache and thread reductions, got %v", result.Reductions)
+	}
+	if result.Reductions[0].Region != "code cache" || result.ReservedCodeCache.Value != MinDegradedCodeCache.Value {
+		t.Errorf("Expected code cache reduced to %s, got %v", MinDegradedCodeCache, result.Reductions[0])
+	}
+	if result.Reductions[1].Region != "thread stacks" || result.ThreadCount >= 250 ||
+		result.ThreadCount < MinDegradedThreadCount {
+		t.Errorf("Expected thread count reduced, got %d (%v)", result.ThreadCount, result.Reductions[1])
+	}
+	if result.Heap.Value < DefaultMinHeap.Value {
+		t.Errorf("Expected heap of at least %s, got %s", DefaultMinHeap, Size(*result.Heap))
+	}
+	validateMemoryBounds(t, result, c.TotalMemory.Value, result.ThreadCount)
+}
+
+func TestDegradeShrinksOnlyAsFarAsNeeded(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 8000,
+		ThreadCount:      250,
+		TotalMemory:      Size{Value: 600 * Mebi},
+		Degrade:          true,
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Reductions) != 1 || result.Reductions[0].Region != "code cache" {
+		t.Fatalf("Expected only a code cache reduction, got %v", result.Reductions)
+	}
+	if result.ThreadCount != 250 {
+		t.Errorf("Expected thread count to stay at 250, got %d", result.ThreadCount)
+	}
+	if result.ReservedCodeCache.Value <= MinDegradedCodeCache.Value {
+		t.Errorf("Expected code cache above the floor, got %s", result.ReservedCodeCache)
+	}
+	if result.Heap.Value < DefaultMinHeap.Value || result.Heap.Value > DefaultMinHeap.Value+Mebi {
+		t.Errorf("Expected heap close to %s, got %s", DefaultMinHeap, Size(*result.Heap))
+	}
+}
+
+func TestDegradeKeepsUserConfiguredRegions(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount:      8000,
+		ThreadCount:           250,
+		ThreadCountProvenance: UserConfigured,
+		TotalMemory:           Size{Value: 384 * Mebi},
+		Degrade:               true,
+	}
+
+	_, err := c.Calculate("-XX:ReservedCodeCacheSize=240M")
+	if err == nil {
+		t.Fatal("Expected error when only the metaspace margin can be reduced")
+	}
+
+	result, err := Calculator{
+		LoadedClassCount:      8000,
+		ThreadCount:           100,
+		ThreadCountProvenance: UserConfigured,
+		TotalMemory:           Size{Value: 256 * Mebi},
+		Degrade:               true,
+		MinHeap:               Size{Value: 32 * Mebi},
+	}.Calculate("-XX:ReservedCodeCacheSize=64M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Reductions) != 1 || result.Reductions[0].Region != "metaspace" {
+		t.Fatalf("Expected only a metaspace reduction, got %v", result.Reductions)
+	}
+	if result.Metaspace.Value < int64(8000)*ClassSize {
+		t.Errorf("Expected metaspace to keep the per-class size, got %s", result.Metaspace)
+	}
+}
+
+func TestDegradeReducesReplacedCodeCache(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount:  8000,
+		ThreadCount:       250,
+		TotalMemory:       Size{Value: 600 * Mebi},
+		Degrade:           true,
+		ReservedCodeCache: Size{Value: 256 * Mebi},
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Reductions) == 0 || result.Reductions[0].Region != "code cache" ||
+		result.Reductions[0].From.Value != 256*Mebi {
+		t.Fatalf("Expected the code cache of 256M to be reduced, got %v", result.Reductions)
+	}
+}
+
+func TestDegradeDisabled(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 8000,
+		ThreadCount:      250,
+		TotalMemory:      Size{Value: 384 * Mebi},
+	}
+
+	if _, err := c.Calculate(""); err == nil {
+		t.Error("Expected error without degrade mode")
+	}
+}
+
+func TestReductionString(t *testing.T) {
+	r := Reduction{
+		Region: "thread stacks",
+		From:   Size{Value: 250 * Mebi},
+		To:     Size{Value: 120 * Mebi},
+		Detail: "250 -> 120 threads",
+	}
+
+	if s := r.String(); !strings.Contains(s, "from 250M to 120M (-130M)") || !strings.HasSuffix(s, "250 -> 120 threads") {
+		t.Errorf("Unexpected reduction string %q", s)
+	}
+}
@@ -39,3 +39,100 @@ func ParseDirectMemory(s string) (DirectMemory, error) {
 
 	return DirectMemory(z), nil
 }
+
+// DirectMemoryRule sizes direct memory for an application that uses a library known to allocate off-heap buffers.
+type DirectMemoryRule struct {
+	// Library is the name of the library the rule applies to, e.g. "netty-buffer".
+	Library string
+
+	// Size is the direct memory the library needs, usually a share of total memory with a floor.
+	Size RelativeSize
+}
+
+// DefaultDirectMemoryRules are the built-in direct memory rules for well-known off-heap heavy libraries.
+var DefaultDirectMemoryRules = []DirectMemoryRule{
+	{Library: "netty-buffer", Size: RelativeSize{Percent: 10, Min: Size{Value: 64 * Mebi}, Max: Size{Value: Gibi}}},
+	{Library: "grpc-netty", Size: RelativeSize{Percent: 10, Min: Size{Value: 64 * Mebi}, Max: Size{Value: Gibi}}},
+	{Library: "kafka-clients", Size: RelativeSize{Percent: 5, Min: Size{Value: 32 * Mebi}, Max: Size{Value: 512 * Mebi}}},
+	{Library: "lucene", Size: RelativeSize{Percent: 10, Min: Size{Value: 64 * Mebi}, Max: Size{Value: Gibi}}},
+	{Library: "chronicle", Size: RelativeSize{Percent: 25, Min: Size{Value: 128 * Mebi}, Max: Size{Value: 4 * Gibi}}},
+}
+
+// DirectMemoryRuleMax is the upper bound of relative direct memory rules that do not set a maximum themselves, so
+// that a share of a large host does not reserve gigabytes of direct memory.
+var DirectMemoryRuleMax = Size{Value: Gibi}
+
+// ParseDirectMemoryRules parses semicolon-separated direct memory rules of the form <library>=<relative size>, e.g.
+// "netty-buffer=15%,min=128M;kafka-clients=64M".
+func ParseDirectMemoryRules(s string) ([]DirectMemoryRule, error) {
+	var rules []DirectMemoryRule
+
+	for _, r := range strings.Split(s, ";") {
+		if strings.TrimSpace(r) == "" {
+			continue
+		}
+
+		library, size, ok := strings.Cut(r, "=")
+		library = strings.TrimSpace(library)
+		if !ok || library == "" {
+			return nil, fmt.Errorf("direct memory rule %q must have the form <library>=<size>", r)
+		}
+
+		z, err := ParseRelativeSize(size)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse direct memory rule for %s\n%w", library, err)
+		}
+
+		rules = append(rules, DirectMemoryRule{Library: library, Size: z})
+	}
+
+	return rules, nil
+}
+
+// MergeDirectMemoryRules returns the rules with the overrides applied. An override replaces the rule for the same
+// library or is appended if there is none.
+func MergeDirectMemoryRules(rules []DirectMemoryRule, overrides []DirectMemoryRule) []DirectMemoryRule {
+	merged := append([]DirectMemoryRule(nil), rules...)
+
+	for _, o := range overrides {
+		found := false
+		for i := range merged {
+			if merged[i].Library == o.Library {
+				merged[i] = o
+				found = true
+			}
+		}
+		if !found {
+			merged = append(merged, o)
+		}
+	}
+
+	return merged
+}
+
+// calculateDirectMemory raises direct memory, Calculator.DirectMemory if set, to the largest size required by the
+// rules of the detected libraries.
+// Relative rules without a maximum are bounded by DirectMemoryRuleMax. Direct memory configured by the user is left
+// untouched.
+func (c Calculator) calculateDirectMemory(m *MemoryRegions) {
+	if m.DirectMemory.Provenance == UserConfigured {
+		return
+	}
+	if c.DirectMemory.Value > 0 {
+		m.DirectMemory = DirectMemory{Value: c.DirectMemory.Value, Provenance: Default}
+	}
+
+	for _, r := range c.DirectMemoryRules {
+		if r.Size.Absolute.Value == 0 && r.Size.Max.Value == 0 {
+			r.Size.Max = DirectMemoryRuleMax
+		}
+
+		s := r.Size.Resolve(c.TotalMemory)
+		s.Value = ((s.Value + Mebi - 1) / Mebi) * Mebi
+
+		if s.Value > m.DirectMemory.Value {
+			m.DirectMemory = DirectMemory(s)
+			m.DirectMemoryLibrary = r.Library
+		}
+	}
+}
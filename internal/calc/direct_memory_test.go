@@ -0,0 +1,168 @@
+package calc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDirectMemoryRules(t *testing.T) {
+	rules, err := ParseDirectMemoryRules("netty-buffer=15%,min=128M; kafka-clients=64M;")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []DirectMemoryRule{
+		{Library: "netty-buffer", Size: RelativeSize{Percent: 15, Min: Size{Value: 128 * Mebi}}},
+		{Library: "kafka-clients", Size: RelativeSize{Absolute: Size{Value: 64 * Mebi}}},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, rules)
+	}
+
+	for _, s := range []string{"netty-buffer", "=10%", "netty-buffer=lots"} {
+		if _, err := ParseDirectMemoryRules(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestMergeDirectMemoryRules(t *testing.T) {
+	overrides := []DirectMemoryRule{
+		{Library: "netty-buffer", Size: RelativeSize{Absolute: Size{Value: 256 * Mebi}}},
+		{Library: "aeron", Size: RelativeSize{Percent: 20}},
+	}
+
+	merged := MergeDirectMemoryRules(DefaultDirectMemoryRules, overrides)
+
+	if len(merged) != len(DefaultDirectMemoryRules)+1 {
+		t.Fatalf("Expected %d rules, got %d", len(DefaultDirectMemoryRules)+1, len(merged))
+	}
+	if merged[0] != overrides[0] {
+		t.Errorf("Expected netty-buffer rule to be replaced, got %+v", merged[0])
+	}
+	if merged[len(merged)-1] != overrides[1] {
+		t.Errorf("Expected aeron rule to be appended, got %+v", merged[len(merged)-1])
+	}
+	if DefaultDirectMemoryRules[0].Size.Percent != 10 {
+		t.Error("Expected default rules to be left unchanged")
+	}
+}
+
+func TestCalculateDirectMemoryFromRules(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 5000,
+		ThreadCount:      50,
+		TotalMemory:      Size{Value: 2 * Gibi},
+		DirectMemoryRules: []DirectMemoryRule{
+			DefaultDirectMemoryRules[2], // kafka-clients: 5%, min 32M
+			DefaultDirectMemoryRules[0], // netty-buffer: 10%, min 64M
+		},
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10% of 2G rounded up to whole mebibytes
+	expected := ((2*Gibi/10 + Mebi - 1) / Mebi) * Mebi
+	if result.DirectMemory.Value != expected {
+		t.Errorf("Expected direct memory %d, got %d", expected, result.DirectMemory.Value)
+	}
+	if result.DirectMemory.Provenance != Calculated {
+		t.Errorf("Expected calculated provenance, got %v", result.DirectMemory.Provenance)
+	}
+	if result.DirectMemoryLibrary != "netty-buffer" {
+		t.Errorf("Expected netty-buffer to trigger the bump, got %q", result.DirectMemoryLibrary)
+	}
+	validateMemoryBounds(t, result, c.TotalMemory.Value, c.ThreadCount)
+}
+
+func TestCalculateDirectMemoryBoundsRelativeRules(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 5000,
+		ThreadCount:      50,
+		TotalMemory:      Size{Value: 64 * Gibi},
+		DirectMemoryRules: []DirectMemoryRule{
+			{Library: "custom", Size: RelativeSize{Percent: 10}},
+		},
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DirectMemory.Value != DirectMemoryRuleMax.Value {
+		t.Errorf("Expected direct memory bounded to %s, got %s", DirectMemoryRuleMax, result.DirectMemory)
+	}
+
+	c.DirectMemoryRules = []DirectMemoryRule{DefaultDirectMemoryRules[4]} // chronicle: 25%, max 4G
+	if result, err = c.Calculate(""); err != nil {
+		t.Fatal(err)
+	}
+	if result.DirectMemory.Value != 4*Gibi {
+		t.Errorf("Expected direct memory bounded to the rule maximum 4G, got %s", result.DirectMemory)
+	}
+}
+
+func TestCalculateDirectMemoryKeepsUserConfiguration(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount:  5000,
+		ThreadCount:       50,
+		TotalMemory:       Size{Value: 2 * Gibi},
+		DirectMemoryRules: DefaultDirectMemoryRules,
+	}
+
+	result, err := c.Calculate("-XX:MaxDirectMemorySize=32M")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.DirectMemory.Value != 32*Mebi || result.DirectMemoryLibrary != "" {
+		t.Errorf("Expected user configured direct memory of 32M, got %s (%q)",
+			Size(result.DirectMemory), result.DirectMemoryLibrary)
+	}
+}
+
+func TestCalculateDirectMemoryWithoutRules(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 5000,
+		ThreadCount:      50,
+		TotalMemory:      Size{Value: 2 * Gibi},
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.DirectMemory != DefaultDirectMemory {
+		t.Errorf("Expected default direct memory, got %+v", result.DirectMemory)
+	}
+}
+
+func TestCalculateDirectMemoryReplacesDefault(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 5000,
+		ThreadCount:      50,
+		TotalMemory:      Size{Value: 2 * Gibi},
+		DirectMemory:     Size{Value: 128 * Mebi},
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DirectMemory.Value != 128*Mebi || result.DirectMemory.Provenance != Default {
+		t.Errorf("Expected default direct memory of 128M, got %+v", result.DirectMemory)
+	}
+
+	// Rules of detected libraries still raise it
+	c.DirectMemoryRules = []DirectMemoryRule{DefaultDirectMemoryRules[0]}
+	if result, err = c.Calculate(""); err != nil {
+		t.Fatal(err)
+	}
+	if result.DirectMemory.Value <= 128*Mebi || result.DirectMemoryLibrary != "netty-buffer" {
+		t.Errorf("Expected netty-buffer to raise direct memory above 128M, got %+v", result.DirectMemory)
+	}
+}
@@ -1,8 +1,48 @@
 package calc
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // HeadRoom represents the memory headroom.
 type HeadRoom Size
 
 func (h HeadRoom) String() string {
 	return Size(h).String()
 }
+
+// ParseHeadRoom parses a headroom specification. A plain integer is a percentage of total memory for compatibility
+// with earlier releases, e.g. "10". Any other value is a relative size such as "10%", "300M" or
+// "10%,min=128M,max=2G".
+func ParseHeadRoom(s string) (RelativeSize, error) {
+	t := strings.TrimSpace(s)
+
+	if p, err := strconv.Atoi(t); err == nil {
+		if p < 0 || p > 100 {
+			return RelativeSize{}, fmt.Errorf("headroom percentage %d must be between 0 and 100", p)
+		}
+		return RelativeSize{Percent: float64(p)}, nil
+	}
+
+	r, err := ParseRelativeSize(t)
+	if err != nil {
+		return RelativeSize{}, fmt.Errorf("unable to parse headroom %q\n%w", t, err)
+	}
+	return r, nil
+}
+
+// HeadRoomSpec returns the headroom specification, preferring HeadRoomSize over the HeadRoom percentage
+func (c Calculator) HeadRoomSpec() RelativeSize {
+	if c.HeadRoomSize != nil {
+		return *c.HeadRoomSize
+	}
+	return RelativeSize{Percent: float64(c.HeadRoom)}
+}
+
+// calculateHeadRoom calculates the head room based on total memory and the headroom specification
+func (c Calculator) calculateHeadRoom(m *MemoryRegions) {
+	h := HeadRoom(c.HeadRoomSpec().Resolve(c.TotalMemory))
+	m.HeadRoom = &h
+}
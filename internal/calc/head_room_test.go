@@ -0,0 +1,82 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestParseHeadRoom(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    RelativeSize
+		expectError bool
+	}{
+		{input: "0", expected: RelativeSize{}},
+		{input: "10", expected: RelativeSize{Percent: 10}},
+		{input: "10%", expected: RelativeSize{Percent: 10}},
+		{input: "300M", expected: RelativeSize{Absolute: Size{Value: 300 * Mebi}}},
+		{
+			input:    "10%,min=128M,max=2G",
+			expected: RelativeSize{Percent: 10, Min: Size{Value: 128 * Mebi}, Max: Size{Value: 2 * Gibi}},
+		},
+		{input: "-1", expectError: true},
+		{input: "101", expectError: true},
+		{input: "lots", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := ParseHeadRoom(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if r != tt.expected {
+				t.Errorf("ParseHeadRoom(%q) = %+v, expected %+v", tt.input, r, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCalculateHeadRoomSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		headRoom string
+		total    int64
+		expected int64
+	}{
+		{"absolute", "300M", 2 * Gibi, 300 * Mebi},
+		{"raised to minimum", "10%,min=128M", 512 * Mebi, 128 * Mebi},
+		{"capped at maximum", "10%,max=2G", 64 * Gibi, 2 * Gibi},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := ParseHeadRoom(tt.headRoom)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := Calculator{
+				HeadRoom:         50, // ignored in favor of HeadRoomSize
+				HeadRoomSize:     &h,
+				LoadedClassCount: 5000,
+				ThreadCount:      20,
+				TotalMemory:      Size{Value: tt.total},
+			}
+
+			result, err := c.Calculate("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.HeadRoom.Value != tt.expected {
+				t.Errorf("Expected headroom %d, got %d", tt.expected, result.HeadRoom.Value)
+			}
+			validateMemoryBounds(t, result, c.TotalMemory.Value, c.ThreadCount)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InitialHeap represents the initial heap size.
+type InitialHeap Size
+
+func (i InitialHeap) String() string {
+	return fmt.Sprintf("-Xms%s", Size(i))
+}
+
+// MatchInitialHeap returns true if the string is an initial heap flag.
+func MatchInitialHeap(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "-Xms")
+}
+
+// ParseInitialHeapPolicy parses an initial heap policy. "max" sets the initial heap to the maximum heap, any other
+// value is a relative size of the maximum heap, e.g. "25%" or "25%,min=256M".
+func ParseInitialHeapPolicy(s string) (RelativeSize, error) {
+	t := strings.TrimSpace(s)
+	if strings.EqualFold(t, "max") {
+		return RelativeSize{Percent: 100}, nil
+	}
+
+	r, err := ParseRelativeSize(t)
+	if err != nil {
+		return RelativeSize{}, fmt.Errorf("unable to parse initial heap policy %q\n%w", t, err)
+	}
+	return r, nil
+}
+
+// calculateInitialHeap derives the initial heap from the heap if an initial heap policy is set and the user did not
+// configure -Xms. The initial heap never exceeds the heap.
+func (c Calculator) calculateInitialHeap(m *MemoryRegions) {
+	if c.InitialHeap == nil || m.userInitialHeap || m.Heap == nil {
+		return
+	}
+
+	s := c.InitialHeap.Resolve(Size(*m.Heap))
+	s.Value = min(s.Value, m.Heap.Value)
+	i := InitialHeap(s)
+	m.InitialHeap = &i
+}
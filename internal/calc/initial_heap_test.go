@@ -0,0 +1,50 @@
+package calc
+
+import "testing"
+
+func TestParseInitialHeapPolicy(t *testing.T) {
+	r, err := ParseInitialHeapPolicy("max")
+	if err != nil || r.Percent != 100 {
+		t.Errorf("Expected 100%% for max, got %+v, %v", r, err)
+	}
+
+	r, err = ParseInitialHeapPolicy("25%,min=256M")
+	if err != nil || r.Percent != 25 || r.Min.Value != 256*Mebi {
+		t.Errorf("Expected 25%% with 256M minimum, got %+v, %v", r, err)
+	}
+
+	if _, err := ParseInitialHeapPolicy("min"); err == nil {
+		t.Error("Expected error for invalid policy")
+	}
+}
+
+func TestCalculateInitialHeap(t *testing.T) {
+	policy := RelativeSize{Percent: 100}
+	c := Calculator{
+		LoadedClassCount: 5000,
+		ThreadCount:      50,
+		InitialHeap:      &policy,
+		TotalMemory:      Size{Value: 2 * Gibi},
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InitialHeap == nil || result.InitialHeap.Value != result.Heap.Value {
+		t.Errorf("Expected initial heap to equal heap %s, got %v", result.Heap, result.InitialHeap)
+	}
+
+	result, err = c.Calculate("-Xms256M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InitialHeap != nil {
+		t.Errorf("Expected configured -Xms to take precedence, got %s", result.InitialHeap)
+	}
+
+	c.InitialHeap = nil
+	if result, _ = c.Calculate(""); result.InitialHeap != nil {
+		t.Errorf("Expected no initial heap without policy, got %s", result.InitialHeap)
+	}
+}
@@ -0,0 +1,36 @@
+package calc
+
+// JavaDefaults holds the memory region defaults that depend on the Java feature version.
+type JavaDefaults struct {
+	// ReservedCodeCache is the code cache reserved by the JVM when it is not configured explicitly.
+	ReservedCodeCache ReservedCodeCache
+
+	// Stack is the thread stack size used when -Xss is not configured explicitly.
+	Stack Stack
+
+	// Metaspace reports whether the JVM sizes class metadata with -XX:MaxMetaspaceSize. Java 7 and earlier keep
+	// class metadata in the permanent generation instead, sized with -XX:MaxPermSize.
+	Metaspace bool
+}
+
+// DefaultsForJavaVersion returns the memory region defaults for the given Java feature version. A version of 0
+// means the version is unknown and yields the defaults of current Java releases.
+//
+// Java 7 and earlier do not enable tiered compilation by default and reserve only 48M of code cache. Java 8 and
+// later reserve 240M for tiered compilation. The default thread stack size on 64-bit platforms is 1M for all
+// versions.
+func DefaultsForJavaVersion(javaVersion int) JavaDefaults {
+	if javaVersion > 0 && javaVersion < 8 {
+		return JavaDefaults{
+			ReservedCodeCache: ReservedCodeCache{Value: 48 * Mebi, Provenance: Default},
+			Stack:             DefaultStack,
+			Metaspace:         false,
+		}
+	}
+
+	return JavaDefaults{
+		ReservedCodeCache: DefaultReservedCodeCache,
+		Stack:             DefaultStack,
+		Metaspace:         true,
+	}
+}
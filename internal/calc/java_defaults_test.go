@@ -0,0 +1,81 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestDefaultsForJavaVersion(t *testing.T) {
+	tests := []struct {
+		name              string
+		javaVersion       int
+		reservedCodeCache int64
+		metaspace         bool
+	}{
+		{"Unknown version", 0, 240 * Mebi, true},
+		{"Java 7", 7, 48 * Mebi, false},
+		{"Java 8", 8, 240 * Mebi, true},
+		{"Java 21", 21, 240 * Mebi, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DefaultsForJavaVersion(tt.javaVersion)
+			if d.ReservedCodeCache.Value != tt.reservedCodeCache {
+				t.Errorf("Expected code cache %d, got %d", tt.reservedCodeCache, d.ReservedCodeCache.Value)
+			}
+			if d.Stack.Value != Mebi {
+				t.Errorf("Expected stack %d, got %d", Mebi, d.Stack.Value)
+			}
+			if d.Metaspace != tt.metaspace {
+				t.Errorf("Expected metaspace %v, got %v", tt.metaspace, d.Metaspace)
+			}
+		})
+	}
+}
+
+func TestCalculateUsesJavaVersionDefaults(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 5000,
+		ThreadCount:      100,
+		TotalMemory:      Size{Value: Gibi},
+		JavaVersion:      7,
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ReservedCodeCache.Value != 48*Mebi {
+		t.Errorf("Expected 48M code cache for Java 7, got %s", result.ReservedCodeCache)
+	}
+	validateMemoryBounds(t, result, c.TotalMemory.Value, c.ThreadCount)
+}
+
+func TestCalculatePermGenJava7(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 5000,
+		ThreadCount:      100,
+		TotalMemory:      Size{Value: Gibi},
+		JavaVersion:      7,
+	}
+
+	result, err := c.Calculate("-XX:MaxPermSize=128M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Metaspace.Value != 128*Mebi || result.Metaspace.Provenance != UserConfigured {
+		t.Errorf("Expected user configured 128M permanent generation, got %s", result.Metaspace)
+	}
+	if s := result.Metaspace.PermGenString(); s != "-XX:MaxPermSize=128M" {
+		t.Errorf("Expected -XX:MaxPermSize=128M, got %s", s)
+	}
+
+	c.JavaVersion = 17
+	if result, err = c.Calculate("-XX:MaxPermSize=128M"); err != nil {
+		t.Fatal(err)
+	}
+	if result.Metaspace.Provenance == UserConfigured {
+		t.Errorf("Expected -XX:MaxPermSize to be ignored for Java 17, got %s", result.Metaspace)
+	}
+}
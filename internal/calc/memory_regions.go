@@ -13,17 +13,56 @@ type MemoryRegions struct {
 	Metaspace         *Metaspace
 	ReservedCodeCache ReservedCodeCache
 	Stack             Stack
+
+	// SharedArchive is the memory mapped for CDS archives. It is zero if no CDS archive is used.
+	SharedArchive SharedArchive
+
+	// NativeAgents is the native memory reserved for detected agents. It is zero if no known agent is used.
+	NativeAgents NativeAgents
+
+	// VirtualThreadStacks is the part of the heap reserved for virtual thread stacks. It is
+	// contained in Heap and therefore not added to any of the region sizes.
+	VirtualThreadStacks VirtualThreadStacks
+
+	// CompilationMode is the JIT compilation mode detected from the JVM flags.
+	CompilationMode CompilationMode
+
+	// CodeHeaps splits ReservedCodeCache into code heaps. It is nil unless a segmented code
+	// cache was requested and applies to the compilation mode.
+	CodeHeaps *CodeHeaps
+
+	// DirectMemoryLibrary names the detected library whose direct memory rule sized DirectMemory.
+	// It is empty when direct memory keeps its default or is configured by the user.
+	DirectMemoryLibrary string
+
+	// ThreadCount is the platform thread count the regions were calculated for. It differs from the
+	// configured thread count if degrade mode reduced it.
+	ThreadCount int
+
+	// Reductions lists the regions degrade mode shrank, in the order they were reduced.
+	Reductions []Reduction
+
+	// InitialHeap is the initial heap derived from Heap. It is nil unless an initial heap policy
+	// is set and -Xms is not configured.
+	InitialHeap *InitialHeap
+
+	// userInitialHeap records that the JVM flags already configure the initial heap.
+	userInitialHeap bool
+
+	// userCodeHeaps records that the JVM flags already configure the code heaps.
+	userCodeHeaps bool
 }
 
-// FixedRegionsSize calculates the size of fixed memory regions (Direct, Metaspace, CodeCache, Stack).
+// FixedRegionsSize calculates the size of fixed memory regions (Direct, Metaspace, SharedArchive, CodeCache, Stack,
+// NativeAgents).
 func (m MemoryRegions) FixedRegionsSize(threadCount int) (Size, error) {
 	if m.Metaspace == nil {
 		return Size{}, fmt.Errorf("unable to calculate fixed regions size without metaspace")
 	}
 
 	return Size{
-		Value: m.DirectMemory.Value + m.Metaspace.Value + m.ReservedCodeCache.Value +
-			(m.Stack.Value * int64(threadCount)),
+		Value: m.DirectMemory.Value + m.Metaspace.Value + m.SharedArchive.Value + m.ReservedCodeCache.Value +
+			(m.Stack.Value * int64(threadCount)) + m.NativeAgents.Value,
 		Provenance: Calculated,
 	}, nil
 }
@@ -36,8 +75,14 @@ func (m MemoryRegions) FixedRegionsString(threadCount int) string {
 	if m.Metaspace != nil {
 		s = append(s, m.Metaspace.String())
 	}
+	if m.SharedArchive.Value > 0 {
+		s = append(s, fmt.Sprintf("%s CDS archive", m.SharedArchive))
+	}
 	s = append(s, m.ReservedCodeCache.String())
 	s = append(s, fmt.Sprintf("%s * %d threads", m.Stack.String(), threadCount))
+	if m.NativeAgents.Value > 0 {
+		s = append(s, fmt.Sprintf("%s native agents", m.NativeAgents))
+	}
 
 	return strings.Join(s, ", ")
 }
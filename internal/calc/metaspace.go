@@ -9,6 +9,9 @@ import (
 // MetaspaceRE is the regular expression for matching metaspace flags.
 var MetaspaceRE = regexp.MustCompile(fmt.Sprintf("^-XX:MaxMetaspaceSize=(%s)$", SizePattern))
 
+// PermGenRE is the regular expression for matching permanent generation flags of Java 7 and earlier.
+var PermGenRE = regexp.MustCompile(fmt.Sprintf("^-XX:MaxPermSize=(%s)$", SizePattern))
+
 // Metaspace represents the metaspace memory size.
 type Metaspace Size
 
@@ -16,6 +19,12 @@ func (m Metaspace) String() string {
 	return fmt.Sprintf("-XX:MaxMetaspaceSize=%s", Size(m))
 }
 
+// PermGenString returns the flag that sizes class metadata on Java 7 and earlier, which keep it in the permanent
+// generation instead of metaspace.
+func (m Metaspace) PermGenString() string {
+	return fmt.Sprintf("-XX:MaxPermSize=%s", Size(m))
+}
+
 // MatchMetaspace returns true if the string matches the metaspace flag pattern.
 func MatchMetaspace(s string) bool {
 	return MetaspaceRE.MatchString(strings.TrimSpace(s))
@@ -36,3 +45,51 @@ func ParseMetaspace(s string) (*Metaspace, error) {
 	m := Metaspace(z)
 	return &m, nil
 }
+
+// MatchPermGen returns true if the string matches the permanent generation flag pattern.
+func MatchPermGen(s string) bool {
+	return PermGenRE.MatchString(strings.TrimSpace(s))
+}
+
+// ParsePermGen parses a permanent generation flag into a Metaspace object, as both size class metadata.
+func ParsePermGen(s string) (*Metaspace, error) {
+	g := PermGenRE.FindStringSubmatch(s)
+	if g == nil {
+		return nil, fmt.Errorf("%s does not match permanent generation pattern %s", s, PermGenRE.String())
+	}
+
+	z, err := ParseSize(g[1])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse permanent generation size\n%w", err)
+	}
+
+	m := Metaspace(z)
+	return &m, nil
+}
+
+// MetaspaceFormula describes how the calculated metaspace is derived, with the actual values, e.g.
+// "14000000 + 12345 classes × 5800 = 85601000". Classes loaded from CDS archives are subtracted, e.g.
+// "14000000 + (12345 - 1300 shared) classes × 5800 = 78061000".
+func (c Calculator) MetaspaceFormula() string {
+	o, z, n := c.classOverhead(), c.classSize(), int64(c.metaspaceClassCount())
+	if c.SharedClassCount > 0 {
+		return fmt.Sprintf("%d + (%d - %d shared) classes × %d = %d", o, c.LoadedClassCount, c.SharedClassCount, z, o+n*z)
+	}
+	return fmt.Sprintf("%d + %d classes × %d = %d", o, c.LoadedClassCount, z, o+n*z)
+}
+
+// classSize returns the metaspace footprint of a single class, falling back to ClassSize.
+func (c Calculator) classSize() int64 {
+	if c.ClassSize.Value > 0 {
+		return c.ClassSize.Value
+	}
+	return ClassSize
+}
+
+// classOverhead returns the metaspace footprint of the JVM itself, falling back to ClassOverhead.
+func (c Calculator) classOverhead() int64 {
+	if c.ClassOverhead.Value > 0 {
+		return c.ClassOverhead.Value
+	}
+	return ClassOverhead
+}
@@ -0,0 +1,105 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NativeAgentRule reserves native memory for an agent known to allocate memory outside of the JVM regions, like the
+// native agents of application performance monitoring tools and profilers.
+type NativeAgentRule struct {
+	// Agent is the name of the agent the rule applies to, e.g. "dynatrace".
+	Agent string
+
+	// Patterns are matched against the lower case path of -agentpath and -javaagent options and the library name of
+	// -agentlib options. An agent matches if its path contains any of the patterns. Default: Agent.
+	Patterns []string
+
+	// Size is the native memory the agent needs.
+	Size Size
+}
+
+// DefaultNativeAgentRules are the built-in native memory rules for well-known agents.
+var DefaultNativeAgentRules = []NativeAgentRule{
+	{Agent: "dynatrace", Patterns: []string{"dynatrace", "oneagent"}, Size: Size{Value: 128 * Mebi}},
+	{Agent: "appdynamics", Size: Size{Value: 64 * Mebi}},
+	{Agent: "async-profiler", Patterns: []string{"asyncprofiler", "async-profiler"}, Size: Size{Value: 32 * Mebi}},
+}
+
+// NativeAgents represents the native memory reserved for the detected agents.
+type NativeAgents Size
+
+func (n NativeAgents) String() string {
+	return Size(n).String()
+}
+
+// Matches returns true if the rule applies to the agent at path.
+func (r NativeAgentRule) Matches(path string) bool {
+	p := strings.ToLower(path)
+	if len(r.Patterns) == 0 {
+		return strings.Contains(p, strings.ToLower(r.Agent))
+	}
+	for _, s := range r.Patterns {
+		if strings.Contains(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNativeAgentRules parses semicolon-separated native agent rules of the form <agent>=<size>, e.g.
+// "dynatrace=256M;my-agent=16M". The agent name is matched against agent paths.
+func ParseNativeAgentRules(s string) ([]NativeAgentRule, error) {
+	var rules []NativeAgentRule
+
+	for _, r := range strings.Split(s, ";") {
+		if strings.TrimSpace(r) == "" {
+			continue
+		}
+
+		agent, size, ok := strings.Cut(r, "=")
+		agent = strings.TrimSpace(agent)
+		if !ok || agent == "" {
+			return nil, fmt.Errorf("native agent rule %q must have the form <agent>=<size>", r)
+		}
+
+		z, err := ParseSize(strings.TrimSpace(size))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse native agent rule for %s\n%w", agent, err)
+		}
+
+		rules = append(rules, NativeAgentRule{Agent: agent, Size: z})
+	}
+
+	return rules, nil
+}
+
+// MergeNativeAgentRules returns the rules with the overrides applied. An override replaces the size of the rule for
+// the same agent, keeping its patterns, or is appended if there is none.
+func MergeNativeAgentRules(rules []NativeAgentRule, overrides []NativeAgentRule) []NativeAgentRule {
+	merged := append([]NativeAgentRule(nil), rules...)
+
+	for _, o := range overrides {
+		found := false
+		for i := range merged {
+			if merged[i].Agent == o.Agent {
+				merged[i].Size = o.Size
+				found = true
+			}
+		}
+		if !found {
+			merged = append(merged, o)
+		}
+	}
+
+	return merged
+}
+
+// calculateNativeAgents reserves the native memory of the detected agents
+func (c Calculator) calculateNativeAgents(m *MemoryRegions) {
+	n := NativeAgents{Provenance: Calculated}
+	for _, r := range c.NativeAgentRules {
+		n.Value += r.Size.Value
+	}
+	m.NativeAgents = n
+}
@@ -0,0 +1,91 @@
+package calc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNativeAgentRules(t *testing.T) {
+	rules, err := ParseNativeAgentRules("dynatrace=256M; my-agent=16M;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Agent != "dynatrace" || rules[0].Size.Value != 256*Mebi ||
+		rules[1].Agent != "my-agent" || rules[1].Size.Value != 16*Mebi {
+		t.Errorf("Unexpected rules %+v", rules)
+	}
+
+	for _, s := range []string{"dynatrace", "=16M", "dynatrace=lots"} {
+		if _, err := ParseNativeAgentRules(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
+
+func TestMergeNativeAgentRules(t *testing.T) {
+	merged := MergeNativeAgentRules(DefaultNativeAgentRules, []NativeAgentRule{
+		{Agent: "dynatrace", Size: Size{Value: 256 * Mebi}},
+		{Agent: "my-agent", Size: Size{Value: 16 * Mebi}},
+	})
+
+	if len(merged) != len(DefaultNativeAgentRules)+1 {
+		t.Fatalf("Expected %d rules, got %d", len(DefaultNativeAgentRules)+1, len(merged))
+	}
+	if merged[0].Size.Value != 256*Mebi || !merged[0].Matches("/opt/dynatrace/oneagent/agent/lib64/liboneagentloader.so") {
+		t.Errorf("Expected the dynatrace override to keep its patterns, got %+v", merged[0])
+	}
+	if DefaultNativeAgentRules[0].Size.Value != 128*Mebi {
+		t.Error("Expected the default rules to be unchanged")
+	}
+}
+
+func TestNativeAgentRuleMatches(t *testing.T) {
+	tests := []struct {
+		agent    string
+		path     string
+		expected bool
+	}{
+		{"dynatrace", "/opt/dynatrace/oneagent/agent/lib64/liboneagentloader.so", true},
+		{"appdynamics", "/opt/appdynamics/javaagent.jar", true},
+		{"async-profiler", "/opt/async-profiler/lib/libasyncProfiler.so", true},
+		{"async-profiler", "asyncProfiler", true},
+		{"async-profiler", "jdwp", false},
+	}
+
+	for _, tt := range tests {
+		for _, r := range DefaultNativeAgentRules {
+			if r.Agent == tt.agent && r.Matches(tt.path) != tt.expected {
+				t.Errorf("Expected %s matching %s to be %t", r.Agent, tt.path, tt.expected)
+			}
+		}
+	}
+}
+
+func TestCalculateNativeAgents(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 10_000,
+		ThreadCount:      50,
+		TotalMemory:      Size{Value: 2 * Gibi},
+	}
+	baseline, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.NativeAgentRules = DefaultNativeAgentRules[:2]
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.NativeAgents.Value != 192*Mebi {
+		t.Errorf("Expected native agents %d, got %d", 192*Mebi, result.NativeAgents.Value)
+	}
+	if result.Heap.Value != baseline.Heap.Value-192*Mebi {
+		t.Errorf("Expected heap %d, got %d", baseline.Heap.Value-192*Mebi, result.Heap.Value)
+	}
+	if s := result.FixedRegionsString(c.ThreadCount); !strings.Contains(s, "192M native agents") {
+		t.Errorf("Expected fixed regions to list the native agents, got %s", s)
+	}
+	validateMemoryBounds(t, result, c.TotalMemory.Value, c.ThreadCount)
+}
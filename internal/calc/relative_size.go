@@ -0,0 +1,107 @@
+package calc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RelativeSize is a memory size that is either a percentage of total memory or an absolute size, optionally bounded
+// by a minimum and a maximum. Its textual form is a comma-separated list of a value and optional bounds, e.g. "10%",
+// "300M" or "10%,min=128M,max=2G".
+type RelativeSize struct {
+	// Percent is the share of total memory in percent. It is ignored when Absolute is set.
+	Percent float64
+
+	// Absolute is a fixed size that does not depend on total memory.
+	Absolute Size
+
+	// Min is the lower bound of the resolved size. A zero value means no lower bound.
+	Min Size
+
+	// Max is the upper bound of the resolved size. A zero value means no upper bound.
+	Max Size
+}
+
+// ParseRelativeSize parses a relative size such as "10%", "300M" or "10%,min=128M,max=2G".
+func ParseRelativeSize(s string) (RelativeSize, error) {
+	parts := strings.Split(strings.TrimSpace(s), ",")
+
+	r := RelativeSize{}
+	v := strings.TrimSpace(parts[0])
+	if p, ok := strings.CutSuffix(v, "%"); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || f < 0 || f > 100 {
+			return RelativeSize{}, fmt.Errorf("percentage %q must be a number between 0 and 100", v)
+		}
+		r.Percent = f
+	} else {
+		z, err := ParseSize(v)
+		if err != nil {
+			return RelativeSize{}, fmt.Errorf("unable to parse size %q\n%w", v, err)
+		}
+		r.Absolute = z
+	}
+
+	for _, b := range parts[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(b), "=")
+		if !ok {
+			return RelativeSize{}, fmt.Errorf("bound %q must have the form min=<size> or max=<size>", b)
+		}
+
+		z, err := ParseSize(value)
+		if err != nil {
+			return RelativeSize{}, fmt.Errorf("unable to parse %s bound\n%w", key, err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "min":
+			r.Min = z
+		case "max":
+			r.Max = z
+		default:
+			return RelativeSize{}, fmt.Errorf("unknown bound %q, expected min or max", key)
+		}
+	}
+
+	if r.Max.Value > 0 && r.Min.Value > r.Max.Value {
+		return RelativeSize{}, fmt.Errorf("minimum %s is larger than maximum %s", r.Min, r.Max)
+	}
+
+	return r, nil
+}
+
+// Resolve returns the size relative to the given total memory, clamped to the bounds.
+func (r RelativeSize) Resolve(total Size) Size {
+	v := r.Absolute.Value
+	if v == 0 {
+		v = int64(float64(total.Value) * r.Percent / 100)
+	}
+
+	if r.Max.Value > 0 && v > r.Max.Value {
+		v = r.Max.Value
+	}
+	if v < r.Min.Value {
+		v = r.Min.Value
+	}
+
+	return Size{Value: v, Provenance: Calculated}
+}
+
+func (r RelativeSize) String() string {
+	var s []string
+
+	if r.Absolute.Value > 0 {
+		s = append(s, r.Absolute.String())
+	} else {
+		s = append(s, strconv.FormatFloat(r.Percent, 'f', -1, 64)+"%")
+	}
+	if r.Min.Value > 0 {
+		s = append(s, "min="+r.Min.String())
+	}
+	if r.Max.Value > 0 {
+		s = append(s, "max="+r.Max.String())
+	}
+
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,86 @@
+package calc
+
+import (
+	"testing"
+)
+
+func TestParseRelativeSize(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    RelativeSize
+		expectError bool
+	}{
+		{input: "10%", expected: RelativeSize{Percent: 10}},
+		{input: "2.5%", expected: RelativeSize{Percent: 2.5}},
+		{input: "300M", expected: RelativeSize{Absolute: Size{Value: 300 * Mebi}}},
+		{
+			input:    "10%,min=128M,max=2G",
+			expected: RelativeSize{Percent: 10, Min: Size{Value: 128 * Mebi}, Max: Size{Value: 2 * Gibi}},
+		},
+		{input: " 5% , min=64M ", expected: RelativeSize{Percent: 5, Min: Size{Value: 64 * Mebi}}},
+		{input: "", expectError: true},
+		{input: "abc", expectError: true},
+		{input: "101%", expectError: true},
+		{input: "-1%", expectError: true},
+		{input: "10%,min", expectError: true},
+		{input: "10%,avg=1G", expectError: true},
+		{input: "10%,min=2G,max=1G", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := ParseRelativeSize(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if r != tt.expected {
+				t.Errorf("ParseRelativeSize(%q) = %+v, expected %+v", tt.input, r, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRelativeSizeResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     string
+		total    int64
+		expected int64
+	}{
+		{"percentage", "10%", 1 * Gibi, 1 * Gibi / 10},
+		{"absolute", "300M", 1 * Gibi, 300 * Mebi},
+		{"raised to minimum", "10%,min=128M", 512 * Mebi, 128 * Mebi},
+		{"capped at maximum", "10%,max=2G", 64 * Gibi, 2 * Gibi},
+		{"within bounds", "10%,min=128M,max=2G", 4 * Gibi, 4 * Gibi / 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRelativeSize(tt.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := r.Resolve(Size{Value: tt.total}); actual.Value != tt.expected {
+				t.Errorf("Resolve(%d) = %d, expected %d", tt.total, actual.Value, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRelativeSizeString(t *testing.T) {
+	for _, s := range []string{"10%", "2.5%", "300M", "10%,min=128M,max=2G"} {
+		r, err := ParseRelativeSize(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.String() != s {
+			t.Errorf("Expected %q, got %q", s, r.String())
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package calc
+
+// SharedArchive represents the memory mapped for class data sharing (CDS) archives. Classes loaded from the archives
+// are not allocated in metaspace, but the archives are mapped into the JVM process.
+type SharedArchive Size
+
+func (s SharedArchive) String() string {
+	return Size(s).String()
+}
+
+// calculateSharedArchive reserves the memory of the mapped CDS archives
+func (c Calculator) calculateSharedArchive(m *MemoryRegions) {
+	m.SharedArchive = SharedArchive{Value: c.SharedArchiveSize.Value, Provenance: Calculated}
+}
+
+// metaspaceClassCount returns the number of loaded classes allocated in metaspace, those not loaded from CDS archives
+func (c Calculator) metaspaceClassCount() int {
+	return max(c.LoadedClassCount-c.SharedClassCount, 0)
+}
@@ -0,0 +1,66 @@
+package calc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCalculateSharedArchive(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount: 10_000,
+		ThreadCount:      50,
+		TotalMemory:      Size{Value: 2 * Gibi},
+	}
+	baseline, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.SharedClassCount = 2_000
+	c.SharedArchiveSize = Size{Value: 30 * Mebi}
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ClassOverhead + 8_000*ClassSize
+	if result.Metaspace.Value != expected {
+		t.Errorf("Expected metaspace %d without shared classes, got %d", expected, result.Metaspace.Value)
+	}
+	if result.SharedArchive.Value != 30*Mebi {
+		t.Errorf("Expected CDS archive %d, got %d", 30*Mebi, result.SharedArchive.Value)
+	}
+
+	// The mapped archive is reserved in place of the metaspace of the shared classes
+	heap := baseline.Heap.Value + 2_000*ClassSize - 30*Mebi
+	if result.Heap.Value != heap {
+		t.Errorf("Expected heap %d, got %d", heap, result.Heap.Value)
+	}
+	if s := result.FixedRegionsString(c.ThreadCount); !strings.Contains(s, "30M CDS archive") {
+		t.Errorf("Expected fixed regions to list the CDS archive, got %s", s)
+	}
+	validateMemoryBounds(t, result, c.TotalMemory.Value, c.ThreadCount)
+
+	if f := c.MetaspaceFormula(); !strings.Contains(f, "(10000 - 2000 shared) classes") {
+		t.Errorf("Expected metaspace formula to subtract shared classes, got %s", f)
+	}
+}
+
+func TestCalculateSharedArchiveUserMetaspace(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount:  10_000,
+		SharedClassCount:  2_000,
+		SharedArchiveSize: Size{Value: 30 * Mebi},
+		ThreadCount:       50,
+		TotalMemory:       Size{Value: 2 * Gibi},
+	}
+
+	result, err := c.Calculate("-XX:MaxMetaspaceSize=128M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Metaspace.Value != 128*Mebi || result.SharedArchive.Value != 30*Mebi {
+		t.Errorf("Expected configured metaspace and CDS archive, got %d and %d",
+			result.Metaspace.Value, result.SharedArchive.Value)
+	}
+}
@@ -52,6 +52,10 @@ const (
 	// Calculated indicates the size value was computed by the memory calculator
 	// based on available resources and allocation algorithms
 	Calculated
+
+	// Measured indicates the value was observed in a previous run of the application,
+	// such as the classes listed in a class load log
+	Measured
 )
 
 // Size represents a memory size value with provenance tracking and unit conversion capabilities.
@@ -0,0 +1,40 @@
+package calc
+
+import (
+	"fmt"
+)
+
+// DefaultVirtualThreadStack is the estimated heap footprint of a single virtual thread stack (16K). Virtual thread
+// stacks are stored as stack chunk objects on the heap and grow with the call depth of the parked thread.
+var DefaultVirtualThreadStack = Size{Value: 16 * Kibi, Provenance: Default}
+
+// VirtualThreadStacks represents the heap reserved for the stacks of concurrently live virtual threads.
+type VirtualThreadStacks Size
+
+func (v VirtualThreadStacks) String() string {
+	return Size(v).String()
+}
+
+// calculateVirtualThreadStacks estimates the heap needed by the stacks of the expected virtual threads
+func (c Calculator) calculateVirtualThreadStacks(m *MemoryRegions) {
+	stack := c.VirtualThreadStack
+	if stack.Value <= 0 {
+		stack = DefaultVirtualThreadStack
+	}
+
+	m.VirtualThreadStacks = VirtualThreadStacks{
+		Value:      stack.Value * int64(c.VirtualThreadCount),
+		Provenance: Calculated,
+	}
+}
+
+// validateVirtualThreadStacks validates that the heap can hold the estimated virtual thread stacks
+func (c Calculator) validateVirtualThreadStacks(m *MemoryRegions) error {
+	if m.Heap == nil || m.VirtualThreadStacks.Value <= m.Heap.Value {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"heap of %s cannot hold the estimated %s of stacks for %d virtual threads",
+		Size(*m.Heap), m.VirtualThreadStacks, c.VirtualThreadCount)
+}
@@ -0,0 +1,73 @@
+package calc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCalculateVirtualThreadStacks(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount:   5000,
+		ThreadCount:        50,
+		VirtualThreadCount: 10_000,
+		TotalMemory:        Size{Value: 2 * Gibi},
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := int64(10_000) * DefaultVirtualThreadStack.Value
+	if result.VirtualThreadStacks.Value != expected {
+		t.Errorf("Expected virtual thread stacks %d, got %d", expected, result.VirtualThreadStacks.Value)
+	}
+
+	// Virtual thread stacks are part of the heap, not an additional region
+	withoutVirtualThreads := c
+	withoutVirtualThreads.VirtualThreadCount = 0
+	baseline, err := withoutVirtualThreads.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Heap.Value != baseline.Heap.Value {
+		t.Errorf("Expected heap %d to be unchanged by virtual threads, got %d", baseline.Heap.Value, result.Heap.Value)
+	}
+	validateMemoryBounds(t, result, c.TotalMemory.Value, c.ThreadCount)
+}
+
+func TestCalculateVirtualThreadStackSize(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount:   5000,
+		ThreadCount:        50,
+		VirtualThreadCount: 1_000,
+		VirtualThreadStack: Size{Value: 64 * Kibi},
+		TotalMemory:        Size{Value: 2 * Gibi},
+	}
+
+	result, err := c.Calculate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.VirtualThreadStacks.Value != 1_000*64*Kibi {
+		t.Errorf("Expected virtual thread stacks %d, got %d", 1_000*64*Kibi, result.VirtualThreadStacks.Value)
+	}
+}
+
+func TestCalculateVirtualThreadStacksExceedHeap(t *testing.T) {
+	c := Calculator{
+		LoadedClassCount:   5000,
+		ThreadCount:        50,
+		VirtualThreadCount: 100_000,
+		TotalMemory:        Size{Value: 2 * Gibi},
+	}
+
+	_, err := c.Calculate("-Xmx512m")
+	if err == nil {
+		t.Fatal("Expected error when virtual thread stacks exceed the heap")
+	}
+	if !strings.Contains(err.Error(), "virtual threads") {
+		t.Errorf("Expected virtual thread error, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package calculator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/count"
+	"github.com/patbaumgartner/memory-calculator/internal/parser"
+)
+
+// agents holds the agents and boot class path entries configured in JVM options.
+type agents struct {
+	// java holds the jar paths of -javaagent options.
+	java []string
+
+	// native holds the library paths of -agentpath options and the library names of -agentlib options.
+	native []string
+
+	// bootClasspath holds the jars and directories appended or prepended to the boot class path.
+	bootClasspath []string
+}
+
+// parseAgents returns the agents and boot class path entries of the given JVM options. Agent options, e.g. the
+// "=config.yml" of "-javaagent:agent.jar=config.yml", are not part of the paths, and relative paths are resolved with
+// resolvePath.
+func parseAgents(options []string) agents {
+	var a agents
+	for _, o := range options {
+		switch {
+		case strings.HasPrefix(o, "-javaagent:"):
+			path, _, _ := strings.Cut(strings.TrimPrefix(o, "-javaagent:"), "=")
+			a.java = append(a.java, resolvePath(path))
+		case strings.HasPrefix(o, "-agentpath:"):
+			path, _, _ := strings.Cut(strings.TrimPrefix(o, "-agentpath:"), "=")
+			a.native = append(a.native, resolvePath(path))
+		case strings.HasPrefix(o, "-agentlib:"):
+			name, _, _ := strings.Cut(strings.TrimPrefix(o, "-agentlib:"), "=")
+			a.native = append(a.native, name)
+		case strings.HasPrefix(o, "-Xbootclasspath/a:"), strings.HasPrefix(o, "-Xbootclasspath/p:"):
+			for _, p := range filepath.SplitList(o[len("-Xbootclasspath/a:"):]) {
+				if p != "" {
+					a.bootClasspath = append(a.bootClasspath, resolvePath(p))
+				}
+			}
+		}
+	}
+	return a
+}
+
+// classPaths returns the jars and directories holding the classes of the agents and the boot class path.
+func (a agents) classPaths() []string {
+	return append(append([]string(nil), a.java...), a.bootClasspath...)
+}
+
+// resolvePath resolves a relative path against the working directory if it exists there, and against the application
+// path otherwise.
+func resolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return filepath.Join(applicationPath(), path)
+}
+
+// CountAgentClasses counts the classes in agent jars and in the jars and directories of the boot class path.
+func (m MemoryCalculator) CountAgentClasses(opts string) (int, error) {
+	p, err := parser.SplitFlags(opts)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse JVM options\n%w", err)
+	}
+
+	var jars []string
+	agentClassCount := 0
+	for _, path := range parseAgents(p).classPaths() {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			n, err := count.Classes(path)
+			if err != nil {
+				return 0, fmt.Errorf("error counting boot class path classes\n%w", err)
+			}
+			agentClassCount += n
+			continue
+		}
+		jars = append(jars, path)
+	}
+	if len(jars) == 0 {
+		return agentClassCount, nil
+	}
+
+	n, skippedAgents, err := count.JarClassesFrom(jars...)
+	if err != nil {
+		return 0, fmt.Errorf("error counting agent jar classes \n%w", err)
+	} else if skippedAgents > 0 {
+		m.Logger.Infof(
+			`WARNING: could not count classes from all agent jars (skipped %d), `+
+				`class count and metaspace may not be sized correctly`, skippedAgents)
+	}
+	return agentClassCount + n, nil
+}
+
+// parseNativeAgentConfig reserves native memory for the agents in the JVM options that match a native agent rule.
+// The built-in rules can be overridden in $BPL_JVM_NATIVE_AGENT_RULES. Each rule applies once, however many agents
+// match it.
+func (m MemoryCalculator) parseNativeAgentConfig(c *calc.Calculator, opts string) error {
+	rules := calc.DefaultNativeAgentRules
+	if s, ok := os.LookupEnv("BPL_JVM_NATIVE_AGENT_RULES"); ok {
+		overrides, err := calc.ParseNativeAgentRules(s)
+		if err != nil {
+			return fmt.Errorf("unable to parse $BPL_JVM_NATIVE_AGENT_RULES=%s\n%w", s, err)
+		}
+		rules = calc.MergeNativeAgentRules(rules, overrides)
+	}
+
+	p, err := parser.SplitFlags(opts)
+	if err != nil {
+		return fmt.Errorf("unable to parse JVM options\n%w", err)
+	}
+	a := parseAgents(p)
+
+	for _, r := range rules {
+		for _, path := range append(a.native, a.java...) {
+			if r.Matches(path) {
+				m.Logger.Infof("Detected agent %s, reserving %s of native memory", r.Agent, r.Size)
+				c.NativeAgentRules = append(c.NativeAgentRules, r)
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package calculator
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+)
+
+// writeJar writes a jar holding the given class files.
+func writeJar(t *testing.T, path string, classes ...string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	for _, c := range classes {
+		if _, err := z.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseAgents(t *testing.T) {
+	appPath := t.TempDir()
+	_ = os.Setenv("BPI_APPLICATION_PATH", appPath)
+	defer func() { _ = os.Unsetenv("BPI_APPLICATION_PATH") }()
+
+	a := parseAgents([]string{
+		"-javaagent:/opt/otel/opentelemetry-javaagent.jar=otel.exporter=http://collector:4318",
+		"-javaagent:agents/elastic-apm-agent.jar",
+		"-agentpath:/opt/async-profiler/lib/libasyncProfiler.so=start,event=cpu,file=/tmp/profile.html",
+		"-agentlib:jdwp=transport=dt_socket,server=y,address=*:5005",
+		"-Xbootclasspath/a:/opt/boot/a.jar:/opt/boot/classes",
+		"-Xmx1G",
+	})
+
+	expected := agents{
+		java: []string{
+			"/opt/otel/opentelemetry-javaagent.jar",
+			filepath.Join(appPath, "agents", "elastic-apm-agent.jar"),
+		},
+		native:        []string{"/opt/async-profiler/lib/libasyncProfiler.so", "jdwp"},
+		bootClasspath: []string{"/opt/boot/a.jar", "/opt/boot/classes"},
+	}
+	if !reflect.DeepEqual(a, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, a)
+	}
+}
+
+func TestCountAgentClassesBootClasspath(t *testing.T) {
+	mc := Create(true)
+
+	dir := t.TempDir()
+	writeJar(t, filepath.Join(dir, "agent.jar"), "com/example/Agent.class", "com/example/Transformer.class")
+	writeJar(t, filepath.Join(dir, "boot.jar"), "com/example/Boot.class")
+	classes := filepath.Join(dir, "classes")
+	if err := os.MkdirAll(classes, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(classes, "Patch.class"), []byte("class"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := "-javaagent:" + filepath.Join(dir, "agent.jar") + "=config=a:b " +
+		"-Xbootclasspath/a:" + filepath.Join(dir, "boot.jar") + string(os.PathListSeparator) + classes
+	n, err := mc.CountAgentClasses(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("Expected 4 agent and boot class path classes, got %d", n)
+	}
+}
+
+func TestParseNativeAgentConfig(t *testing.T) {
+	mc := Create(true)
+	defer func() { _ = os.Unsetenv("BPL_JVM_NATIVE_AGENT_RULES") }()
+
+	opts := "-agentpath:/opt/dynatrace/oneagent/agent/lib64/liboneagentloader.so " +
+		"-agentpath:/opt/dynatrace/oneagent/agent/lib64/liboneagentjava.so -agentlib:jdwp"
+	c := calc.Calculator{}
+	if err := mc.parseNativeAgentConfig(&c, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.NativeAgentRules) != 1 || c.NativeAgentRules[0].Agent != "dynatrace" ||
+		c.NativeAgentRules[0].Size.Value != 128*calc.Mebi {
+		t.Errorf("Expected the dynatrace rule once, got %+v", c.NativeAgentRules)
+	}
+
+	_ = os.Setenv("BPL_JVM_NATIVE_AGENT_RULES", "dynatrace=256M;jdwp=8M")
+	c = calc.Calculator{}
+	if err := mc.parseNativeAgentConfig(&c, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.NativeAgentRules) != 2 || c.NativeAgentRules[0].Size.Value != 256*calc.Mebi ||
+		c.NativeAgentRules[1].Agent != "jdwp" {
+		t.Errorf("Expected the overridden dynatrace rule and the jdwp rule, got %+v", c.NativeAgentRules)
+	}
+
+	_ = os.Setenv("BPL_JVM_NATIVE_AGENT_RULES", "dynatrace")
+	if err := mc.parseNativeAgentConfig(&calc.Calculator{}, opts); err == nil {
+		t.Error("Expected an error for an invalid rule")
+	}
+}
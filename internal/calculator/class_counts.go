@@ -0,0 +1,222 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/patbaumgartner/memory-calculator/internal/count"
+	"github.com/patbaumgartner/memory-calculator/internal/parser"
+)
+
+// CountClasses counts the classes of the application, its agents and the JVM as configured in the environment, and
+// fingerprints the counted files, for recording the counts in a lock file ahead of time.
+func (m MemoryCalculator) CountClasses() (*count.Lock, error) {
+	_, user, err := userOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := m.profileOptions(user)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := m.countClasses(opts)
+	if err != nil {
+		return nil, err
+	}
+	if l.Fingerprint, err = fingerprint(opts); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// classCounts returns the class counts recorded in the lock file if it matches the application, and counts the
+// classes otherwise. $BPI_CLASS_COUNT_LOCK_TRUST skips comparing the fingerprint of the lock file.
+func (m MemoryCalculator) classCounts(opts string) (*count.Lock, error) {
+	path := filepath.Join(applicationPath(), count.LockFile)
+	if s, ok := os.LookupEnv("BPI_CLASS_COUNT_LOCK"); ok && s != "" {
+		path = s
+	}
+
+	l, err := count.ReadLock(path)
+	if err != nil {
+		m.Logger.Infof("WARNING: Ignoring class count lock file, counting classes: %v", err)
+		return m.countClasses(opts)
+	} else if l == nil {
+		return m.countClasses(opts)
+	}
+
+	trust := false
+	if s, ok := os.LookupEnv("BPI_CLASS_COUNT_LOCK_TRUST"); ok {
+		if trust, err = strconv.ParseBool(s); err != nil {
+			return nil, fmt.Errorf("unable to convert $BPI_CLASS_COUNT_LOCK_TRUST=%s to boolean\n%w", s, err)
+		}
+	}
+
+	if !trust {
+		matches, err := lockMatches(l, opts)
+		if err != nil {
+			return nil, err
+		}
+		if !matches {
+			m.Logger.Infof("WARNING: Class count lock file %s does not match the application, counting classes", path)
+			return m.countClasses(opts)
+		}
+	}
+
+	if _, ok := os.LookupEnv("BPI_JVM_CLASS_COUNT"); ok {
+		if l.JVM, err = m.jvmClassCount(); err != nil {
+			return nil, err
+		}
+	}
+	m.Logger.Infof("Using class counts of %s", path)
+	return l, nil
+}
+
+// countClasses counts the classes of the application, its agents and the JVM as configured in the environment.
+func (m MemoryCalculator) countClasses(opts string) (*count.Lock, error) {
+	jvm, err := m.jvmClassCount()
+	if err != nil {
+		return nil, err
+	}
+
+	agents, err := m.CountAgentClasses(opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine agent class count\n%w", err)
+	}
+
+	options, err := scanOptions()
+	if err != nil {
+		return nil, err
+	}
+	if options.Cache, err = m.classCountCache(); err != nil {
+		return nil, err
+	}
+
+	appPath := applicationPath()
+	scan, err := count.ScanWith(appPath, options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine class count\n%w", err)
+	}
+	if scan, err = m.scanAppServers(scan, appPath, options); err != nil {
+		return nil, err
+	}
+	if options.Cache != nil {
+		if err := options.Cache.Save(); err != nil {
+			m.Logger.Infof("WARNING: Unable to save class count cache: %v", err)
+		}
+	}
+	if options.Unique {
+		m.Logger.Infof("Counted %d class files, %d unique classes", scan.Classes, scan.UniqueClasses)
+	}
+
+	return &count.Lock{
+		NestedJarDepth: options.NestedJarDepth,
+		Unique:         options.Unique,
+		Application:    scan,
+		Agents:         agents,
+		JVM:            jvm,
+	}, nil
+}
+
+// lockMatches returns true if the lock file was recorded with the current scan options for the files counted now.
+func lockMatches(l *count.Lock, opts string) (bool, error) {
+	options, err := scanOptions()
+	if err != nil {
+		return false, err
+	}
+	if l.NestedJarDepth != options.NestedJarDepth || l.Unique != options.Unique {
+		return false, nil
+	}
+
+	f, err := fingerprint(opts)
+	if err != nil {
+		return false, err
+	}
+	return f == l.Fingerprint, nil
+}
+
+// fingerprint returns the fingerprint of the application path, the application server directories, the agent jars and
+// the boot class path.
+func fingerprint(opts string) (string, error) {
+	p, err := parser.SplitFlags(opts)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse JVM options\n%w", err)
+	}
+
+	appPath := applicationPath()
+	paths := []string{appPath}
+	for _, d := range appServerDirs(appPath) {
+		paths = append(paths, d.path)
+	}
+	paths = append(paths, parseAgents(p).classPaths()...)
+
+	f, err := count.Fingerprint(paths...)
+	if err != nil {
+		return "", fmt.Errorf("unable to fingerprint application\n%w", err)
+	}
+	return f, nil
+}
+
+// applicationPath returns the application path configured in $BPI_APPLICATION_PATH, /app by default.
+func applicationPath() string {
+	if path, ok := os.LookupEnv("BPI_APPLICATION_PATH"); ok {
+		return path
+	}
+	return "/app"
+}
+
+// DefaultJVMClassCount is the number of JVM classes assumed if the Java runtime cannot be read.
+const DefaultJVMClassCount = 1000
+
+// jvmClassCount returns the number of JVM classes configured in $BPI_JVM_CLASS_COUNT. Otherwise the classes of the
+// Java runtime at $JAVA_HOME are counted, falling back to DefaultJVMClassCount.
+func (m MemoryCalculator) jvmClassCount() (int, error) {
+	if s, ok := os.LookupEnv("BPI_JVM_CLASS_COUNT"); ok {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("unable to convert $BPI_JVM_CLASS_COUNT=%s to integer\n%w", s, err)
+		}
+		return n, nil
+	}
+
+	javaHome, ok := os.LookupEnv("JAVA_HOME")
+	if !ok || javaHome == "" {
+		return DefaultJVMClassCount, nil
+	}
+
+	n, err := count.JVMClasses(javaHome)
+	if errors.Is(err, fs.ErrNotExist) {
+		return DefaultJVMClassCount, nil
+	} else if err != nil {
+		m.Logger.Infof("WARNING: Unable to count JVM classes, assuming %d: %v", DefaultJVMClassCount, err)
+		return DefaultJVMClassCount, nil
+	}
+	m.Logger.Debugf("Counted %d JVM classes in %s", n, javaHome)
+	return n, nil
+}
+
+// scanOptions returns the class scanning options configured in the environment, without a cache.
+func scanOptions() (count.Options, error) {
+	options := count.Options{}
+	if s, ok := os.LookupEnv("BPI_NESTED_JAR_DEPTH"); ok {
+		depth, err := strconv.Atoi(s)
+		if err != nil {
+			return count.Options{}, fmt.Errorf("unable to convert $BPI_NESTED_JAR_DEPTH=%s to integer\n%w", s, err)
+		}
+		options.NestedJarDepth = depth
+	}
+	if s, ok := os.LookupEnv("BPI_SCAN_WORKERS"); ok {
+		workers, err := strconv.Atoi(s)
+		if err != nil {
+			return count.Options{}, fmt.Errorf("unable to convert $BPI_SCAN_WORKERS=%s to integer\n%w", s, err)
+		}
+		options.Workers = workers
+	}
+	options.Unique = os.Getenv("BPI_CLASS_COUNT_MODE") == count.UniqueMode
+	return options, nil
+}
@@ -0,0 +1,110 @@
+package calculator
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/patbaumgartner/memory-calculator/internal/count"
+)
+
+func TestClassCountsLockFile(t *testing.T) {
+	mc := Create(true)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "App.class"), []byte("class"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = os.Setenv("BPI_APPLICATION_PATH", dir)
+	_ = os.Setenv("BPI_CLASS_COUNT_NO_CACHE", "true")
+	defer func() {
+		_ = os.Unsetenv("BPI_APPLICATION_PATH")
+		_ = os.Unsetenv("BPI_CLASS_COUNT_NO_CACHE")
+		_ = os.Unsetenv("BPI_CLASS_COUNT_LOCK_TRUST")
+		_ = os.Unsetenv("BPI_JVM_CLASS_COUNT")
+	}()
+
+	l, err := mc.CountClasses()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if l.Application.Classes != 1 || l.JVM != 1000 || l.Fingerprint == "" {
+		t.Fatalf("Unexpected class counts %+v", l)
+	}
+
+	// Record a different count to tell the lock file from a scan
+	l.Application.Classes = 5000
+	if err := l.Write(filepath.Join(dir, count.LockFile)); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := mc.classCounts(""); err != nil || c.Application.Classes != 5000 {
+		t.Errorf("Expected class counts of the lock file, got %+v, %v", c, err)
+	}
+
+	_ = os.Setenv("BPI_JVM_CLASS_COUNT", "2000")
+	if c, _ := mc.classCounts(""); c.JVM != 2000 {
+		t.Errorf("Expected $BPI_JVM_CLASS_COUNT to override the lock file, got %d", c.JVM)
+	}
+
+	// A changed application is scanned again unless the lock file is trusted
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "App.class"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := mc.classCounts(""); c.Application.Classes != 1 {
+		t.Errorf("Expected the changed application to be scanned, got %d classes", c.Application.Classes)
+	}
+
+	_ = os.Setenv("BPI_CLASS_COUNT_LOCK_TRUST", "true")
+	if c, _ := mc.classCounts(""); c.Application.Classes != 5000 {
+		t.Errorf("Expected the trusted lock file to be used, got %d classes", c.Application.Classes)
+	}
+}
+
+func TestJVMClassCount(t *testing.T) {
+	mc := Create(true)
+
+	javaHome := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(javaHome, "jmods"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	b.Write(count.JmodMagic)
+	z := zip.NewWriter(&b)
+	for _, name := range []string{"classes/java/lang/Object.class", "classes/java/lang/String.class"} {
+		if _, err := z.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(javaHome, "jmods", "java.base.jmod"), b.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = os.Unsetenv("BPI_JVM_CLASS_COUNT")
+	_ = os.Setenv("JAVA_HOME", javaHome)
+	defer func() {
+		_ = os.Unsetenv("JAVA_HOME")
+		_ = os.Unsetenv("BPI_JVM_CLASS_COUNT")
+	}()
+
+	if n, err := mc.jvmClassCount(); err != nil || n != 2 {
+		t.Errorf("Expected 2 classes of the Java runtime, got %d, %v", n, err)
+	}
+
+	_ = os.Setenv("JAVA_HOME", t.TempDir())
+	if n, _ := mc.jvmClassCount(); n != DefaultJVMClassCount {
+		t.Errorf("Expected %d classes for a runtime without modules, got %d", DefaultJVMClassCount, n)
+	}
+
+	_ = os.Setenv("BPI_JVM_CLASS_COUNT", "1500")
+	if n, _ := mc.jvmClassCount(); n != 1500 {
+		t.Errorf("Expected $BPI_JVM_CLASS_COUNT to apply, got %d", n)
+	}
+}
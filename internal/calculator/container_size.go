@@ -0,0 +1,149 @@
+package calculator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+)
+
+// ContainerSize is the container memory required to run the JVM with a target heap.
+type ContainerSize struct {
+	// Heap is the target heap size.
+	Heap calc.Size
+
+	// Minimum is the smallest total memory, in whole mebibytes, that fits the heap and all other regions.
+	Minimum calc.Size
+
+	// Recommended is Minimum rounded up to a Kubernetes friendly step (see RoundContainerMemory).
+	Recommended calc.Size
+
+	// Calculator holds the inputs of the calculation for the recommended total memory.
+	Calculator calc.Calculator
+
+	// Regions holds the memory regions for the recommended total memory.
+	Regions calc.MemoryRegions
+
+	// Options holds the options of the output variable for the recommended total memory like Calculate, followed by
+	// the target heap and the calculated flags.
+	Options string
+
+	// Output is the environment variable that receives the options.
+	Output string
+}
+
+// Quantity returns the recommended total memory as a Kubernetes resource quantity, e.g. "3584Mi" or "4Gi".
+func (s ContainerSize) Quantity() string {
+	return KubernetesQuantity(s.Recommended)
+}
+
+// SizeContainer calculates the container memory required for the given heap. It uses the same region model, class
+// counting and headroom rules as Calculate, but searches for the total memory instead of deriving the heap from it.
+// Degrade mode does not apply, as the container is sized for the regions at their regular size.
+func (m MemoryCalculator) SizeContainer(heap calc.Size) (*ContainerSize, error) {
+	if heap.Value <= 0 {
+		return nil, fmt.Errorf("heap size must be positive")
+	}
+
+	setup, err := m.Configure()
+	if err != nil {
+		return nil, err
+	}
+
+	c := setup.Calculator
+	c.Degrade = false
+
+	// Round the heap to whole kibibytes so that it can be expressed as -Xmx
+	heap = calc.Size{Value: (heap.Value + calc.Kibi - 1) / calc.Kibi * calc.Kibi, Provenance: calc.UserConfigured}
+	flags := strings.TrimSpace(setup.Options + " " + calc.Heap(heap).String())
+
+	minimum, err := RequiredTotalMemory(c, flags)
+	if err != nil {
+		return nil, fmt.Errorf("unable to size container for a heap of %s\n%w", heap, err)
+	}
+
+	c.TotalMemory = RoundContainerMemory(minimum)
+	r, err := c.Calculate(flags)
+	if err != nil {
+		return nil, fmt.Errorf("unable to calculate memory configuration\n%w", err)
+	}
+
+	calculated := emittedFlags(m.buildCalculatedValues(r, setup.Release), setup.respected)
+	options := setup.outputOptions(append([]string{calc.Heap(heap).String()}, calculated...))
+
+	m.Logger.Infof(
+		"Container memory for a heap of %s: minimum %s, recommended %s",
+		heap, minimum, KubernetesQuantity(c.TotalMemory))
+
+	return &ContainerSize{
+		Heap:        heap,
+		Minimum:     minimum,
+		Recommended: c.TotalMemory,
+		Calculator:  c,
+		Regions:     r,
+		Options:     options,
+		Output:      setup.Output,
+	}, nil
+}
+
+// RequiredTotalMemory returns the smallest total memory, in whole mebibytes, for which the calculator succeeds with
+// the given flags. The flags are expected to fix the heap size with -Xmx.
+func RequiredTotalMemory(c calc.Calculator, flags string) (calc.Size, error) {
+	fits := func(mebibytes int64) bool {
+		c.TotalMemory = calc.Size{Value: mebibytes * calc.Mebi}
+		_, err := c.Calculate(flags)
+		return err == nil
+	}
+
+	// Find an upper bound by doubling, then search the smallest fitting size between the bounds
+	lo, hi := int64(0), int64(1_024)
+	for !fits(hi) {
+		if hi*calc.Mebi >= MaxJVMSize {
+			c.TotalMemory = calc.Size{Value: MaxJVMSize}
+			_, err := c.Calculate(flags)
+			return calc.Size{}, fmt.Errorf("memory regions do not fit into %s\n%w", calc.Size{Value: MaxJVMSize}, err)
+		}
+		lo, hi = hi, hi*2
+	}
+
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if fits(mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	return calc.Size{Value: hi * calc.Mebi, Provenance: calc.Calculated}, nil
+}
+
+// RoundContainerMemory rounds total memory up to a step that suits Kubernetes memory limits: 64Mi below 1Gi,
+// 256Mi below 4Gi, 512Mi below 16Gi and 1Gi above.
+func RoundContainerMemory(s calc.Size) calc.Size {
+	step := calc.Gibi
+	switch {
+	case s.Value < calc.Gibi:
+		step = 64 * calc.Mebi
+	case s.Value < 4*calc.Gibi:
+		step = 256 * calc.Mebi
+	case s.Value < 16*calc.Gibi:
+		step = 512 * calc.Mebi
+	}
+
+	return calc.Size{Value: (s.Value + step - 1) / step * step, Provenance: calc.Calculated}
+}
+
+// KubernetesQuantity formats a size as a Kubernetes resource quantity using binary suffixes, e.g. "512Mi" or "4Gi".
+func KubernetesQuantity(s calc.Size) string {
+	switch {
+	case s.Value%calc.Gibi == 0:
+		return fmt.Sprintf("%dGi", s.Value/calc.Gibi)
+	case s.Value%calc.Mebi == 0:
+		return fmt.Sprintf("%dMi", s.Value/calc.Mebi)
+	case s.Value%calc.Kibi == 0:
+		return fmt.Sprintf("%dKi", s.Value/calc.Kibi)
+	default:
+		return fmt.Sprintf("%d", s.Value)
+	}
+}
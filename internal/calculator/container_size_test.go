@@ -0,0 +1,143 @@
+package calculator
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+)
+
+func TestRequiredTotalMemory(t *testing.T) {
+	c := calc.Calculator{
+		HeadRoom:         10,
+		LoadedClassCount: 10_000,
+		ThreadCount:      100,
+	}
+
+	minimum, err := RequiredTotalMemory(c, "-Xmx1G")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.TotalMemory = minimum
+	if _, err := c.Calculate("-Xmx1G"); err != nil {
+		t.Errorf("Expected calculation to fit into %s: %v", minimum, err)
+	}
+
+	c.TotalMemory = calc.Size{Value: minimum.Value - calc.Mebi}
+	if _, err := c.Calculate("-Xmx1G"); err == nil {
+		t.Errorf("Expected %s to be the smallest fitting size", minimum)
+	}
+}
+
+func TestRequiredTotalMemoryTooLarge(t *testing.T) {
+	c := calc.Calculator{LoadedClassCount: 10_000, ThreadCount: 100}
+
+	if _, err := RequiredTotalMemory(c, "-Xmx128T"); err == nil {
+		t.Error("Expected error for a heap larger than the maximum JVM size")
+	}
+}
+
+func TestRoundContainerMemory(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected int64
+	}{
+		{size: 300 * calc.Mebi, expected: 320 * calc.Mebi},
+		{size: 320 * calc.Mebi, expected: 320 * calc.Mebi},
+		{size: 1_100 * calc.Mebi, expected: 1_280 * calc.Mebi},
+		{size: 3_986 * calc.Mebi, expected: 4 * calc.Gibi},
+		{size: 5 * calc.Gibi, expected: 5 * calc.Gibi},
+		{size: 5*calc.Gibi + 1, expected: 5*calc.Gibi + 512*calc.Mebi},
+		{size: 20*calc.Gibi + 1, expected: 21 * calc.Gibi},
+	}
+
+	for _, tt := range tests {
+		if actual := RoundContainerMemory(calc.Size{Value: tt.size}); actual.Value != tt.expected {
+			t.Errorf("RoundContainerMemory(%d) = %d, expected %d", tt.size, actual.Value, tt.expected)
+		}
+	}
+}
+
+func TestKubernetesQuantity(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{size: 4 * calc.Gibi, expected: "4Gi"},
+		{size: 3_584 * calc.Mebi, expected: "3584Mi"},
+		{size: 1_536 * calc.Kibi, expected: "1536Ki"},
+		{size: 1_000, expected: "1000"},
+	}
+
+	for _, tt := range tests {
+		if actual := KubernetesQuantity(calc.Size{Value: tt.size}); actual != tt.expected {
+			t.Errorf("KubernetesQuantity(%d) = %q, expected %q", tt.size, actual, tt.expected)
+		}
+	}
+}
+
+func TestSizeContainer(t *testing.T) {
+	mc := Create(true)
+
+	_ = os.Setenv("BPL_JVM_LOADED_CLASS_COUNT", "10000")
+	_ = os.Setenv("BPL_JVM_HEAD_ROOM", "10")
+	_ = os.Setenv("BPL_JVM_DEGRADE", "true")
+	defer func() {
+		_ = os.Unsetenv("BPL_JVM_LOADED_CLASS_COUNT")
+		_ = os.Unsetenv("BPL_JVM_HEAD_ROOM")
+		_ = os.Unsetenv("BPL_JVM_DEGRADE")
+	}()
+
+	size, err := mc.SizeContainer(calc.Size{Value: 3 * calc.Gibi})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size.Minimum.Value <= 3*calc.Gibi || size.Recommended.Value < size.Minimum.Value {
+		t.Errorf("Unexpected minimum %s and recommended %s", size.Minimum, size.Recommended)
+	}
+	if size.Quantity() != KubernetesQuantity(size.Recommended) {
+		t.Errorf("Unexpected quantity %q", size.Quantity())
+	}
+	if size.Regions.Heap == nil || size.Regions.Heap.Value != 3*calc.Gibi {
+		t.Errorf("Expected heap of 3G, got %+v", size.Regions.Heap)
+	}
+	if len(size.Regions.Reductions) != 0 {
+		t.Errorf("Expected no degrade reductions, got %v", size.Regions.Reductions)
+	}
+	if !strings.HasPrefix(size.Options, "-Xmx3G ") {
+		t.Errorf("Expected options to start with -Xmx3G, got %q", size.Options)
+	}
+
+	if _, err := mc.SizeContainer(calc.Size{}); err == nil {
+		t.Error("Expected error for zero heap")
+	}
+}
+
+func TestSizeContainerOptions(t *testing.T) {
+	mc := Create(true)
+
+	setOptionSources(t, map[string]string{
+		"BPL_JVM_LOADED_CLASS_COUNT": "10000",
+		"BPL_JVM_INITIAL_HEAP":       "50%",
+		"BPL_JVM_EXTRA_OPTIONS":      "-XX:+AlwaysPreTouch",
+		"JAVA_TOOL_OPTIONS":          "-XX:InitialRAMPercentage=50 -Dgreeting='Hello World'",
+	})
+
+	size, err := mc.SizeContainer(calc.Size{Value: calc.Gibi})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(size.Options, "-XX:InitialRAMPercentage=50 -Dgreeting='Hello World' ") {
+		t.Errorf("Expected the options of the output variable first, got %q", size.Options)
+	}
+	if !strings.Contains(size.Options, " -XX:+AlwaysPreTouch ") || !strings.Contains(size.Options, " -Xmx1G ") {
+		t.Errorf("Expected the extra options and the target heap, got %q", size.Options)
+	}
+	if strings.Contains(size.Options, "-Xms") {
+		t.Errorf("Expected no initial heap for the ergonomic initial heap of the user, got %q", size.Options)
+	}
+}
@@ -0,0 +1,30 @@
+package calculator
+
+// FrameworkClassLoad describes how many classes a framework loads at runtime.
+type FrameworkClassLoad struct {
+	// Framework is the name of the framework as detected while scanning, e.g. "spring-boot".
+	Framework string
+
+	// LoadFactor is the share of the scanned classes that are loaded with the framework.
+	LoadFactor float64
+
+	// DynamicClasses is the number of classes generated at runtime, e.g. proxies and lambdas, that are not
+	// found while scanning.
+	DynamicClasses int
+}
+
+// FrameworkClassLoads lists the class loading of the frameworks detected while scanning.
+var FrameworkClassLoads = []FrameworkClassLoad{
+	// Auto-configuration loads a large part of the classpath, CGLIB proxies and lambdas are generated on top
+	{Framework: "spring-boot", LoadFactor: 0.6, DynamicClasses: 4_000},
+	// Build-time initialization prunes the classes that are loaded at runtime
+	{Framework: "quarkus", LoadFactor: 0.4, DynamicClasses: 500},
+	// Ahead-of-time dependency injection avoids reflection and runtime proxies
+	{Framework: "micronaut", LoadFactor: 0.4, DynamicClasses: 500},
+	// CDI and EJB containers generate proxies for managed beans
+	{Framework: "jakarta-ee", LoadFactor: 0.5, DynamicClasses: 2_000},
+	// Entity proxies and bytecode enhancement generate classes per entity
+	{Framework: "hibernate", LoadFactor: 0.5, DynamicClasses: 2_000},
+	// Scripts and closures compile into classes at runtime
+	{Framework: "groovy", LoadFactor: 0.6, DynamicClasses: 3_000},
+}
@@ -22,11 +22,13 @@ package calculator
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/patbaumgartner/memory-calculator/internal/calc"
 	"github.com/patbaumgartner/memory-calculator/internal/count"
+	"github.com/patbaumgartner/memory-calculator/internal/jvm"
 	"github.com/patbaumgartner/memory-calculator/internal/logger"
 	"github.com/patbaumgartner/memory-calculator/internal/parser"
 )
@@ -42,6 +44,8 @@ const (
 	DefaultMemoryLimitPathV2 = "/sys/fs/cgroup/memory.max"
 	// DefaultMemoryInfoPath is the path to /proc/meminfo.
 	DefaultMemoryInfoPath = "/proc/meminfo"
+	// DefaultClassListMargin is the default percentage added to the classes of a class list (10%).
+	DefaultClassListMargin = 10
 	// DefaultThreadCount is the default thread count (250).
 	DefaultThreadCount = 250
 	// MaxJVMSize is the maximum size of the JVM.
@@ -68,13 +72,107 @@ func Create(quiet bool) *MemoryCalculator {
 	}
 }
 
+// Result holds the outcome of a memory calculation.
+type Result struct {
+	// Properties holds the environment variables to set, keyed by variable name.
+	Properties map[string]string
+
+	// Calculator holds the inputs the calculation was performed with.
+	Calculator calc.Calculator
+
+	// Regions holds the calculated memory regions.
+	Regions calc.MemoryRegions
+
+	// Release describes the detected Java runtime, or nil if it could not be detected.
+	Release *jvm.Release
+
+	// Profile is the name of the selected profile, or empty if none is selected.
+	Profile string
+
+	// Frameworks lists the frameworks detected while counting classes, sorted by name.
+	Frameworks []string
+
+	// Scan holds the classes counted in the application path and application servers. The application is scanned
+	// even if the loaded class count is configured or measured, to detect libraries and frameworks.
+	Scan count.Result
+
+	// Output is the environment variable in Properties that receives the calculated options.
+	Output string
+
+	// UserFlags lists the memory flags configured by the user with the source of JVM options each was taken from.
+	UserFlags []UserFlag
+
+	// Conflicts lists the conflicting memory flags and how they were resolved.
+	Conflicts []Conflict
+}
+
 // Execute performs the memory calculation and returns environment variables.
 func (m MemoryCalculator) Execute() (map[string]string, error) {
+	r, err := m.Calculate()
+	if err != nil {
+		return nil, err
+	}
+	return r.Properties, nil
+}
+
+// Setup holds a calculator configured from the environment, ready to calculate for any total memory.
+type Setup struct {
+	// Calculator holds the inputs parsed from the environment. Its TotalMemory is not set.
+	Calculator calc.Calculator
+
+	// Options holds the user's options of all sources in the order the JVM applies them, merged according to the
+	// merge policy, followed by the options of the selected profile.
+	Options string
+
+	// Sources holds the sources of the user's JVM options in the order the JVM applies them.
+	Sources []jvm.OptionSource
+
+	// Output is the environment variable that receives the calculated options.
+	Output string
+
+	// OutputOptions holds the user's options of the Output variable followed by the options of the selected profile.
+	// The calculated options are appended to them.
+	OutputOptions string
+
+	// Conflicts lists the conflicting memory flags of the user's options and how they were resolved.
+	Conflicts []Conflict
+
+	// respected holds the memory settings configured by ergonomic flags of the user that no flag is emitted for.
+	respected map[string]bool
+
+	// Profile is the name of the selected profile, or empty if none is selected.
+	Profile string
+
+	// Frameworks lists the frameworks detected while counting classes, sorted by name.
+	Frameworks []string
+
+	// Scan holds the classes counted in the application path and application servers. The application is scanned
+	// even if the loaded class count is configured or measured, to detect libraries and frameworks.
+	Scan count.Result
+
+	// Release describes the detected Java runtime, or nil if it could not be detected.
+	Release *jvm.Release
+}
+
+// outputOptions returns the options of the Output variable with the given calculated flags appended to
+// OutputOptions.
+func (s *Setup) outputOptions(calculated []string) string {
+	return joinOptions(s.OutputOptions, parser.FormatFlags(calculated))
+}
+
+// Configure parses the calculation inputs from environment variables and counts the application's classes. The
+// resulting setup can be used for any number of calculations with different total memory.
+func (m MemoryCalculator) Configure() (*Setup, error) {
 	c := calc.Calculator{
 		HeadRoom:    DefaultHeadroom,
 		ThreadCount: DefaultThreadCount,
 	}
 
+	release := m.detectRelease()
+	if release != nil {
+		c.JavaVersion = release.Major
+	}
+
 	// Parse configuration from environment variables
 	if err := m.parseHeadroomConfig(&c); err != nil {
 		return nil, err
@@ -84,16 +182,108 @@ func (m MemoryCalculator) Execute() (map[string]string, error) {
 		return nil, err
 	}
 
-	var values []string
-	opts, ok := os.LookupEnv("JAVA_TOOL_OPTIONS")
-	if ok {
-		values = append(values, opts)
+	if err := m.parseDegradeConfig(&c); err != nil {
+		return nil, err
+	}
+
+	if err := m.parseVirtualThreadConfig(&c, release); err != nil {
+		return nil, err
+	}
+
+	if err := m.parseCodeCacheConfig(&c, release); err != nil {
+		return nil, err
+	}
+
+	if err := m.parseInitialHeapConfig(&c); err != nil {
+		return nil, err
+	}
+
+	if err := m.parseMetaspaceConfig(&c); err != nil {
+		return nil, err
+	}
+
+	if err := m.parseProfileSizeConfig(&c); err != nil {
+		return nil, err
+	}
+
+	sources, user, err := userOptions()
+	if err != nil {
+		return nil, err
+	}
+	extra, err := m.profileExtras(user)
+	if err != nil {
+		return nil, err
+	}
+	output, err := outputVariable()
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := mergePolicy()
+	if err != nil {
+		return nil, err
+	}
+	calculated := []string{heapSetting, stackSetting, metaspaceSetting, directMemorySetting, codeCacheSetting}
+	if c.InitialHeap != nil {
+		calculated = append(calculated, initialHeapSetting)
+	}
+	merged, err := mergeOptions(sources, output, policy, calculated)
+	if err != nil {
+		return nil, err
 	}
+	opts := joinOptions(merged.options, parser.FormatFlags(extra))
 
 	// Parse class count configuration
-	if err := m.parseClassCountConfig(&c, opts); err != nil {
+	scan, err := m.parseClassCountConfig(&c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.parseSharedArchiveConfig(&c, opts); err != nil {
+		return nil, err
+	}
+
+	if err := m.parseNativeAgentConfig(&c, opts); err != nil {
+		return nil, err
+	}
+
+	return &Setup{
+		Calculator:    c,
+		Options:       opts,
+		Sources:       sources,
+		Output:        output,
+		OutputOptions: joinOptions(merged.output, parser.FormatFlags(extra)),
+		Conflicts:     merged.conflicts,
+		respected:     merged.respected,
+		Profile:       os.Getenv("BPL_JVM_PROFILE"),
+		Frameworks:    scan.Frameworks,
+		Scan:          scan,
+		Release:       release,
+	}, nil
+}
+
+// Calculate performs the memory calculation and returns the environment variables together with the inputs
+// and memory regions they were derived from.
+func (m MemoryCalculator) Calculate() (*Result, error) {
+	setup, err := m.Configure()
+	if err != nil {
 		return nil, err
 	}
+	c, opts, release := setup.Calculator, setup.Options, setup.Release
+	if setup.Profile != "" {
+		m.Logger.Infof("Using profile %s", setup.Profile)
+	}
+
+	flags, err := userFlags(setup.Sources)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range flags {
+		m.Logger.Infof("Using %s", f)
+	}
+	for _, conflict := range setup.Conflicts {
+		m.Logger.Infof("WARNING: Conflicting memory flags for %s", conflict)
+	}
 
 	// Determine total memory
 	totalMemory, err := m.determineTotalMemory()
@@ -109,15 +299,61 @@ func (m MemoryCalculator) Execute() (map[string]string, error) {
 	}
 
 	// Build calculated values
-	calculated := m.buildCalculatedValues(r)
-	values = append(values, calculated...)
+	calculated := emittedFlags(m.buildCalculatedValues(r, release), setup.respected)
 
 	m.Logger.Infof(
 		"Calculated JVM Memory Configuration: %s (Total Memory: %s, Thread Count: %d, "+
-			"Loaded Class Count: %d, Headroom: %d%%)",
-		strings.Join(calculated, " "), c.TotalMemory, c.ThreadCount, c.LoadedClassCount, c.HeadRoom)
+			"Loaded Class Count: %d, Headroom: %s = %s)",
+		parser.FormatFlags(calculated), c.TotalMemory, r.ThreadCount, c.LoadedClassCount, c.HeadRoomSpec(), r.HeadRoom)
+	if r.Metaspace.Provenance != calc.UserConfigured {
+		m.Logger.Debugf("Metaspace Calculation: %s", c.MetaspaceFormula())
+	}
+	for _, reduction := range r.Reductions {
+		m.Logger.Infof("WARNING: Degraded to fit a minimum heap: %s", reduction)
+	}
+	if c.SharedClassCount > 0 {
+		m.Logger.Infof(
+			"Sized metaspace without %d classes loaded from CDS archives, reserved %s for the mapped archives",
+			c.SharedClassCount, r.SharedArchive)
+	}
+	if r.DirectMemoryLibrary != "" {
+		m.Logger.Infof("Raised direct memory to %s for detected library %s", calc.Size(r.DirectMemory), r.DirectMemoryLibrary)
+	}
+	if c.VirtualThreadCount > 0 {
+		m.Logger.Infof(
+			"Reserved %s of heap for the stacks of %d virtual threads",
+			r.VirtualThreadStacks, c.VirtualThreadCount)
+	}
+
+	return &Result{
+		Properties: map[string]string{setup.Output: setup.outputOptions(calculated)},
+		Calculator: c,
+		Regions:    r,
+		Release:    release,
+		Profile:    setup.Profile,
+		Frameworks: setup.Frameworks,
+		Scan:       setup.Scan,
+		Output:     setup.Output,
+		UserFlags:  flags,
+		Conflicts:  setup.Conflicts,
+	}, nil
+}
 
-	return map[string]string{"JAVA_TOOL_OPTIONS": strings.Join(values, " ")}, nil
+// detectRelease detects the Java runtime from $JAVA_HOME/release, returning nil if it cannot be determined
+func (m MemoryCalculator) detectRelease() *jvm.Release {
+	javaHome, ok := os.LookupEnv("JAVA_HOME")
+	if !ok || javaHome == "" {
+		return nil
+	}
+
+	release, err := jvm.ReadRelease(javaHome)
+	if err != nil {
+		m.Logger.Infof("WARNING: Unable to detect Java version from %s: %s", javaHome, err)
+		return nil
+	}
+
+	m.Logger.Infof("Detected Java runtime %s at %s", release, javaHome)
+	return release
 }
 
 func (m MemoryCalculator) getMemoryLimitFromPath(memoryLimitPath string) int64 {
@@ -208,53 +444,22 @@ func parseMemInfo(s string) (int64, error) {
 	return 0, fmt.Errorf("failed to find MemAvailable in meminfo")
 }
 
-// CountAgentClasses counts classes in agent JARs.
-func (m MemoryCalculator) CountAgentClasses(opts string) (int, error) {
-	var agentClassCount, skippedAgents int
-	p, err := parser.ParseFlags(opts)
-	if err != nil {
-		return 0, fmt.Errorf("unable to parse $JAVA_TOOL_OPTIONS\n%w", err)
-	}
-
-	var agentPaths []string
-	for _, s := range p {
-		if strings.HasPrefix(s, "-javaagent:") {
-			agentPaths = append(agentPaths, strings.Split(s, ":")[1])
-		}
-	}
-	if len(agentPaths) > 0 {
-		agentClassCount, skippedAgents, err = count.JarClassesFrom(agentPaths...)
-		if err != nil {
-			return 0, fmt.Errorf("error counting agent jar classes \n%w", err)
-		} else if skippedAgents > 0 {
-			m.Logger.Infof(
-				`WARNING: could not count classes from all agent jars (skipped %d), `+
-					`class count and metaspace may not be sized correctly`, skippedAgents)
-		}
-	}
-	return agentClassCount, nil
-}
-
 // parseHeadroomConfig parses headroom configuration from environment variables
 func (m MemoryCalculator) parseHeadroomConfig(c *calc.Calculator) error {
 	var deprecatedHeadroom bool
 
 	if s, ok := os.LookupEnv("BPL_JVM_HEADROOM"); ok {
-		headroom, err := strconv.Atoi(s)
-		if err != nil {
-			return fmt.Errorf("unable to convert $BPL_JVM_HEADROOM=%s to integer\n%w", s, err)
+		if err := setHeadroom(c, "BPL_JVM_HEADROOM", s); err != nil {
+			return err
 		}
-		c.HeadRoom = headroom
 		deprecatedHeadroom = true
 		m.Logger.Info("WARNING: BPL_JVM_HEADROOM is deprecated and will be removed, please switch to BPL_JVM_HEAD_ROOM")
 	}
 
 	if s, ok := os.LookupEnv("BPL_JVM_HEAD_ROOM"); ok {
-		headroom, err := strconv.Atoi(s)
-		if err != nil {
-			return fmt.Errorf("unable to convert $BPL_JVM_HEAD_ROOM=%s to integer\n%w", s, err)
+		if err := setHeadroom(c, "BPL_JVM_HEAD_ROOM", s); err != nil {
+			return err
 		}
-		c.HeadRoom = headroom
 		if deprecatedHeadroom {
 			m.Logger.Info(
 				"WARNING: You have set both BPL_JVM_HEAD_ROOM and BPL_JVM_HEADROOM. " +
@@ -265,7 +470,25 @@ func (m MemoryCalculator) parseHeadroomConfig(c *calc.Calculator) error {
 	return nil
 }
 
-// parseThreadCountConfig parses thread count configuration from environment variables
+// setHeadroom applies a headroom specification, keeping plain percentages in HeadRoom for compatibility
+func setHeadroom(c *calc.Calculator, key string, s string) error {
+	if headroom, err := strconv.Atoi(s); err == nil {
+		c.HeadRoom = headroom
+		c.HeadRoomSize = nil
+		return nil
+	}
+
+	r, err := calc.ParseHeadRoom(s)
+	if err != nil {
+		return fmt.Errorf("unable to parse $%s=%s\n%w", key, s, err)
+	}
+	c.HeadRoomSize = &r
+	return nil
+}
+
+// parseThreadCountConfig parses thread count configuration from environment variables. A thread count set in
+// $BPL_JVM_THREAD_COUNT is configured by the user, one set in $BPL_JVM_PROFILE_THREAD_COUNT by the selected profile
+// replaces the default and may still be reduced in degrade mode.
 func (m MemoryCalculator) parseThreadCountConfig(c *calc.Calculator) error {
 	if threadCount, ok := os.LookupEnv("BPL_JVM_THREAD_COUNT"); ok {
 		count, err := strconv.Atoi(threadCount)
@@ -273,41 +496,267 @@ func (m MemoryCalculator) parseThreadCountConfig(c *calc.Calculator) error {
 			return fmt.Errorf("unable to convert $BPL_JVM_THREAD_COUNT=%s to integer\n%w", threadCount, err)
 		}
 		c.ThreadCount = count
+		c.ThreadCountProvenance = calc.UserConfigured
+		return nil
+	}
+
+	if threadCount, ok := os.LookupEnv("BPL_JVM_PROFILE_THREAD_COUNT"); ok {
+		count, err := strconv.Atoi(threadCount)
+		if err != nil {
+			return fmt.Errorf("unable to convert $BPL_JVM_PROFILE_THREAD_COUNT=%s to integer\n%w", threadCount, err)
+		}
+		c.ThreadCount = count
 	}
 	return nil
 }
 
-// parseClassCountConfig parses class count configuration from environment variables
-func (m MemoryCalculator) parseClassCountConfig(c *calc.Calculator, opts string) error {
-	if s, ok := os.LookupEnv("BPL_JVM_LOADED_CLASS_COUNT"); ok {
+// parseDegradeConfig parses degrade mode configuration from environment variables
+func (m MemoryCalculator) parseDegradeConfig(c *calc.Calculator) error {
+	if s, ok := os.LookupEnv("BPL_JVM_DEGRADE"); ok {
+		degrade, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("unable to convert $BPL_JVM_DEGRADE=%s to boolean\n%w", s, err)
+		}
+		c.Degrade = degrade
+	}
+
+	if s, ok := os.LookupEnv("BPL_JVM_DEGRADE_MIN_HEAP"); ok {
+		minHeap, err := calc.ParseSize(s)
+		if err != nil {
+			return fmt.Errorf("unable to parse $BPL_JVM_DEGRADE_MIN_HEAP=%s\n%w", s, err)
+		}
+		c.MinHeap = minHeap
+	}
+
+	return nil
+}
+
+// parseVirtualThreadConfig parses virtual thread configuration from environment variables
+func (m MemoryCalculator) parseVirtualThreadConfig(c *calc.Calculator, release *jvm.Release) error {
+	if s, ok := os.LookupEnv("BPL_JVM_VIRTUAL_THREAD_COUNT"); ok {
 		count, err := strconv.Atoi(s)
 		if err != nil {
-			return fmt.Errorf("unable to convert $BPL_JVM_LOADED_CLASS_COUNT=%s to integer\n%w", s, err)
+			return fmt.Errorf("unable to convert $BPL_JVM_VIRTUAL_THREAD_COUNT=%s to integer\n%w", s, err)
 		}
-		c.LoadedClassCount = count
+		if count > 0 && !release.Supports(jvm.VirtualThreads) {
+			m.Logger.Infof(
+				"WARNING: Java %s does not support virtual threads, ignoring $BPL_JVM_VIRTUAL_THREAD_COUNT=%s",
+				release.JavaVersion, s)
+			return nil
+		}
+		c.VirtualThreadCount = count
+	}
+
+	if s, ok := os.LookupEnv("BPL_JVM_VIRTUAL_THREAD_STACK_SIZE"); ok {
+		size, err := calc.ParseSize(s)
+		if err != nil {
+			return fmt.Errorf("unable to parse $BPL_JVM_VIRTUAL_THREAD_STACK_SIZE=%s\n%w", s, err)
+		}
+		c.VirtualThreadStack = size
+	}
+
+	return nil
+}
+
+// parseCodeCacheConfig parses code cache configuration from environment variables
+func (m MemoryCalculator) parseCodeCacheConfig(c *calc.Calculator, release *jvm.Release) error {
+	s, ok := os.LookupEnv("BPL_JVM_SEGMENTED_CODE_CACHE")
+	if !ok {
+		return nil
+	}
+
+	segmented, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("unable to convert $BPL_JVM_SEGMENTED_CODE_CACHE=%s to boolean\n%w", s, err)
+	}
+	if segmented && !release.Supports(jvm.SegmentedCodeCache) {
+		m.Logger.Infof(
+			"WARNING: Java %s does not support a segmented code cache, ignoring $BPL_JVM_SEGMENTED_CODE_CACHE=%s",
+			release.JavaVersion, s)
 		return nil
 	}
+	c.SegmentedCodeCache = segmented
+
+	return nil
+}
+
+// parseInitialHeapConfig parses the initial heap policy from environment variables
+func (m MemoryCalculator) parseInitialHeapConfig(c *calc.Calculator) error {
+	if s, ok := os.LookupEnv("BPL_JVM_INITIAL_HEAP"); ok {
+		r, err := calc.ParseInitialHeapPolicy(s)
+		if err != nil {
+			return fmt.Errorf("unable to parse $BPL_JVM_INITIAL_HEAP=%s\n%w", s, err)
+		}
+		c.InitialHeap = &r
+	}
+	return nil
+}
+
+// parseProfileSizeConfig parses the code cache and direct memory of the selected profile from environment variables.
+// Unlike $BPL_JVM_CODE_CACHE_SIZE and $BPL_JVM_DIRECT_MEMORY_SIZE they replace the defaults rather than being
+// configured by the user, so degrade mode may still reduce the code cache and detected libraries raise direct memory.
+func (m MemoryCalculator) parseProfileSizeConfig(c *calc.Calculator) error {
+	if s, ok := os.LookupEnv("BPL_JVM_PROFILE_CODE_CACHE_SIZE"); ok {
+		size, err := calc.ParseSize(s)
+		if err != nil {
+			return fmt.Errorf("unable to parse $BPL_JVM_PROFILE_CODE_CACHE_SIZE=%s\n%w", s, err)
+		}
+		c.ReservedCodeCache = size
+	}
+	if s, ok := os.LookupEnv("BPL_JVM_PROFILE_DIRECT_MEMORY_SIZE"); ok {
+		size, err := calc.ParseSize(s)
+		if err != nil {
+			return fmt.Errorf("unable to parse $BPL_JVM_PROFILE_DIRECT_MEMORY_SIZE=%s\n%w", s, err)
+		}
+		c.DirectMemory = size
+	}
+	return nil
+}
+
+// profileOptions appends the profile options (see profileExtras) to the user's options.
+func (m MemoryCalculator) profileOptions(opts string) (string, error) {
+	extra, err := m.profileExtras(opts)
+	if err != nil {
+		return "", err
+	}
+	return joinOptions(opts, parser.FormatFlags(extra)), nil
+}
+
+// profileExtras returns the code cache size, direct memory size and extra options from environment variables that
+// the user's options do not configure. Options the user already configured take precedence and are not returned.
+func (m MemoryCalculator) profileExtras(opts string) ([]string, error) {
+	var extra []string
+	if s, ok := os.LookupEnv("BPL_JVM_CODE_CACHE_SIZE"); ok {
+		size, err := calc.ParseSize(s)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse $BPL_JVM_CODE_CACHE_SIZE=%s\n%w", s, err)
+		}
+		extra = append(extra, calc.ReservedCodeCache(size).String())
+	}
+	if s, ok := os.LookupEnv("BPL_JVM_DIRECT_MEMORY_SIZE"); ok {
+		size, err := calc.ParseSize(s)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse $BPL_JVM_DIRECT_MEMORY_SIZE=%s\n%w", s, err)
+		}
+		extra = append(extra, calc.DirectMemory(size).String())
+	}
+	if s, ok := os.LookupEnv("BPL_JVM_EXTRA_OPTIONS"); ok {
+		p, err := parser.ParseFlags(s)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse $BPL_JVM_EXTRA_OPTIONS=%s\n%w", s, err)
+		}
+		extra = append(extra, p...)
+	}
+	if len(extra) == 0 {
+		return nil, nil
+	}
+
+	user, err := parser.SplitFlags(opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse JVM options\n%w", err)
+	}
+	configured := make(map[string]bool, len(user))
+	for _, o := range user {
+		configured[optionName(o)] = true
+	}
 
-	// Calculate class count dynamically
-	appPath := "/app" // Default application path
-	if path, ok := os.LookupEnv("BPI_APPLICATION_PATH"); ok {
-		appPath = path
+	var values []string
+	for _, o := range extra {
+		if !configured[optionName(o)] {
+			configured[optionName(o)] = true
+			values = append(values, o)
+		}
+	}
+	return values, nil
+}
+
+// optionName returns the name of a JVM option without its value, e.g. ExitOnOutOfMemoryError for
+// -XX:+ExitOnOutOfMemoryError, -XX:MaxDirectMemorySize for -XX:MaxDirectMemorySize=10M and -Xss for -Xss1M
+func optionName(o string) string {
+	switch {
+	case strings.HasPrefix(o, "-XX:+"), strings.HasPrefix(o, "-XX:-"):
+		return "-XX:" + o[len("-XX:+"):]
+	case strings.HasPrefix(o, "-XX:"), strings.HasPrefix(o, "-D"):
+		name, _, _ := strings.Cut(o, "=")
+		return name
+	}
+	for _, p := range []string{"-Xmx", "-Xms", "-Xss"} {
+		if strings.HasPrefix(o, p) {
+			return p
+		}
+	}
+	return o
+}
+
+// parseMetaspaceConfig parses the metaspace model coefficients from environment variables
+func (m MemoryCalculator) parseMetaspaceConfig(c *calc.Calculator) error {
+	if s, ok := os.LookupEnv("BPL_JVM_CLASS_SIZE"); ok {
+		size, err := calc.ParseSize(s)
+		if err != nil {
+			return fmt.Errorf("unable to parse $BPL_JVM_CLASS_SIZE=%s\n%w", s, err)
+		}
+		c.ClassSize = size
 	}
 
-	jvmClassCount := 1000 // Default JVM class count
-	if jvmCountStr, ok := os.LookupEnv("BPI_JVM_CLASS_COUNT"); ok {
-		count, err := strconv.Atoi(jvmCountStr)
+	if s, ok := os.LookupEnv("BPL_JVM_CLASS_OVERHEAD"); ok {
+		size, err := calc.ParseSize(s)
 		if err != nil {
-			return fmt.Errorf("unable to convert $BPI_JVM_CLASS_COUNT=%s to integer\n%w", jvmCountStr, err)
+			return fmt.Errorf("unable to parse $BPL_JVM_CLASS_OVERHEAD=%s\n%w", s, err)
 		}
-		jvmClassCount = count
+		c.ClassOverhead = size
+	}
+
+	return nil
+}
+
+// parseLanguageWeights parses the weights of script entries from environment variables
+func (m MemoryCalculator) parseLanguageWeights() (count.LanguageWeights, error) {
+	s, ok := os.LookupEnv("BPL_JVM_LANGUAGE_WEIGHTS")
+	if !ok {
+		return nil, nil
+	}
+
+	w, err := count.ParseLanguageWeights(s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse $BPL_JVM_LANGUAGE_WEIGHTS=%s\n%w", s, err)
+	}
+	return w, nil
+}
+
+// parseClassCountConfig parses class count configuration from environment variables and returns the classes
+// counted in the application path. The application is scanned for off-heap libraries even if the loaded class count
+// is configured or measured.
+func (m MemoryCalculator) parseClassCountConfig(c *calc.Calculator, opts string) (count.Result, error) {
+	counts, err := m.classCounts(opts)
+	if err != nil {
+		return count.Result{}, err
+	}
+	scan, jvmClassCount, agentClassCount := counts.Application, counts.JVM, counts.Agents
+
+	if c.DirectMemoryRules, err = m.directMemoryRules(scan.Libraries); err != nil {
+		return count.Result{}, err
+	}
+
+	if s, ok := os.LookupEnv("BPL_JVM_LOADED_CLASS_COUNT"); ok {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return count.Result{}, fmt.Errorf("unable to convert $BPL_JVM_LOADED_CLASS_COUNT=%s to integer\n%w", s, err)
+		}
+		c.LoadedClassCount = n
+		c.LoadedClassCountProvenance = calc.UserConfigured
+		return scan, nil
+	}
+
+	if path, ok := os.LookupEnv("BPL_JVM_CLASS_LIST"); ok && path != "" {
+		return scan, m.parseClassList(c, path)
 	}
 
 	adjustmentFactor := 100
 	if adjustmentStr, ok := os.LookupEnv("BPI_CLASS_ADJUSTMENT_FACTOR"); ok {
 		factor, err := strconv.Atoi(adjustmentStr)
 		if err != nil {
-			return fmt.Errorf("unable to convert $BPI_CLASS_ADJUSTMENT_FACTOR=%s to integer\n%w", adjustmentStr, err)
+			return count.Result{}, fmt.Errorf(
+				"unable to convert $BPI_CLASS_ADJUSTMENT_FACTOR=%s to integer\n%w", adjustmentStr, err)
 		}
 		adjustmentFactor = factor
 	}
@@ -316,32 +765,216 @@ func (m MemoryCalculator) parseClassCountConfig(c *calc.Calculator, opts string)
 	if staticStr, ok := os.LookupEnv("BPI_CLASS_STATIC_ADJUSTMENT"); ok {
 		adjustment, err := strconv.Atoi(staticStr)
 		if err != nil {
-			return fmt.Errorf("unable to convert $BPI_CLASS_STATIC_ADJUSTMENT=%s to integer\n%w", staticStr, err)
+			return count.Result{}, fmt.Errorf("unable to convert $BPI_CLASS_STATIC_ADJUSTMENT=%s to integer\n%w", staticStr, err)
 		}
 		staticAdjustment = adjustment
 	}
 
-	agentClassCount, err := m.CountAgentClasses(opts)
+	weights, err := m.parseLanguageWeights()
 	if err != nil {
-		return fmt.Errorf("unable to determine agent class count\n%w", err)
+		return count.Result{}, err
+	}
+	appClassCount := int(scan.Weighted(weights))
+	if unweighted := int(scan.Weighted(nil)); appClassCount != unweighted {
+		m.Logger.Debugf(
+			"Weighted %d application classes to %d for scripts %v with weights %v",
+			unweighted, appClassCount, scan.Languages, weights)
 	}
 
-	appClassCount, err := count.Classes(appPath)
+	loadFactor, dynamicClasses, err := m.classLoad(scan.Frameworks)
 	if err != nil {
-		return fmt.Errorf("unable to determine class count\n%w", err)
+		return count.Result{}, err
 	}
 
 	totalClasses := float64(jvmClassCount+appClassCount+agentClassCount+staticAdjustment) *
 		(float64(adjustmentFactor) / 100.0)
 
 	m.Logger.Debugf(
-		"Memory Calculation: (%d%% * (%d + %d + %d + %d)) * %0.2f",
-		adjustmentFactor, jvmClassCount, appClassCount, agentClassCount, staticAdjustment, ClassLoadFactor)
+		"Memory Calculation: (%d%% * (%d + %d + %d + %d)) * %0.2f + %d",
+		adjustmentFactor, jvmClassCount, appClassCount, agentClassCount, staticAdjustment, loadFactor, dynamicClasses)
+
+	c.LoadedClassCount = int(totalClasses*loadFactor) + dynamicClasses
+	c.LoadedClassCountProvenance = calc.Calculated
+	return scan, nil
+}
 
-	c.LoadedClassCount = int(totalClasses * ClassLoadFactor)
+// parseClassList sets the loaded class count to the classes loaded in a previous run, as listed in the class list at
+// path, plus the margin configured in $BPL_JVM_CLASS_LIST_MARGIN
+func (m MemoryCalculator) parseClassList(c *calc.Calculator, path string) error {
+	margin := DefaultClassListMargin
+	if s, ok := os.LookupEnv("BPL_JVM_CLASS_LIST_MARGIN"); ok {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("unable to convert $BPL_JVM_CLASS_LIST_MARGIN=%s to integer\n%w", s, err)
+		}
+		if n < 0 {
+			return fmt.Errorf("$BPL_JVM_CLASS_LIST_MARGIN=%s must not be negative", s)
+		}
+		margin = n
+	}
+
+	n, err := count.LoadedClasses(path)
+	if err != nil {
+		return fmt.Errorf("unable to read $BPL_JVM_CLASS_LIST\n%w", err)
+	}
+
+	c.LoadedClassCount = n + (n*margin+99)/100
+	c.LoadedClassCountProvenance = calc.Measured
+	m.Logger.Infof("Measured %d loaded classes in %s, %d with a margin of %d%%", n, path, c.LoadedClassCount, margin)
 	return nil
 }
 
+// classCountCache opens the class count cache configured in the environment, returning nil if caching is disabled.
+// A cache that cannot be read is logged and replaced, so that all archives are scanned again.
+func (m MemoryCalculator) classCountCache() (*count.Cache, error) {
+	if s, ok := os.LookupEnv("BPI_CLASS_COUNT_NO_CACHE"); ok {
+		disabled, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert $BPI_CLASS_COUNT_NO_CACHE=%s to boolean\n%w", s, err)
+		}
+		if disabled {
+			return nil, nil
+		}
+	}
+
+	hash := false
+	if s, ok := os.LookupEnv("BPI_CLASS_COUNT_CACHE_HASH"); ok {
+		var err error
+		if hash, err = strconv.ParseBool(s); err != nil {
+			return nil, fmt.Errorf("unable to convert $BPI_CLASS_COUNT_CACHE_HASH=%s to boolean\n%w", s, err)
+		}
+	}
+
+	path := count.DefaultCachePath()
+	if s, ok := os.LookupEnv("BPI_CLASS_COUNT_CACHE"); ok && s != "" {
+		path = s
+	}
+
+	c, err := count.OpenCache(path, hash)
+	if err != nil {
+		m.Logger.Infof("WARNING: Ignoring class count cache, counting all classes: %v", err)
+	}
+	return c, nil
+}
+
+// scanAppServers adds the classes of the application server installations configured in the environment to the
+// scan of the application path. Directories inside the application path are not scanned twice.
+func (m MemoryCalculator) scanAppServers(
+	scan count.Result, appPath string, options count.Options,
+) (count.Result, error) {
+	for _, d := range appServerDirs(appPath) {
+		if _, err := os.Stat(d.path); err != nil {
+			continue
+		}
+
+		r, err := count.ScanWith(d.path, options)
+		if err != nil {
+			return count.Result{}, fmt.Errorf("unable to determine class count of %s\n%w", d.server.Name, err)
+		}
+		m.Logger.Infof("Detected %s at $%s, counted %d classes in %s", d.server.Name, d.server.HomeEnv, r.Classes, d.path)
+		scan = scan.Merge(r)
+	}
+	return scan, nil
+}
+
+// appServerDir is a directory of an application server installation configured in the environment.
+type appServerDir struct {
+	server count.AppServer
+	path   string
+}
+
+// appServerDirs returns the directories of the application server installations configured in the environment,
+// except for those inside the application path.
+func appServerDirs(appPath string) []appServerDir {
+	var dirs []appServerDir
+	seen := map[string]bool{}
+	for _, a := range count.AppServers {
+		home, ok := os.LookupEnv(a.HomeEnv)
+		if !ok || home == "" {
+			continue
+		}
+
+		for _, d := range a.Dirs {
+			path := filepath.Join(home, d)
+			if seen[path] || isWithin(path, appPath) {
+				continue
+			}
+			seen[path] = true
+			dirs = append(dirs, appServerDir{server: a, path: path})
+		}
+	}
+	return dirs
+}
+
+// isWithin returns true if path is dir or below dir
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// classLoad returns the class load factor and the dynamic class allowance for the detected frameworks. The load
+// factor is the highest of the detected frameworks unless $BPL_JVM_CLASS_LOAD_FACTOR is set, the allowances add up.
+// Without a detected framework, the load factor of the profile in $BPL_JVM_PROFILE_CLASS_LOAD_FACTOR applies.
+func (m MemoryCalculator) classLoad(frameworks []string) (float64, int, error) {
+	loadFactor, dynamicClasses := 0.0, 0
+	for _, f := range frameworks {
+		for _, r := range FrameworkClassLoads {
+			if r.Framework == f {
+				m.Logger.Infof(
+					"Detected framework %s, class load factor %0.2f, %d dynamic classes",
+					f, r.LoadFactor, r.DynamicClasses)
+				loadFactor = max(loadFactor, r.LoadFactor)
+				dynamicClasses += r.DynamicClasses
+			}
+		}
+	}
+	if loadFactor == 0 {
+		loadFactor = ClassLoadFactor
+		if s, ok := os.LookupEnv("BPL_JVM_PROFILE_CLASS_LOAD_FACTOR"); ok {
+			factor, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("unable to convert $BPL_JVM_PROFILE_CLASS_LOAD_FACTOR=%s to float\n%w", s, err)
+			}
+			loadFactor = factor
+		}
+	}
+
+	if s, ok := os.LookupEnv("BPL_JVM_CLASS_LOAD_FACTOR"); ok {
+		factor, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unable to convert $BPL_JVM_CLASS_LOAD_FACTOR=%s to float\n%w", s, err)
+		}
+		loadFactor = factor
+	}
+
+	return loadFactor, dynamicClasses, nil
+}
+
+// directMemoryRules returns the direct memory rules for the detected libraries, applying overrides from
+// $BPL_JVM_DIRECT_MEMORY_RULES to the built-in rules
+func (m MemoryCalculator) directMemoryRules(libraries []string) ([]calc.DirectMemoryRule, error) {
+	rules := calc.DefaultDirectMemoryRules
+	if s, ok := os.LookupEnv("BPL_JVM_DIRECT_MEMORY_RULES"); ok {
+		overrides, err := calc.ParseDirectMemoryRules(s)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse $BPL_JVM_DIRECT_MEMORY_RULES=%s\n%w", s, err)
+		}
+		rules = calc.MergeDirectMemoryRules(rules, overrides)
+	}
+
+	var triggered []calc.DirectMemoryRule
+	for _, l := range libraries {
+		for _, r := range rules {
+			if r.Library == l {
+				m.Logger.Debugf("Detected off-heap library %s, direct memory rule %s", l, r.Size)
+				triggered = append(triggered, r)
+			}
+		}
+	}
+
+	return triggered, nil
+}
+
 // determineTotalMemory determines the total memory available to the JVM
 func (m MemoryCalculator) determineTotalMemory() (calc.Size, error) {
 	totalMemory := UnsetTotalMemory
@@ -393,8 +1026,9 @@ func (m MemoryCalculator) determineTotalMemory() (calc.Size, error) {
 	return calc.Size{Value: totalMemory}, nil
 }
 
-// buildCalculatedValues builds the list of calculated JVM memory options
-func (m MemoryCalculator) buildCalculatedValues(r calc.MemoryRegions) []string {
+// buildCalculatedValues builds the list of calculated JVM memory options, sizing class metadata with
+// -XX:MaxPermSize for Java runtimes that keep it in the permanent generation
+func (m MemoryCalculator) buildCalculatedValues(r calc.MemoryRegions, release *jvm.Release) []string {
 	var calculated []string
 	if r.DirectMemory.Provenance != calc.UserConfigured {
 		calculated = append(calculated, r.DirectMemory.String())
@@ -402,12 +1036,22 @@ func (m MemoryCalculator) buildCalculatedValues(r calc.MemoryRegions) []string {
 	if r.Heap.Provenance != calc.UserConfigured {
 		calculated = append(calculated, r.Heap.String())
 	}
+	if r.InitialHeap != nil {
+		calculated = append(calculated, r.InitialHeap.String())
+	}
 	if r.Metaspace.Provenance != calc.UserConfigured {
-		calculated = append(calculated, r.Metaspace.String())
+		if release.Supports(jvm.Metaspace) {
+			calculated = append(calculated, r.Metaspace.String())
+		} else {
+			calculated = append(calculated, r.Metaspace.PermGenString())
+		}
 	}
 	if r.ReservedCodeCache.Provenance != calc.UserConfigured {
 		calculated = append(calculated, r.ReservedCodeCache.String())
 	}
+	if r.CodeHeaps != nil {
+		calculated = append(calculated, r.CodeHeaps.Flags()...)
+	}
 	if r.Stack.Provenance != calc.UserConfigured {
 		calculated = append(calculated, r.Stack.String())
 	}
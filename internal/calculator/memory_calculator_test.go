@@ -1,12 +1,15 @@
 package calculator
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/count"
+	"github.com/patbaumgartner/memory-calculator/internal/jvm"
 )
 
 func TestExecuteWithDefaultValues(t *testing.T) {
@@ -227,6 +230,32 @@ func TestParseHeadroomConfig(t *testing.T) {
 		}
 	})
 
+	t.Run("Absolute Size", func(t *testing.T) {
+		_ = os.Setenv("BPL_JVM_HEAD_ROOM", "300M")
+		defer func() { _ = os.Unsetenv("BPL_JVM_HEAD_ROOM") }()
+
+		c := &calc.Calculator{HeadRoom: DefaultHeadroom}
+		if err := mc.parseHeadroomConfig(c); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if c.HeadRoomSize == nil || c.HeadRoomSize.Absolute.Value != 300*calc.Mebi {
+			t.Errorf("Expected absolute headroom of 300M, got %+v", c.HeadRoomSize)
+		}
+	})
+
+	t.Run("Bounded Percentage", func(t *testing.T) {
+		_ = os.Setenv("BPL_JVM_HEAD_ROOM", "10%,min=128M,max=2G")
+		defer func() { _ = os.Unsetenv("BPL_JVM_HEAD_ROOM") }()
+
+		c := &calc.Calculator{HeadRoom: DefaultHeadroom}
+		if err := mc.parseHeadroomConfig(c); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if c.HeadRoomSize == nil || c.HeadRoomSize.String() != "10%,min=128M,max=2G" {
+			t.Errorf("Expected bounded headroom, got %+v", c.HeadRoomSize)
+		}
+	})
+
 	t.Run("Invalid Value", func(t *testing.T) {
 		_ = os.Setenv("BPL_JVM_HEAD_ROOM", "invalid")
 		defer func() { _ = os.Unsetenv("BPL_JVM_HEAD_ROOM") }()
@@ -267,7 +296,7 @@ func TestParseClassCountConfig(t *testing.T) {
 		defer cleanupEnv()
 
 		c := &calc.Calculator{}
-		err := mc.parseClassCountConfig(c, "")
+		_, err := mc.parseClassCountConfig(c, "")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -290,7 +319,7 @@ func TestParseClassCountConfig(t *testing.T) {
 		// = 1001 * 0.35 = 350.35 -> 350
 
 		c := &calc.Calculator{}
-		err := mc.parseClassCountConfig(c, "")
+		_, err := mc.parseClassCountConfig(c, "")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -317,7 +346,7 @@ func TestParseClassCountConfig(t *testing.T) {
 		// = 2101 * 1.5 * 0.35 = 3151.5 * 0.35 = 1103.025 -> 1103
 
 		c := &calc.Calculator{}
-		err := mc.parseClassCountConfig(c, "")
+		_, err := mc.parseClassCountConfig(c, "")
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -327,3 +356,643 @@ func TestParseClassCountConfig(t *testing.T) {
 		}
 	})
 }
+
+func TestCalculateWithJavaHome(t *testing.T) {
+	tempDir := t.TempDir()
+	javaHome := t.TempDir()
+
+	_ = os.Setenv("BPI_APPLICATION_PATH", tempDir)
+	_ = os.Setenv("BPL_JVM_TOTAL_MEMORY", "1G")
+	defer func() {
+		_ = os.Unsetenv("BPI_APPLICATION_PATH")
+		_ = os.Unsetenv("BPL_JVM_TOTAL_MEMORY")
+		_ = os.Unsetenv("JAVA_HOME")
+	}()
+
+	t.Run("Java 17 runtime", func(t *testing.T) {
+		writeRelease(t, javaHome, "JAVA_VERSION=\"17.0.9\"\nIMPLEMENTOR=\"Eclipse Adoptium\"\n")
+		_ = os.Setenv("JAVA_HOME", javaHome)
+
+		result, err := Create(true).Calculate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Release == nil || result.Release.Major != 17 {
+			t.Fatalf("Expected Java 17 to be detected, got %v", result.Release)
+		}
+		if result.Calculator.JavaVersion != 17 {
+			t.Errorf("Expected calculator Java version 17, got %d", result.Calculator.JavaVersion)
+		}
+		if !strings.Contains(result.Properties["JAVA_TOOL_OPTIONS"], "-XX:MaxMetaspaceSize=") {
+			t.Errorf("Expected metaspace flag, got %s", result.Properties["JAVA_TOOL_OPTIONS"])
+		}
+	})
+
+	t.Run("Java 7 runtime", func(t *testing.T) {
+		writeRelease(t, javaHome, "JAVA_VERSION=\"1.7.0_80\"\n")
+		_ = os.Setenv("JAVA_HOME", javaHome)
+
+		result, err := Create(true).Calculate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		options := result.Properties["JAVA_TOOL_OPTIONS"]
+		if strings.Contains(options, "-XX:MaxMetaspaceSize=") || !strings.Contains(options, "-XX:MaxPermSize=") {
+			t.Errorf("Expected permanent generation instead of metaspace flag for Java 7, got %s", options)
+		}
+		if !strings.Contains(options, "-XX:ReservedCodeCacheSize=48M") {
+			t.Errorf("Expected 48M code cache for Java 7, got %s", options)
+		}
+	})
+
+	t.Run("Missing release file", func(t *testing.T) {
+		_ = os.Setenv("JAVA_HOME", t.TempDir())
+
+		result, err := Create(true).Calculate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Release != nil {
+			t.Errorf("Expected no release to be detected, got %v", result.Release)
+		}
+	})
+}
+
+func writeRelease(t *testing.T, javaHome, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(javaHome, "release"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseVirtualThreadConfig(t *testing.T) {
+	mc := Create(true)
+
+	t.Run("Virtual thread count", func(t *testing.T) {
+		_ = os.Setenv("BPL_JVM_VIRTUAL_THREAD_COUNT", "5000")
+		_ = os.Setenv("BPL_JVM_VIRTUAL_THREAD_STACK_SIZE", "32K")
+		defer func() {
+			_ = os.Unsetenv("BPL_JVM_VIRTUAL_THREAD_COUNT")
+			_ = os.Unsetenv("BPL_JVM_VIRTUAL_THREAD_STACK_SIZE")
+		}()
+
+		c := &calc.Calculator{}
+		if err := mc.parseVirtualThreadConfig(c, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if c.VirtualThreadCount != 5000 {
+			t.Errorf("Expected virtual thread count 5000, got %d", c.VirtualThreadCount)
+		}
+		if c.VirtualThreadStack.Value != 32*calc.Kibi {
+			t.Errorf("Expected virtual thread stack 32K, got %s", c.VirtualThreadStack)
+		}
+	})
+
+	t.Run("Ignored before Java 21", func(t *testing.T) {
+		_ = os.Setenv("BPL_JVM_VIRTUAL_THREAD_COUNT", "5000")
+		defer func() { _ = os.Unsetenv("BPL_JVM_VIRTUAL_THREAD_COUNT") }()
+
+		c := &calc.Calculator{}
+		if err := mc.parseVirtualThreadConfig(c, &jvm.Release{JavaVersion: "17.0.9", Major: 17}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if c.VirtualThreadCount != 0 {
+			t.Errorf("Expected virtual threads to be ignored on Java 17, got %d", c.VirtualThreadCount)
+		}
+	})
+
+	t.Run("Invalid value", func(t *testing.T) {
+		_ = os.Setenv("BPL_JVM_VIRTUAL_THREAD_COUNT", "many")
+		defer func() { _ = os.Unsetenv("BPL_JVM_VIRTUAL_THREAD_COUNT") }()
+
+		if err := mc.parseVirtualThreadConfig(&calc.Calculator{}, nil); err == nil {
+			t.Error("Expected error for invalid virtual thread count")
+		}
+	})
+}
+
+func TestParseCodeCacheConfig(t *testing.T) {
+	mc := Create(true)
+
+	_ = os.Setenv("BPL_JVM_SEGMENTED_CODE_CACHE", "true")
+	defer func() { _ = os.Unsetenv("BPL_JVM_SEGMENTED_CODE_CACHE") }()
+
+	c := &calc.Calculator{}
+	if err := mc.parseCodeCacheConfig(c, &jvm.Release{JavaVersion: "17.0.9", Major: 17}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !c.SegmentedCodeCache {
+		t.Error("Expected segmented code cache on Java 17")
+	}
+
+	c = &calc.Calculator{}
+	if err := mc.parseCodeCacheConfig(c, &jvm.Release{JavaVersion: "1.8.0_392", Major: 8}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.SegmentedCodeCache {
+		t.Error("Expected segmented code cache to be ignored on Java 8")
+	}
+
+	_ = os.Setenv("BPL_JVM_SEGMENTED_CODE_CACHE", "sometimes")
+	if err := mc.parseCodeCacheConfig(&calc.Calculator{}, nil); err == nil {
+		t.Error("Expected error for invalid boolean")
+	}
+}
+
+func TestDirectMemoryRules(t *testing.T) {
+	mc := Create(true)
+
+	rules, err := mc.directMemoryRules([]string{"netty-buffer", "kafka-clients"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Library != "netty-buffer" || rules[1].Library != "kafka-clients" {
+		t.Errorf("Expected rules for netty-buffer and kafka-clients, got %+v", rules)
+	}
+
+	_ = os.Setenv("BPL_JVM_DIRECT_MEMORY_RULES", "netty-buffer=256M")
+	defer func() { _ = os.Unsetenv("BPL_JVM_DIRECT_MEMORY_RULES") }()
+
+	rules, err = mc.directMemoryRules([]string{"netty-buffer"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Size.Absolute.Value != 256*calc.Mebi {
+		t.Errorf("Expected overridden netty-buffer rule of 256M, got %+v", rules)
+	}
+
+	if rules, _ = mc.directMemoryRules(nil); rules != nil {
+		t.Errorf("Expected no rules without detected libraries, got %+v", rules)
+	}
+
+	_ = os.Setenv("BPL_JVM_DIRECT_MEMORY_RULES", "netty-buffer")
+	if _, err := mc.directMemoryRules(nil); err == nil {
+		t.Error("Expected error for invalid rule")
+	}
+}
+
+func TestDirectMemoryWithConfiguredClassCount(t *testing.T) {
+	appPath := t.TempDir()
+	writeJar(t, filepath.Join(appPath, "netty-buffer-4.1.100.jar"), "io/netty/buffer/PooledByteBufAllocator.class")
+
+	_ = os.Setenv("BPI_APPLICATION_PATH", appPath)
+	_ = os.Setenv("BPL_JVM_LOADED_CLASS_COUNT", "5000")
+	_ = os.Setenv("BPL_JVM_TOTAL_MEMORY", "2G")
+	defer func() {
+		_ = os.Unsetenv("BPI_APPLICATION_PATH")
+		_ = os.Unsetenv("BPL_JVM_LOADED_CLASS_COUNT")
+		_ = os.Unsetenv("BPL_JVM_TOTAL_MEMORY")
+	}()
+
+	result, err := Create(true).Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Calculator.LoadedClassCount != 5000 {
+		t.Errorf("Expected configured class count 5000, got %d", result.Calculator.LoadedClassCount)
+	}
+	if result.Regions.DirectMemoryLibrary != "netty-buffer" {
+		t.Errorf("Expected netty-buffer to raise direct memory, got %s", result.Regions.DirectMemory)
+	}
+}
+
+func TestParseDegradeConfig(t *testing.T) {
+	mc := Create(true)
+
+	_ = os.Setenv("BPL_JVM_DEGRADE", "true")
+	_ = os.Setenv("BPL_JVM_DEGRADE_MIN_HEAP", "96M")
+	defer func() {
+		_ = os.Unsetenv("BPL_JVM_DEGRADE")
+		_ = os.Unsetenv("BPL_JVM_DEGRADE_MIN_HEAP")
+	}()
+
+	c := &calc.Calculator{}
+	if err := mc.parseDegradeConfig(c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !c.Degrade || c.MinHeap.Value != 96*calc.Mebi {
+		t.Errorf("Expected degrade mode with 96M minimum heap, got %v, %s", c.Degrade, c.MinHeap)
+	}
+
+	_ = os.Setenv("BPL_JVM_DEGRADE", "maybe")
+	if err := mc.parseDegradeConfig(&calc.Calculator{}); err == nil {
+		t.Error("Expected error for invalid boolean")
+	}
+
+	_ = os.Setenv("BPL_JVM_DEGRADE", "true")
+	_ = os.Setenv("BPL_JVM_DEGRADE_MIN_HEAP", "lots")
+	if err := mc.parseDegradeConfig(&calc.Calculator{}); err == nil {
+		t.Error("Expected error for invalid minimum heap")
+	}
+}
+
+func TestParseThreadCountConfigProvenance(t *testing.T) {
+	mc := Create(true)
+	defer func() {
+		_ = os.Unsetenv("BPL_JVM_THREAD_COUNT")
+		_ = os.Unsetenv("BPL_JVM_PROFILE_THREAD_COUNT")
+	}()
+
+	_ = os.Setenv("BPL_JVM_PROFILE_THREAD_COUNT", "100")
+	c := &calc.Calculator{ThreadCount: DefaultThreadCount}
+	if err := mc.parseThreadCountConfig(c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.ThreadCount != 100 || c.ThreadCountProvenance == calc.UserConfigured {
+		t.Errorf("Expected profile thread count 100 not to be user configured, got %d %v",
+			c.ThreadCount, c.ThreadCountProvenance)
+	}
+
+	_ = os.Setenv("BPL_JVM_THREAD_COUNT", "250")
+	if err := mc.parseThreadCountConfig(c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.ThreadCount != 250 || c.ThreadCountProvenance != calc.UserConfigured {
+		t.Errorf("Expected explicit thread count 250 to be user configured, got %d %v",
+			c.ThreadCount, c.ThreadCountProvenance)
+	}
+
+	_ = os.Setenv("BPL_JVM_PROFILE_THREAD_COUNT", "many")
+	_ = os.Unsetenv("BPL_JVM_THREAD_COUNT")
+	if err := mc.parseThreadCountConfig(c); err == nil {
+		t.Error("Expected error for invalid profile thread count")
+	}
+}
+
+func TestParseProfileSizeConfig(t *testing.T) {
+	mc := Create(true)
+	defer func() {
+		_ = os.Unsetenv("BPL_JVM_PROFILE_CODE_CACHE_SIZE")
+		_ = os.Unsetenv("BPL_JVM_PROFILE_DIRECT_MEMORY_SIZE")
+	}()
+
+	_ = os.Setenv("BPL_JVM_PROFILE_CODE_CACHE_SIZE", "256M")
+	_ = os.Setenv("BPL_JVM_PROFILE_DIRECT_MEMORY_SIZE", "128M")
+	c := &calc.Calculator{}
+	if err := mc.parseProfileSizeConfig(c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.ReservedCodeCache.Value != 256*calc.Mebi || c.DirectMemory.Value != 128*calc.Mebi {
+		t.Errorf("Expected profile code cache 256M and direct memory 128M, got %s and %s",
+			c.ReservedCodeCache, c.DirectMemory)
+	}
+
+	// The profile sizes are no user configured flags
+	if extra, err := mc.profileExtras(""); err != nil || len(extra) != 0 {
+		t.Errorf("Expected no extra options for the profile sizes, got %v, %v", extra, err)
+	}
+
+	_ = os.Setenv("BPL_JVM_PROFILE_DIRECT_MEMORY_SIZE", "lots")
+	if err := mc.parseProfileSizeConfig(c); err == nil {
+		t.Error("Expected error for invalid profile direct memory size")
+	}
+}
+
+func TestProfileOptions(t *testing.T) {
+	mc := Create(true)
+
+	if opts, err := mc.profileOptions("-Xss512K"); err != nil || opts != "-Xss512K" {
+		t.Errorf("Expected options to be unchanged without profile settings, got %q, %v", opts, err)
+	}
+
+	_ = os.Setenv("BPL_JVM_CODE_CACHE_SIZE", "128M")
+	_ = os.Setenv("BPL_JVM_DIRECT_MEMORY_SIZE", "64M")
+	_ = os.Setenv("BPL_JVM_EXTRA_OPTIONS", "-XX:+ExitOnOutOfMemoryError -XX:+AlwaysPreTouch")
+	defer func() {
+		_ = os.Unsetenv("BPL_JVM_CODE_CACHE_SIZE")
+		_ = os.Unsetenv("BPL_JVM_DIRECT_MEMORY_SIZE")
+		_ = os.Unsetenv("BPL_JVM_EXTRA_OPTIONS")
+	}()
+
+	opts, err := mc.profileOptions("-XX:MaxDirectMemorySize=32M -XX:-AlwaysPreTouch")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "-XX:MaxDirectMemorySize=32M -XX:-AlwaysPreTouch -XX:ReservedCodeCacheSize=128M " +
+		"-XX:+ExitOnOutOfMemoryError"
+	if opts != expected {
+		t.Errorf("Expected %q, got %q", expected, opts)
+	}
+
+	_ = os.Setenv("BPL_JVM_CODE_CACHE_SIZE", "lots")
+	if _, err := mc.profileOptions(""); err == nil {
+		t.Error("Expected error for invalid code cache size")
+	}
+}
+
+func TestParseInitialHeapConfig(t *testing.T) {
+	mc := Create(true)
+
+	_ = os.Setenv("BPL_JVM_INITIAL_HEAP", "max")
+	defer func() { _ = os.Unsetenv("BPL_JVM_INITIAL_HEAP") }()
+
+	c := &calc.Calculator{}
+	if err := mc.parseInitialHeapConfig(c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.InitialHeap == nil || c.InitialHeap.Percent != 100 {
+		t.Errorf("Expected initial heap of 100%%, got %+v", c.InitialHeap)
+	}
+
+	_ = os.Setenv("BPL_JVM_INITIAL_HEAP", "min")
+	if err := mc.parseInitialHeapConfig(&calc.Calculator{}); err == nil {
+		t.Error("Expected error for invalid initial heap policy")
+	}
+}
+
+func TestParseMetaspaceConfig(t *testing.T) {
+	mc := Create(true)
+
+	_ = os.Setenv("BPL_JVM_CLASS_SIZE", "8K")
+	_ = os.Setenv("BPL_JVM_CLASS_OVERHEAD", "20M")
+	defer func() {
+		_ = os.Unsetenv("BPL_JVM_CLASS_SIZE")
+		_ = os.Unsetenv("BPL_JVM_CLASS_OVERHEAD")
+	}()
+
+	c := &calc.Calculator{}
+	if err := mc.parseMetaspaceConfig(c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.ClassSize.Value != 8*calc.Kibi || c.ClassOverhead.Value != 20*calc.Mebi {
+		t.Errorf("Expected class size 8K and overhead 20M, got %s and %s", c.ClassSize, c.ClassOverhead)
+	}
+
+	// The documented defaults
+	_ = os.Setenv("BPL_JVM_CLASS_SIZE", "5800")
+	_ = os.Setenv("BPL_JVM_CLASS_OVERHEAD", "14000000")
+	if err := mc.parseMetaspaceConfig(c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.ClassSize.Value != calc.ClassSize || c.ClassOverhead.Value != calc.ClassOverhead {
+		t.Errorf("Expected default class size and overhead, got %s and %s", c.ClassSize, c.ClassOverhead)
+	}
+
+	_ = os.Setenv("BPL_JVM_CLASS_SIZE", "big")
+	if err := mc.parseMetaspaceConfig(&calc.Calculator{}); err == nil {
+		t.Error("Expected error for invalid class size")
+	}
+}
+
+func TestParseClassCountConfigWithLanguageWeights(t *testing.T) {
+	mc := Create(true)
+
+	dir := t.TempDir()
+	for _, name := range []string{"App.class", "build.groovy", "deploy.groovy"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("content"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_ = os.Setenv("BPI_APPLICATION_PATH", dir)
+	_ = os.Setenv("BPI_JVM_CLASS_COUNT", "0")
+	_ = os.Setenv("BPL_JVM_CLASS_LOAD_FACTOR", "1")
+	_ = os.Setenv("BPL_JVM_LANGUAGE_WEIGHTS", "groovy=5")
+	defer func() {
+		_ = os.Unsetenv("BPI_APPLICATION_PATH")
+		_ = os.Unsetenv("BPI_JVM_CLASS_COUNT")
+		_ = os.Unsetenv("BPL_JVM_CLASS_LOAD_FACTOR")
+		_ = os.Unsetenv("BPL_JVM_LANGUAGE_WEIGHTS")
+	}()
+
+	c := &calc.Calculator{}
+	if _, err := mc.parseClassCountConfig(c, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.LoadedClassCount != 11 {
+		t.Errorf("Expected 11 loaded classes, got %d", c.LoadedClassCount)
+	}
+
+	_ = os.Setenv("BPL_JVM_LANGUAGE_WEIGHTS", "scala=2")
+	if _, err := mc.parseClassCountConfig(&calc.Calculator{}, ""); err == nil {
+		t.Error("Expected error for unknown language")
+	}
+}
+
+func TestClassLoad(t *testing.T) {
+	mc := Create(true)
+
+	factor, dynamic, err := mc.classLoad(nil)
+	if err != nil || factor != ClassLoadFactor || dynamic != 0 {
+		t.Errorf("Expected default load factor without frameworks, got %g, %d, %v", factor, dynamic, err)
+	}
+
+	factor, dynamic, err = mc.classLoad([]string{"hibernate", "spring-boot"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if factor != 0.6 || dynamic != 6_000 {
+		t.Errorf("Expected load factor 0.6 and 6000 dynamic classes, got %g and %d", factor, dynamic)
+	}
+
+	_ = os.Setenv("BPL_JVM_PROFILE_CLASS_LOAD_FACTOR", "0.3")
+	defer func() { _ = os.Unsetenv("BPL_JVM_PROFILE_CLASS_LOAD_FACTOR") }()
+
+	if factor, _, _ = mc.classLoad(nil); factor != 0.3 {
+		t.Errorf("Expected profile load factor 0.3 without frameworks, got %g", factor)
+	}
+	if factor, _, _ = mc.classLoad([]string{"hibernate", "spring-boot"}); factor != 0.6 {
+		t.Errorf("Expected detected frameworks to take precedence over the profile, got %g", factor)
+	}
+
+	_ = os.Setenv("BPL_JVM_CLASS_LOAD_FACTOR", "0.25")
+	defer func() { _ = os.Unsetenv("BPL_JVM_CLASS_LOAD_FACTOR") }()
+
+	if factor, dynamic, _ = mc.classLoad([]string{"spring-boot"}); factor != 0.25 || dynamic != 4_000 {
+		t.Errorf("Expected configured load factor 0.25 with 4000 dynamic classes, got %g and %d", factor, dynamic)
+	}
+}
+
+func TestScanAppServers(t *testing.T) {
+	mc := Create(true)
+
+	home := t.TempDir()
+	for _, dir := range []string{"lib", "webapps/ROOT/WEB-INF/classes"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0o750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"lib/Catalina.class", "lib/Connector.class", "webapps/ROOT/WEB-INF/classes/App.class"} {
+		if err := os.WriteFile(filepath.Join(home, file), []byte("class"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_ = os.Setenv("CATALINA_HOME", home)
+	_ = os.Setenv("CATALINA_BASE", home)
+	defer func() {
+		_ = os.Unsetenv("CATALINA_HOME")
+		_ = os.Unsetenv("CATALINA_BASE")
+	}()
+
+	r, err := mc.scanAppServers(count.Result{Classes: 100}, t.TempDir(), count.Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if r.Classes != 103 {
+		t.Errorf("Expected 103 classes including the application server, got %d", r.Classes)
+	}
+
+	// Directories inside the application path are already counted
+	if r, _ = mc.scanAppServers(count.Result{Classes: 100}, home, count.Options{}); r.Classes != 100 {
+		t.Errorf("Expected application server inside the application path to be skipped, got %d", r.Classes)
+	}
+}
+
+func TestScanAppServersUnique(t *testing.T) {
+	mc := Create(true)
+
+	// The application shares a class with an application deployed to the server
+	app, home := t.TempDir(), t.TempDir()
+	for _, file := range []string{
+		filepath.Join(app, "com/example/App.class"),
+		filepath.Join(app, "com/example/Shared.class"),
+		filepath.Join(home, "webapps/ROOT/WEB-INF/classes/com/example/Shared.class"),
+		filepath.Join(home, "webapps/ROOT/WEB-INF/classes/org/server/Server.class"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(file), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("class"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_ = os.Setenv("CATALINA_HOME", home)
+	defer func() { _ = os.Unsetenv("CATALINA_HOME") }()
+
+	options := count.Options{Unique: true}
+	scan, err := count.ScanWith(app, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := mc.scanAppServers(scan, app, options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if r.Classes != 4 || r.UniqueClasses != 3 {
+		t.Errorf("Expected 4 class files and 3 unique classes, got %d and %d", r.Classes, r.UniqueClasses)
+	}
+}
+
+func TestParseClassCountConfigUnique(t *testing.T) {
+	mc := Create(true)
+
+	dir := t.TempDir()
+	for _, name := range []string{"a/com/example/App.class", "b/com/example/App.class", "a/module-info.class"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("class"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_ = os.Setenv("BPI_APPLICATION_PATH", filepath.Join(dir, "a"))
+	_ = os.Setenv("BPI_JVM_CLASS_COUNT", "0")
+	_ = os.Setenv("BPL_JVM_CLASS_LOAD_FACTOR", "1")
+	_ = os.Setenv("BPI_CLASS_COUNT_MODE", "unique")
+	defer func() {
+		_ = os.Unsetenv("BPI_APPLICATION_PATH")
+		_ = os.Unsetenv("BPI_JVM_CLASS_COUNT")
+		_ = os.Unsetenv("BPL_JVM_CLASS_LOAD_FACTOR")
+		_ = os.Unsetenv("BPI_CLASS_COUNT_MODE")
+	}()
+
+	c := &calc.Calculator{}
+	scan, err := mc.parseClassCountConfig(c, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if scan.Classes != 2 || scan.UniqueClasses != 1 {
+		t.Errorf("Expected 2 class files and 1 unique class, got %d and %d", scan.Classes, scan.UniqueClasses)
+	}
+	if c.LoadedClassCount != 1 {
+		t.Errorf("Expected the unique classes to be loaded, got %d", c.LoadedClassCount)
+	}
+}
+
+func TestClassCountCache(t *testing.T) {
+	mc := Create(true)
+
+	path := filepath.Join(t.TempDir(), "cache.json")
+	_ = os.Setenv("BPI_CLASS_COUNT_CACHE", path)
+	defer func() {
+		_ = os.Unsetenv("BPI_CLASS_COUNT_CACHE")
+		_ = os.Unsetenv("BPI_CLASS_COUNT_NO_CACHE")
+	}()
+
+	if c, err := mc.classCountCache(); err != nil || c == nil {
+		t.Fatalf("Expected a cache, got %v, %v", c, err)
+	}
+
+	// A corrupt cache is replaced
+	if err := os.WriteFile(path, []byte("corrupt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := mc.classCountCache(); err != nil || c == nil {
+		t.Errorf("Expected a corrupt cache to be replaced, got %v, %v", c, err)
+	}
+
+	_ = os.Setenv("BPI_CLASS_COUNT_NO_CACHE", "true")
+	if c, err := mc.classCountCache(); err != nil || c != nil {
+		t.Errorf("Expected no cache, got %v, %v", c, err)
+	}
+
+	_ = os.Setenv("BPI_CLASS_COUNT_NO_CACHE", "maybe")
+	if _, err := mc.classCountCache(); err == nil {
+		t.Error("Expected error for invalid boolean")
+	}
+}
+
+func TestClassList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "classes.log")
+	var b strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&b, "[0.%03ds][info][class,load] com.example.Class%d source: file:/app/\n", i%1000, i)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = os.Unsetenv("BPL_JVM_LOADED_CLASS_COUNT")
+	_ = os.Setenv("BPL_JVM_CLASS_LIST", path)
+	defer func() {
+		_ = os.Unsetenv("BPL_JVM_CLASS_LIST")
+		_ = os.Unsetenv("BPL_JVM_CLASS_LIST_MARGIN")
+	}()
+
+	mc := Create(true)
+	setup, err := mc.Configure()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := setup.Calculator; c.LoadedClassCount != 2200 || c.LoadedClassCountProvenance != calc.Measured {
+		t.Errorf("Expected 2200 measured classes with the default margin, got %d (%d)",
+			c.LoadedClassCount, c.LoadedClassCountProvenance)
+	}
+
+	_ = os.Setenv("BPL_JVM_CLASS_LIST_MARGIN", "25")
+	if setup, err = mc.Configure(); err != nil {
+		t.Fatal(err)
+	}
+	if n := setup.Calculator.LoadedClassCount; n != 2500 {
+		t.Errorf("Expected 2500 classes with a margin of 25%%, got %d", n)
+	}
+
+	_ = os.Setenv("BPL_JVM_CLASS_LIST_MARGIN", "-150")
+	if _, err := mc.Configure(); err == nil {
+		t.Error("Expected an error for a negative margin")
+	}
+	_ = os.Unsetenv("BPL_JVM_CLASS_LIST_MARGIN")
+
+	_ = os.Setenv("BPL_JVM_CLASS_LIST", filepath.Join(t.TempDir(), "missing.log"))
+	if _, err := mc.Configure(); err == nil {
+		t.Error("Expected an error for a missing class list")
+	}
+}
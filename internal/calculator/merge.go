@@ -0,0 +1,342 @@
+package calculator
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/jvm"
+	"github.com/patbaumgartner/memory-calculator/internal/parser"
+)
+
+// Memory settings configured by memory flags.
+const (
+	heapSetting         = "heap"
+	initialHeapSetting  = "initial heap"
+	stackSetting        = "thread stack"
+	metaspaceSetting    = "metaspace"
+	directMemorySetting = "direct memory"
+	codeCacheSetting    = "code cache"
+)
+
+// memorySettings lists the memory settings in the order conflicts are reported.
+var memorySettings = []string{
+	heapSetting, initialHeapSetting, stackSetting, metaspaceSetting, directMemorySetting, codeCacheSetting,
+}
+
+// memorySpellings maps the prefixes of the flags that set a memory setting to the prefix the calculator uses.
+var memorySpellings = []struct {
+	prefix    string
+	setting   string
+	canonical string
+}{
+	{"-Xmx", heapSetting, "-Xmx"},
+	{"-XX:MaxHeapSize=", heapSetting, "-Xmx"},
+	{"-Xms", initialHeapSetting, "-Xms"},
+	{"-XX:InitialHeapSize=", initialHeapSetting, "-Xms"},
+	{"-Xss", stackSetting, "-Xss"},
+	{"-XX:ThreadStackSize=", stackSetting, "-Xss"},
+	{"-XX:MaxMetaspaceSize=", metaspaceSetting, "-XX:MaxMetaspaceSize="},
+	{"-XX:MaxDirectMemorySize=", directMemorySetting, "-XX:MaxDirectMemorySize="},
+	{"-XX:ReservedCodeCacheSize=", codeCacheSetting, "-XX:ReservedCodeCacheSize="},
+}
+
+// ergonomicFlags maps the flags that derive a memory setting from the container memory to the setting. The JVM
+// ignores them if the setting is configured explicitly.
+var ergonomicFlags = map[string]string{
+	"-XX:MaxRAM":               heapSetting,
+	"-XX:MaxRAMPercentage":     heapSetting,
+	"-XX:MaxRAMFraction":       heapSetting,
+	"-XX:MinRAMPercentage":     heapSetting,
+	"-XX:MinRAMFraction":       heapSetting,
+	"-XX:InitialRAMPercentage": initialHeapSetting,
+	"-XX:InitialRAMFraction":   initialHeapSetting,
+}
+
+// Conflict describes memory flags that configure the same memory setting, and how the merge resolved them.
+type Conflict struct {
+	// Setting is the memory setting the flags configure, e.g. "heap".
+	Setting string
+
+	// Flags are the user's flags involved in the conflict, in the order the JVM applies them.
+	Flags []UserFlag
+
+	// Resolution describes which flag takes effect.
+	Resolution string
+
+	// Duplicate is true if the flags repeat the same value, so that dropping all but one does not change anything.
+	Duplicate bool
+}
+
+func (c Conflict) String() string {
+	flags := make([]string, 0, len(c.Flags))
+	for _, f := range c.Flags {
+		flags = append(flags, f.String())
+	}
+	return fmt.Sprintf("%s: %s; %s", c.Setting, strings.Join(flags, ", "), c.Resolution)
+}
+
+// memoryFlag is a memory flag in a source of the user's JVM options.
+type memoryFlag struct {
+	UserFlag
+
+	// setting is the memory setting the flag configures.
+	setting string
+
+	// normalized is the flag in the spelling of the calculator, e.g. -Xmx1G for -XX:MaxHeapSize=1G, or empty if the
+	// flag is ergonomic.
+	normalized string
+
+	// size is the size the flag configures, or empty if the flag is ergonomic.
+	size string
+
+	// index is the position of the flag in the options of its source.
+	index int
+}
+
+// ergonomic returns true if the flag derives its setting from the container memory, like -XX:MaxRAMPercentage.
+func (f memoryFlag) ergonomic() bool {
+	return f.normalized == ""
+}
+
+// classifyFlag returns the memory setting the option configures, the option in the spelling of the calculator and
+// the size it configures. normalized and size are empty for ergonomic flags. ok is false if the option is no memory
+// flag.
+func classifyFlag(o string) (setting string, normalized string, size string, ok bool) {
+	if s, ok := ergonomicFlags[optionName(o)]; ok && strings.Contains(o, "=") {
+		return s, "", "", true
+	}
+
+	for _, s := range memorySpellings {
+		value, found := strings.CutPrefix(o, s.prefix)
+		if !found || value == "" {
+			continue
+		}
+		if s.prefix == "-XX:ThreadStackSize=" {
+			// The thread stack size is given in kibibytes
+			if strings.Trim(value, "0123456789") != "" {
+				return "", "", "", false
+			}
+			value += "K"
+		}
+		return s.setting, s.canonical + value, value, true
+	}
+	return "", "", "", false
+}
+
+// merge holds the user's JVM options merged with the calculation according to a merge policy.
+type merge struct {
+	// options holds the user's options without memory flags, followed by the memory flags the calculation respects
+	// in the spelling of the calculator.
+	options string
+
+	// output holds the options of the output variable without the memory flags that never take effect or are
+	// replaced by calculated flags.
+	output string
+
+	// conflicts lists the conflicting memory flags and how they were resolved.
+	conflicts []Conflict
+
+	// respected holds the memory settings configured by ergonomic flags that calculated flags must not override.
+	respected map[string]bool
+}
+
+// mergeOptions merges the memory flags of the user's JVM options according to the merge policy. Flags that
+// configure the same memory setting are resolved like the JVM resolves them: the flag applied last takes effect, and
+// an explicit size takes precedence over ergonomic flags like -XX:MaxRAMPercentage. calculated holds the memory
+// settings the calculation emits flags for. Flags that never take effect or are replaced by calculated flags are
+// removed from the output variable, unless it refers to argument files.
+func mergeOptions(sources []jvm.OptionSource, output string, policy string, calculated []string) (merge, error) {
+	var flags []memoryFlag
+	var options []string
+	for _, s := range sources {
+		p, err := sourceFlags(s)
+		if err != nil {
+			return merge{}, err
+		}
+
+		for i, o := range p {
+			setting, normalized, size, ok := classifyFlag(o)
+			if !ok {
+				options = append(options, o)
+				continue
+			}
+			flags = append(flags, memoryFlag{
+				UserFlag: UserFlag{Flag: o, Source: s.Name},
+				setting:  setting, normalized: normalized, size: size, index: i,
+			})
+		}
+	}
+
+	m := merge{respected: map[string]bool{}}
+	removed := map[int]bool{}
+	drop := func(f memoryFlag) {
+		if f.Source == output {
+			removed[f.index] = true
+		}
+	}
+
+	for _, setting := range memorySettings {
+		var explicit []memoryFlag
+		ergonomic := map[string][]memoryFlag{}
+		var names []string
+		for _, f := range flags {
+			switch {
+			case f.setting != setting:
+			case f.ergonomic():
+				if _, ok := ergonomic[optionName(f.Flag)]; !ok {
+					names = append(names, optionName(f.Flag))
+				}
+				ergonomic[optionName(f.Flag)] = append(ergonomic[optionName(f.Flag)], f)
+			default:
+				explicit = append(explicit, f)
+			}
+		}
+
+		var effective []memoryFlag
+		for _, n := range names {
+			f, conflicts := resolveRepeated(ergonomic[n], drop)
+			m.conflicts = append(m.conflicts, conflicts...)
+			effective = append(effective, f)
+		}
+
+		if len(explicit) > 0 {
+			last, conflicts := resolveRepeated(explicit, drop)
+			m.conflicts = append(m.conflicts, conflicts...)
+
+			for _, e := range effective {
+				m.conflicts = append(m.conflicts, Conflict{
+					Setting:    setting,
+					Flags:      []UserFlag{e.UserFlag, last.UserFlag},
+					Resolution: fmt.Sprintf("the JVM ignores %s", e.Flag),
+				})
+				drop(e)
+			}
+
+			switch {
+			case policy != jvm.Override || !slices.Contains(calculated, setting):
+				options = append(options, last.normalized)
+			case applyOrder(last.Source) > applyOrder(output):
+				m.conflicts = append(m.conflicts, Conflict{
+					Setting: setting,
+					Flags:   []UserFlag{last.UserFlag},
+					Resolution: fmt.Sprintf("%s is applied after $%s and cannot be overridden by the calculated %s",
+						last.Source, output, setting),
+				})
+				options = append(options, last.normalized)
+			default:
+				m.conflicts = append(m.conflicts, Conflict{
+					Setting:    setting,
+					Flags:      []UserFlag{last.UserFlag},
+					Resolution: fmt.Sprintf("the calculated %s replaces %s", setting, last.Flag),
+				})
+				drop(last)
+			}
+			continue
+		}
+
+		if len(effective) == 0 || !slices.Contains(calculated, setting) {
+			continue
+		}
+		c := Conflict{Setting: setting}
+		for _, e := range effective {
+			c.Flags = append(c.Flags, e.UserFlag)
+		}
+		if policy == jvm.Override {
+			c.Resolution = fmt.Sprintf("the calculated %s replaces them", setting)
+			for _, e := range effective {
+				drop(e)
+			}
+		} else {
+			c.Resolution = fmt.Sprintf("the calculated %s is not emitted", setting)
+			m.respected[setting] = true
+		}
+		m.conflicts = append(m.conflicts, c)
+	}
+
+	if policy == jvm.FailOnConflict {
+		var conflicts []string
+		for _, c := range m.conflicts {
+			if !c.Duplicate {
+				conflicts = append(conflicts, c.String())
+			}
+		}
+		if len(conflicts) > 0 {
+			return merge{}, fmt.Errorf("conflicting memory flags with $BPL_JVM_MERGE_POLICY=%s\n%s",
+				jvm.FailOnConflict, strings.Join(conflicts, "\n"))
+		}
+	}
+
+	m.options = parser.FormatFlags(options)
+	m.output = os.Getenv(output)
+	if len(removed) > 0 {
+		// Only remove flags if each option of the output variable is a flag, not an argument file
+		split, err := parser.SplitFlags(m.output)
+		expanded, _ := sourceFlags(jvm.OptionSource{Name: output, Options: m.output})
+		if err == nil && slices.Equal(split, expanded) {
+			var values []string
+			for i, o := range split {
+				if !removed[i] {
+					values = append(values, o)
+				}
+			}
+			m.output = parser.FormatFlags(values)
+		}
+	}
+	return m, nil
+}
+
+// resolveRepeated returns the flag the JVM applies of flags configuring the same setting, which is the last one,
+// together with a conflict for every other flag. The other flags are passed to drop.
+func resolveRepeated(flags []memoryFlag, drop func(memoryFlag)) (memoryFlag, []Conflict) {
+	last := flags[len(flags)-1]
+	var conflicts []Conflict
+	for _, f := range flags[:len(flags)-1] {
+		c := Conflict{Setting: last.setting, Flags: []UserFlag{f.UserFlag, last.UserFlag}}
+		if f.Flag == last.Flag || sameSize(f.size, last.size) {
+			c.Resolution, c.Duplicate = "duplicate, the JVM applies the same value", true
+		} else {
+			c.Resolution = fmt.Sprintf("the JVM applies %s", last.Flag)
+		}
+		conflicts = append(conflicts, c)
+		drop(f)
+	}
+	return last, conflicts
+}
+
+// sameSize returns true if both sizes are set and equal, e.g. 1G and 1024M.
+func sameSize(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	x, err := calc.ParseSize(a)
+	if err != nil {
+		return a == b
+	}
+	y, err := calc.ParseSize(b)
+	return err == nil && x.Value == y.Value
+}
+
+// mergePolicy returns the merge policy configured in $BPL_JVM_MERGE_POLICY, jvm.RespectUser by default.
+func mergePolicy() (string, error) {
+	s, ok := os.LookupEnv("BPL_JVM_MERGE_POLICY")
+	if !ok || s == "" {
+		return jvm.RespectUser, nil
+	}
+	if !slices.Contains(jvm.MergePolicies, s) {
+		return "", fmt.Errorf("unable to use $BPL_JVM_MERGE_POLICY=%s, expected one of %s",
+			s, strings.Join(jvm.MergePolicies, ", "))
+	}
+	return s, nil
+}
+
+// emittedFlags returns the calculated flags without those for the memory settings the user configured with
+// ergonomic flags that are respected.
+func emittedFlags(calculated []string, respected map[string]bool) []string {
+	return slices.DeleteFunc(calculated, func(o string) bool {
+		setting, _, _, ok := classifyFlag(o)
+		return ok && respected[setting]
+	})
+}
@@ -0,0 +1,259 @@
+package calculator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/patbaumgartner/memory-calculator/internal/jvm"
+)
+
+func TestClassifyFlag(t *testing.T) {
+	tests := []struct {
+		flag       string
+		setting    string
+		normalized string
+		ok         bool
+	}{
+		{flag: "-Xmx1G", setting: heapSetting, normalized: "-Xmx1G", ok: true},
+		{flag: "-XX:MaxHeapSize=1G", setting: heapSetting, normalized: "-Xmx1G", ok: true},
+		{flag: "-XX:MaxRAMPercentage=75.0", setting: heapSetting, ok: true},
+		{flag: "-XX:MaxRAM=4G", setting: heapSetting, ok: true},
+		{flag: "-XX:InitialHeapSize=256M", setting: initialHeapSetting, normalized: "-Xms256M", ok: true},
+		{flag: "-XX:InitialRAMPercentage=50", setting: initialHeapSetting, ok: true},
+		{flag: "-XX:ThreadStackSize=512", setting: stackSetting, normalized: "-Xss512K", ok: true},
+		{flag: "-XX:MaxMetaspaceSize=128M", setting: metaspaceSetting, normalized: "-XX:MaxMetaspaceSize=128M", ok: true},
+		{flag: "-XX:ThreadStackSize=1M"},
+		{flag: "-XX:+UseG1GC"},
+		{flag: "-Xmn256M"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flag, func(t *testing.T) {
+			setting, normalized, _, ok := classifyFlag(tt.flag)
+			if setting != tt.setting || normalized != tt.normalized || ok != tt.ok {
+				t.Errorf("classifyFlag(%q) = %q, %q, %t, expected %q, %q, %t",
+					tt.flag, setting, normalized, ok, tt.setting, tt.normalized, tt.ok)
+			}
+		})
+	}
+}
+
+func TestMergeOptions(t *testing.T) {
+	calculated := []string{heapSetting, stackSetting, metaspaceSetting, directMemorySetting, codeCacheSetting}
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		policy    string
+		options   string
+		output    string
+		conflicts []string
+		respected map[string]bool
+	}{
+		{
+			name:    "No conflicts",
+			env:     map[string]string{"JAVA_TOOL_OPTIONS": "-XX:MaxHeapSize=1G -XX:ThreadStackSize=512 -Dkey=value"},
+			policy:  jvm.RespectUser,
+			options: "-Dkey=value -Xmx1G -Xss512K",
+			output:  "-XX:MaxHeapSize=1G -XX:ThreadStackSize=512 -Dkey=value",
+		},
+		{
+			name:    "Duplicate",
+			env:     map[string]string{"JAVA_TOOL_OPTIONS": "-Xss1M -Dkey=value -Xss1024K"},
+			policy:  jvm.FailOnConflict,
+			options: "-Dkey=value -Xss1024K",
+			output:  "-Dkey=value -Xss1024K",
+			conflicts: []string{
+				"thread stack: -Xss1M from JAVA_TOOL_OPTIONS, -Xss1024K from JAVA_TOOL_OPTIONS; " +
+					"duplicate, the JVM applies the same value",
+			},
+		},
+		{
+			name:    "Different spellings",
+			env:     map[string]string{"JAVA_TOOL_OPTIONS": "-Xss512K", "JAVA_OPTS": "-XX:ThreadStackSize=1024"},
+			policy:  jvm.RespectUser,
+			options: "-Xss1024K",
+			conflicts: []string{
+				"thread stack: -Xss512K from JAVA_TOOL_OPTIONS, -XX:ThreadStackSize=1024 from JAVA_OPTS; " +
+					"the JVM applies -XX:ThreadStackSize=1024",
+			},
+		},
+		{
+			name:    "Ergonomic flag ignored",
+			env:     map[string]string{"JAVA_TOOL_OPTIONS": "-XX:MaxRAMPercentage=75 -Xmx1G"},
+			policy:  jvm.RespectUser,
+			options: "-Xmx1G",
+			output:  "-Xmx1G",
+			conflicts: []string{
+				"heap: -XX:MaxRAMPercentage=75 from JAVA_TOOL_OPTIONS, -Xmx1G from JAVA_TOOL_OPTIONS; " +
+					"the JVM ignores -XX:MaxRAMPercentage=75",
+			},
+		},
+		{
+			name:      "Ergonomic flag respected",
+			env:       map[string]string{"JAVA_TOOL_OPTIONS": "-XX:MaxRAMPercentage=75"},
+			policy:    jvm.RespectUser,
+			output:    "-XX:MaxRAMPercentage=75",
+			conflicts: []string{"heap: -XX:MaxRAMPercentage=75 from JAVA_TOOL_OPTIONS; the calculated heap is not emitted"},
+			respected: map[string]bool{heapSetting: true},
+		},
+		{
+			name:    "Ergonomic flag overridden",
+			env:     map[string]string{"JAVA_TOOL_OPTIONS": "-XX:MaxRAMPercentage=75 -XX:+UseG1GC"},
+			policy:  jvm.Override,
+			options: "-XX:+UseG1GC",
+			output:  "-XX:+UseG1GC",
+			conflicts: []string{
+				"heap: -XX:MaxRAMPercentage=75 from JAVA_TOOL_OPTIONS; the calculated heap replaces them",
+			},
+		},
+		{
+			name:    "Overridden",
+			env:     map[string]string{"JAVA_TOOL_OPTIONS": "-Xmx1G", "_JAVA_OPTIONS": "-Xss512K"},
+			policy:  jvm.Override,
+			options: "-Xss512K",
+			conflicts: []string{
+				"heap: -Xmx1G from JAVA_TOOL_OPTIONS; the calculated heap replaces -Xmx1G",
+				"thread stack: -Xss512K from _JAVA_OPTIONS; _JAVA_OPTIONS is applied after $JAVA_TOOL_OPTIONS and " +
+					"cannot be overridden by the calculated thread stack",
+			},
+		},
+		{
+			name:    "Initial heap not calculated",
+			env:     map[string]string{"JAVA_TOOL_OPTIONS": "-Xms256M -XX:InitialRAMPercentage=50"},
+			policy:  jvm.Override,
+			options: "-Xms256M",
+			output:  "-Xms256M",
+			conflicts: []string{
+				"initial heap: -XX:InitialRAMPercentage=50 from JAVA_TOOL_OPTIONS, -Xms256M from JAVA_TOOL_OPTIONS; " +
+					"the JVM ignores -XX:InitialRAMPercentage=50",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setOptionSources(t, tt.env)
+			sources, err := optionSources()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m, err := mergeOptions(sources, jvm.JavaToolOptions, tt.policy, calculated)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if m.options != tt.options {
+				t.Errorf("Expected options %q, got %q", tt.options, m.options)
+			}
+			if m.output != tt.output {
+				t.Errorf("Expected output %q, got %q", tt.output, m.output)
+			}
+			var conflicts []string
+			for _, c := range m.conflicts {
+				conflicts = append(conflicts, c.String())
+			}
+			if !reflect.DeepEqual(conflicts, tt.conflicts) {
+				t.Errorf("Expected conflicts %q, got %q", tt.conflicts, conflicts)
+			}
+			if tt.respected == nil {
+				tt.respected = map[string]bool{}
+			}
+			if !reflect.DeepEqual(m.respected, tt.respected) {
+				t.Errorf("Expected respected settings %v, got %v", tt.respected, m.respected)
+			}
+		})
+	}
+}
+
+func TestMergeOptionsFailOnConflict(t *testing.T) {
+	setOptionSources(t, map[string]string{"JAVA_TOOL_OPTIONS": "-Xss512K", "JAVA_OPTS": "-Xss1M"})
+	sources, err := optionSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = mergeOptions(sources, jvm.JavaToolOptions, jvm.FailOnConflict, []string{stackSetting})
+	if err == nil || !strings.Contains(err.Error(), "the JVM applies -Xss1M") {
+		t.Errorf("Expected conflict error, got %v", err)
+	}
+}
+
+func TestMergeOptionsKeepsArgFiles(t *testing.T) {
+	argFile := filepath.Join(t.TempDir(), "jvm.options")
+	if err := os.WriteFile(argFile, []byte("-Xss1M\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	setOptionSources(t, map[string]string{"JDK_JAVA_OPTIONS": "-Xss512K @" + argFile})
+	sources, err := optionSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := mergeOptions(sources, jvm.JDKJavaOptions, jvm.RespectUser, []string{stackSetting})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := "-Xss512K @" + argFile; m.output != e {
+		t.Errorf("Expected output %q, got %q", e, m.output)
+	}
+	if m.options != "-Xss1M" {
+		t.Errorf("Expected options -Xss1M, got %q", m.options)
+	}
+}
+
+func TestMergePolicy(t *testing.T) {
+	setOptionSources(t, map[string]string{"BPL_JVM_MERGE_POLICY": ""})
+	if p, err := mergePolicy(); err != nil || p != jvm.RespectUser {
+		t.Errorf("Expected respect-user by default, got %q, %v", p, err)
+	}
+
+	setOptionSources(t, map[string]string{"BPL_JVM_MERGE_POLICY": "merge"})
+	if _, err := mergePolicy(); err == nil {
+		t.Error("Expected error for an unsupported merge policy")
+	}
+}
+
+func TestCalculateMergePolicies(t *testing.T) {
+	env := map[string]string{
+		"BPI_APPLICATION_PATH":       t.TempDir(),
+		"BPL_JVM_TOTAL_MEMORY":       "2G",
+		"BPL_JVM_LOADED_CLASS_COUNT": "5000",
+		"JAVA_TOOL_OPTIONS":          "-XX:MaxRAMPercentage=75 -Xss512K -Xss512K",
+	}
+
+	setOptionSources(t, env)
+	result, err := Create(true).Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := result.Properties["JAVA_TOOL_OPTIONS"]
+	if !strings.HasPrefix(opts, "-XX:MaxRAMPercentage=75 -Xss512K ") || strings.Contains(opts, "-Xmx") {
+		t.Errorf("Expected the percentage to size the heap without a duplicate stack size, got %q", opts)
+	}
+	if len(result.Conflicts) != 2 {
+		t.Errorf("Expected 2 conflicts, got %v", result.Conflicts)
+	}
+
+	env["BPL_JVM_MERGE_POLICY"] = jvm.Override
+	setOptionSources(t, env)
+	result, err = Create(true).Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = result.Properties["JAVA_TOOL_OPTIONS"]
+	if strings.Contains(opts, "MaxRAMPercentage") || strings.Contains(opts, "-Xss512K") ||
+		!strings.Contains(opts, "-Xmx") {
+		t.Errorf("Expected calculated flags only, got %q", opts)
+	}
+
+	env["BPL_JVM_MERGE_POLICY"] = jvm.FailOnConflict
+	setOptionSources(t, env)
+	if _, err := Create(true).Calculate(); err == nil {
+		t.Error("Expected error for conflicting memory flags")
+	}
+}
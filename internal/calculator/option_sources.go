@@ -0,0 +1,155 @@
+package calculator
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/jvm"
+	"github.com/patbaumgartner/memory-calculator/internal/parser"
+)
+
+// UserFlag is a memory flag configured by the user, together with the source of JVM options it was taken from.
+type UserFlag struct {
+	// Flag is the memory flag, e.g. -Xmx1G.
+	Flag string
+
+	// Source is the environment variable the flag was configured in, or jvm.CommandLine.
+	Source string
+}
+
+func (u UserFlag) String() string {
+	return fmt.Sprintf("%s from %s", u.Flag, u.Source)
+}
+
+// optionSources returns the sources of the user's JVM options in the order the JVM applies them: the environment
+// variables of jvm.OptionVariables, with the JVM options of the java command line in $BPL_JVM_COMMAND_LINE applied
+// before $_JAVA_OPTIONS. The @argfiles of the command line are expanded. Sources without options are omitted.
+func optionSources() ([]jvm.OptionSource, error) {
+	var sources []jvm.OptionSource
+	for _, v := range jvm.OptionVariables {
+		if v == jvm.UnderscoreJavaOptions {
+			if s, ok := os.LookupEnv("BPL_JVM_COMMAND_LINE"); ok {
+				args, err := parser.ParseFlags(s)
+				if err != nil {
+					return nil, fmt.Errorf("unable to parse $BPL_JVM_COMMAND_LINE=%s\n%w", s, err)
+				}
+				sources = append(sources, jvm.OptionSource{
+					Name: jvm.CommandLine, Options: parser.FormatFlags(jvm.LauncherOptions(args)),
+				})
+			}
+		}
+		sources = append(sources, jvm.OptionSource{Name: v, Options: os.Getenv(v)})
+	}
+
+	return slices.DeleteFunc(sources, func(s jvm.OptionSource) bool {
+		return strings.TrimSpace(s.Options) == ""
+	}), nil
+}
+
+// userOptions returns the sources of the user's JVM options and their flags joined in the order the JVM applies
+// them, so that a later option overrides an earlier one as in the JVM. @argfiles are expanded as in sourceFlags, so
+// the joined options are split with parser.SplitFlags.
+func userOptions() ([]jvm.OptionSource, string, error) {
+	sources, err := optionSources()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var opts []string
+	for _, s := range sources {
+		p, err := sourceFlags(s)
+		if err != nil {
+			return nil, "", err
+		}
+		opts = append(opts, parser.FormatFlags(p))
+	}
+	return sources, joinOptions(opts...), nil
+}
+
+// sourceFlags returns the flags of a source of JVM options. Like the java launcher, @argfiles are only expanded in
+// the variables of jvm.ArgFileVariables; the command line is expanded by optionSources already.
+func sourceFlags(s jvm.OptionSource) ([]string, error) {
+	split := parser.SplitFlags
+	if slices.Contains(jvm.ArgFileVariables, s.Name) {
+		split = parser.ParseFlags
+	}
+
+	p, err := split(s.Options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse $%s\n%w", s.Name, err)
+	}
+	return p, nil
+}
+
+// userFlags returns the memory flags configured in the sources with the source each was taken from. Flags are
+// grouped by the memory setting they configure like in mergeOptions, so of -Xmx1G and -XX:MaxHeapSize=2G only the
+// flag the JVM applies last is returned. Ergonomic flags like -XX:MaxRAMPercentage are not returned.
+func userFlags(sources []jvm.OptionSource) ([]UserFlag, error) {
+	type keyedFlag struct {
+		UserFlag
+		key string
+	}
+
+	var flags []keyedFlag
+	for _, s := range sources {
+		p, err := sourceFlags(s)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range p {
+			key, normalized, _, ok := classifyFlag(o)
+			switch {
+			case ok && normalized == "":
+				continue
+			case !ok && calc.MatchCodeHeap(o):
+				key = optionName(o)
+			case !ok:
+				continue
+			}
+			flags = slices.DeleteFunc(flags, func(f keyedFlag) bool { return f.key == key })
+			flags = append(flags, keyedFlag{UserFlag: UserFlag{Flag: o, Source: s.Name}, key: key})
+		}
+	}
+
+	result := make([]UserFlag, 0, len(flags))
+	for _, f := range flags {
+		result = append(result, f.UserFlag)
+	}
+	return result, nil
+}
+
+// applyOrder returns the position at which the JVM applies the options of a source, see optionSources.
+func applyOrder(source string) int {
+	order := slices.Clone(jvm.OptionVariables)
+	order = slices.Insert(order, slices.Index(order, jvm.UnderscoreJavaOptions), jvm.CommandLine)
+	return slices.Index(order, source)
+}
+
+// outputVariable returns the environment variable configured in $BPL_JVM_OUTPUT_VARIABLE that receives the
+// calculated options, $JAVA_TOOL_OPTIONS by default.
+func outputVariable() (string, error) {
+	s, ok := os.LookupEnv("BPL_JVM_OUTPUT_VARIABLE")
+	if !ok || s == "" {
+		return jvm.JavaToolOptions, nil
+	}
+	if !slices.Contains(jvm.OptionVariables, s) {
+		return "", fmt.Errorf("unable to use $BPL_JVM_OUTPUT_VARIABLE=%s, expected one of %s",
+			s, strings.Join(jvm.OptionVariables, ", "))
+	}
+	return s, nil
+}
+
+// joinOptions joins the non-empty options with spaces.
+func joinOptions(opts ...string) string {
+	var values []string
+	for _, o := range opts {
+		if o = strings.TrimSpace(o); o != "" {
+			values = append(values, o)
+		}
+	}
+	return strings.Join(values, " ")
+}
@@ -0,0 +1,226 @@
+package calculator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/jvm"
+	"github.com/patbaumgartner/memory-calculator/internal/parser"
+)
+
+// setOptionSources sets the environment variables of the sources of JVM options and unsets them at the end of the
+// test.
+func setOptionSources(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	for _, k := range append([]string{"BPL_JVM_COMMAND_LINE", "BPL_JVM_OUTPUT_VARIABLE", "BPL_JVM_MERGE_POLICY"},
+		jvm.OptionVariables...) {
+		_ = os.Unsetenv(k)
+	}
+	for k, v := range env {
+		_ = os.Setenv(k, v)
+	}
+	t.Cleanup(func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+	})
+}
+
+func TestUserOptions(t *testing.T) {
+	setOptionSources(t, map[string]string{
+		"JAVA_TOOL_OPTIONS":    "-Xmx1G -Xss1M",
+		"JDK_JAVA_OPTIONS":     "  ",
+		"JAVA_OPTS":            "-Xmx2G",
+		"BPL_JVM_COMMAND_LINE": "java -XX:MaxMetaspaceSize=128M -jar app.jar -Xmx4G",
+		"_JAVA_OPTIONS":        "-Xss512K",
+	})
+
+	sources, opts, err := userOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []jvm.OptionSource{
+		{Name: "JAVA_TOOL_OPTIONS", Options: "-Xmx1G -Xss1M"},
+		{Name: "JAVA_OPTS", Options: "-Xmx2G"},
+		{Name: "command line", Options: "-XX:MaxMetaspaceSize=128M"},
+		{Name: "_JAVA_OPTIONS", Options: "-Xss512K"},
+	}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Expected sources %+v, got %+v", expected, sources)
+	}
+	if e := "-Xmx1G -Xss1M -Xmx2G -XX:MaxMetaspaceSize=128M -Xss512K"; opts != e {
+		t.Errorf("Expected options %q, got %q", e, opts)
+	}
+
+	flags, err := userFlags(sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFlags := []UserFlag{
+		{Flag: "-Xmx2G", Source: "JAVA_OPTS"},
+		{Flag: "-XX:MaxMetaspaceSize=128M", Source: "command line"},
+		{Flag: "-Xss512K", Source: "_JAVA_OPTIONS"},
+	}
+	if !reflect.DeepEqual(flags, expectedFlags) {
+		t.Errorf("Expected flags %+v, got %+v", expectedFlags, flags)
+	}
+}
+
+func TestUserFlagsGroupsSpellings(t *testing.T) {
+	sources := []jvm.OptionSource{
+		{Name: "JAVA_TOOL_OPTIONS", Options: "-Xmx512m -XX:MaxHeapSize=600m -XX:MaxRAMPercentage=75"},
+		{Name: "JAVA_OPTS", Options: "-XX:ThreadStackSize=512 -Xss1M"},
+	}
+
+	flags, err := userFlags(sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []UserFlag{
+		{Flag: "-XX:MaxHeapSize=600m", Source: "JAVA_TOOL_OPTIONS"},
+		{Flag: "-Xss1M", Source: "JAVA_OPTS"},
+	}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("Expected flags %+v, got %+v", expected, flags)
+	}
+}
+
+func TestOutputVariable(t *testing.T) {
+	setOptionSources(t, nil)
+	if v, err := outputVariable(); err != nil || v != "JAVA_TOOL_OPTIONS" {
+		t.Errorf("Expected JAVA_TOOL_OPTIONS by default, got %q, %v", v, err)
+	}
+
+	setOptionSources(t, map[string]string{"BPL_JVM_OUTPUT_VARIABLE": "JDK_JAVA_OPTIONS"})
+	if v, err := outputVariable(); err != nil || v != "JDK_JAVA_OPTIONS" {
+		t.Errorf("Expected JDK_JAVA_OPTIONS, got %q, %v", v, err)
+	}
+
+	setOptionSources(t, map[string]string{"BPL_JVM_OUTPUT_VARIABLE": "CATALINA_OPTS"})
+	if _, err := outputVariable(); err == nil {
+		t.Error("Expected error for an unsupported output variable")
+	}
+}
+
+func TestCalculateOutputVariable(t *testing.T) {
+	setOptionSources(t, map[string]string{
+		"BPI_APPLICATION_PATH":       t.TempDir(),
+		"BPL_JVM_TOTAL_MEMORY":       "2G",
+		"BPL_JVM_LOADED_CLASS_COUNT": "5000",
+		"BPL_JVM_OUTPUT_VARIABLE":    "JAVA_OPTS",
+		"JAVA_OPTS":                  "-Dspring.profiles.active=prod",
+		"JAVA_TOOL_OPTIONS":          "-Xmx768M",
+		"_JAVA_OPTIONS":              "-Xmx1G",
+	})
+
+	result, err := Create(true).Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, exists := result.Properties["JAVA_TOOL_OPTIONS"]; exists {
+		t.Error("Expected no JAVA_TOOL_OPTIONS in the result")
+	}
+	opts := result.Properties["JAVA_OPTS"]
+	if !strings.HasPrefix(opts, "-Dspring.profiles.active=prod ") {
+		t.Errorf("Expected the options of $JAVA_OPTS to be kept, got %q", opts)
+	}
+	if strings.Contains(opts, "-Xmx") {
+		t.Errorf("Expected no -Xmx, as the user configured it, got %q", opts)
+	}
+	if h := result.Regions.Heap; h == nil || h.Value != 1<<30 {
+		t.Errorf("Expected the heap of $_JAVA_OPTIONS, got %v", h)
+	}
+	if e := []UserFlag{{Flag: "-Xmx1G", Source: "_JAVA_OPTIONS"}}; !reflect.DeepEqual(result.UserFlags, e) {
+		t.Errorf("Expected user flags %+v, got %+v", e, result.UserFlags)
+	}
+}
+
+func TestCalculateArgFile(t *testing.T) {
+	argFile := filepath.Join(t.TempDir(), "jvm.options")
+	if err := os.WriteFile(argFile, []byte("# Memory\n-Xmx1G\n-Xss512K\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	setOptionSources(t, map[string]string{
+		"BPI_APPLICATION_PATH":       t.TempDir(),
+		"BPL_JVM_TOTAL_MEMORY":       "2G",
+		"BPL_JVM_LOADED_CLASS_COUNT": "5000",
+		"JDK_JAVA_OPTIONS":           "@" + argFile,
+	})
+
+	result, err := Create(true).Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h := result.Regions.Heap; h == nil || h.Value != 1<<30 || h.Provenance != calc.UserConfigured {
+		t.Errorf("Expected the user configured heap of the argument file, got %+v", h)
+	}
+	if s := result.Regions.Stack; s.Value != 512*calc.Kibi || s.Provenance != calc.UserConfigured {
+		t.Errorf("Expected the user configured stack of the argument file, got %+v", s)
+	}
+	if opts := result.Properties["JAVA_TOOL_OPTIONS"]; strings.Contains(opts, "-Xmx") || strings.Contains(opts, "-Xss") {
+		t.Errorf("Expected no calculated heap or stack, got %q", opts)
+	}
+	e := []UserFlag{{Flag: "-Xmx1G", Source: "JDK_JAVA_OPTIONS"}, {Flag: "-Xss512K", Source: "JDK_JAVA_OPTIONS"}}
+	if !reflect.DeepEqual(result.UserFlags, e) {
+		t.Errorf("Expected user flags %+v, got %+v", e, result.UserFlags)
+	}
+}
+
+func TestCalculateArgFileInJavaToolOptions(t *testing.T) {
+	argFile := filepath.Join(t.TempDir(), "jvm.options")
+	if err := os.WriteFile(argFile, []byte("-Xmx1G\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	setOptionSources(t, map[string]string{
+		"BPI_APPLICATION_PATH":       t.TempDir(),
+		"BPL_JVM_TOTAL_MEMORY":       "2G",
+		"BPL_JVM_LOADED_CLASS_COUNT": "5000",
+		"JAVA_TOOL_OPTIONS":          "@" + argFile,
+	})
+
+	result, err := Create(true).Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h := result.Regions.Heap; h == nil || h.Provenance == calc.UserConfigured {
+		t.Errorf("Expected a calculated heap, got %+v", h)
+	}
+	if len(result.UserFlags) != 0 {
+		t.Errorf("Expected no user flags, got %+v", result.UserFlags)
+	}
+}
+
+func TestCalculateRoundTripsQuotedOptions(t *testing.T) {
+	setOptionSources(t, map[string]string{
+		"BPI_APPLICATION_PATH":       t.TempDir(),
+		"BPL_JVM_TOTAL_MEMORY":       "2G",
+		"BPL_JVM_LOADED_CLASS_COUNT": "5000",
+		"JAVA_TOOL_OPTIONS":          `-Dapp.name="My App" -Dpath=C:\app -D'key with space'=value`,
+		"BPL_JVM_EXTRA_OPTIONS":      `-Dgreeting='She said "Hello"'`,
+	})
+
+	result, err := Create(true).Calculate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := parser.SplitFlags(result.Properties["JAVA_TOOL_OPTIONS"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"-Dapp.name=My App", `-Dpath=C:\app`, "-Dkey with space=value", `-Dgreeting=She said "Hello"`,
+	}
+	if len(p) < len(expected) || !reflect.DeepEqual(p[:len(expected)], expected) {
+		t.Errorf("Expected options to start with %q, got %q", expected, p)
+	}
+}
@@ -0,0 +1,108 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/count"
+	"github.com/patbaumgartner/memory-calculator/internal/parser"
+)
+
+// parseSharedArchiveConfig detects the CDS archives the JVM maps and records the classes loaded from them, which are
+// not allocated in metaspace, and the memory they map.
+func (m MemoryCalculator) parseSharedArchiveConfig(c *calc.Calculator, opts string) error {
+	archives, err := m.sharedArchives(opts)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range archives {
+		if a.Classlist != "" {
+			m.Logger.Debugf("CDS archive %s maps %s with %d classes listed in %s",
+				a.Path, calc.Size{Value: a.Size}, a.Classes, a.Classlist)
+		} else {
+			m.Logger.Debugf("CDS archive %s maps %s with an estimated %d classes",
+				a.Path, calc.Size{Value: a.Size}, a.Classes)
+		}
+		c.SharedClassCount += a.Classes
+		c.SharedArchiveSize.Value += a.Size
+	}
+	c.SharedClassCount = min(c.SharedClassCount, c.LoadedClassCount)
+	return nil
+}
+
+// sharedArchives returns the CDS archives the JVM maps with the given options: the archives of
+// -XX:SharedArchiveFile and the default archive of the Java runtime at $JAVA_HOME unless a static archive replaces it.
+// No archive is mapped if sharing is disabled with -Xshare:off. Relative archive paths are resolved like agent paths,
+// see resolvePath.
+func (m MemoryCalculator) sharedArchives(opts string) ([]count.SharedArchive, error) {
+	p, err := parser.SplitFlags(opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse JVM options\n%w", err)
+	}
+
+	share, compressedOops := true, true
+	var files []string
+	var classlist string
+	for _, o := range p {
+		switch {
+		case o == "-Xshare:off", o == "-XX:-UseSharedSpaces":
+			share = false
+		case o == "-Xshare:on", o == "-Xshare:auto", o == "-XX:+UseSharedSpaces":
+			share = true
+		case o == "-XX:-UseCompressedOops":
+			compressedOops = false
+		case o == "-XX:+UseCompressedOops":
+			compressedOops = true
+		case strings.HasPrefix(o, "-XX:SharedArchiveFile="):
+			files = filepath.SplitList(strings.TrimPrefix(o, "-XX:SharedArchiveFile="))
+		case strings.HasPrefix(o, "-XX:SharedClassListFile="):
+			classlist = strings.TrimPrefix(o, "-XX:SharedClassListFile=")
+		}
+	}
+	if !share {
+		return nil, nil
+	}
+
+	var archives []count.SharedArchive
+	static := false
+	for _, f := range files {
+		f = resolvePath(f)
+		classlists := []string{strings.TrimSuffix(f, filepath.Ext(f)) + ".classlist"}
+		if classlist != "" {
+			classlists = append([]string{classlist}, classlists...)
+		}
+
+		a, err := count.ReadSharedArchive(f, classlists...)
+		if err != nil {
+			m.Logger.Infof("WARNING: Ignoring CDS archive, metaspace may not be sized correctly: %v", err)
+			continue
+		}
+		static = static || !a.Dynamic
+		archives = append(archives, a)
+	}
+
+	javaHome := os.Getenv("JAVA_HOME")
+	if static || javaHome == "" {
+		return archives, nil
+	}
+
+	name := count.DefaultCDSArchive
+	if !compressedOops {
+		name = count.DefaultCDSArchiveNoCoops
+	}
+	a, err := count.ReadSharedArchive(
+		filepath.Join(javaHome, "lib", "server", name), filepath.Join(javaHome, "lib", "classlist"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return archives, nil
+	} else if err != nil {
+		m.Logger.Infof("WARNING: Ignoring default CDS archive, metaspace may not be sized correctly: %v", err)
+		return archives, nil
+	}
+	return append([]count.SharedArchive{a}, archives...), nil
+}
@@ -0,0 +1,118 @@
+package calculator
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/count"
+)
+
+// writeCDSArchive writes a CDS archive of the given size with the given magic.
+func writeCDSArchive(t *testing.T, path string, magic uint32, size int) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, size)
+	binary.LittleEndian.PutUint32(b, magic)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSharedArchives(t *testing.T) {
+	mc := Create(true)
+
+	javaHome, appPath := t.TempDir(), t.TempDir()
+	writeCDSArchive(t, filepath.Join(javaHome, "lib", "server", count.DefaultCDSArchive), count.CDSMagic, 1000)
+	writeCDSArchive(t, filepath.Join(javaHome, "lib", "server", count.DefaultCDSArchiveNoCoops), count.CDSMagic, 2000)
+	if err := os.WriteFile(filepath.Join(javaHome, "lib", "classlist"), []byte("java/lang/Object\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = os.Setenv("JAVA_HOME", javaHome)
+	_ = os.Setenv("BPI_APPLICATION_PATH", appPath)
+	defer func() {
+		_ = os.Unsetenv("JAVA_HOME")
+		_ = os.Unsetenv("BPI_APPLICATION_PATH")
+	}()
+
+	sizes := func(opts string) []int64 {
+		t.Helper()
+		archives, err := mc.sharedArchives(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s []int64
+		for _, a := range archives {
+			s = append(s, a.Size)
+		}
+		return s
+	}
+
+	if s := sizes(""); len(s) != 1 || s[0] != 1000 {
+		t.Errorf("Expected the default archive, got %v", s)
+	}
+	if s := sizes("-XX:-UseCompressedOops"); len(s) != 1 || s[0] != 2000 {
+		t.Errorf("Expected the default archive without compressed oops, got %v", s)
+	}
+	if s := sizes("-Xshare:off"); len(s) != 0 {
+		t.Errorf("Expected no archive with sharing disabled, got %v", s)
+	}
+
+	// A Spring Boot training run is only mapped if the options reference it
+	writeCDSArchive(t, filepath.Join(appPath, "application.jsa"), count.CDSDynamicMagic, 3000)
+	if s := sizes(""); len(s) != 1 || s[0] != 1000 {
+		t.Errorf("Expected the default archive only, got %v", s)
+	}
+	if s := sizes("-XX:SharedArchiveFile=application.jsa"); len(s) != 2 || s[0] != 1000 || s[1] != 3000 {
+		t.Errorf("Expected the default and the Spring Boot archive, got %v", s)
+	}
+
+	// A static archive replaces the default archive, relative paths are resolved against the working directory first
+	writeCDSArchive(t, filepath.Join(appPath, "app.jsa"), count.CDSMagic, 4000)
+	if s := sizes("-XX:SharedArchiveFile=app.jsa"); len(s) != 1 || s[0] != 4000 {
+		t.Errorf("Expected the static application archive, got %v", s)
+	}
+	workDir := t.TempDir()
+	writeCDSArchive(t, filepath.Join(workDir, "app.jsa"), count.CDSMagic, 5000)
+	t.Chdir(workDir)
+	if s := sizes("-XX:SharedArchiveFile=app.jsa"); len(s) != 1 || s[0] != 5000 {
+		t.Errorf("Expected the archive in the working directory, got %v", s)
+	}
+
+	// Archives that cannot be read are ignored
+	if s := sizes("-XX:SharedArchiveFile=missing.jsa"); len(s) != 1 || s[0] != 1000 {
+		t.Errorf("Expected the default archive only, got %v", s)
+	}
+}
+
+func TestParseSharedArchiveConfig(t *testing.T) {
+	mc := Create(true)
+
+	javaHome := t.TempDir()
+	archive := filepath.Join(javaHome, "lib", "server", count.DefaultCDSArchive)
+	writeCDSArchive(t, archive, count.CDSMagic, 8*count.CDSBytesPerClass)
+
+	_ = os.Setenv("JAVA_HOME", javaHome)
+	_ = os.Setenv("BPI_APPLICATION_PATH", t.TempDir())
+	defer func() {
+		_ = os.Unsetenv("JAVA_HOME")
+		_ = os.Unsetenv("BPI_APPLICATION_PATH")
+	}()
+
+	c := calc.Calculator{LoadedClassCount: 5}
+	if err := mc.parseSharedArchiveConfig(&c, ""); err != nil {
+		t.Fatal(err)
+	}
+	if c.SharedClassCount != 5 {
+		t.Errorf("Expected shared classes limited to the 5 loaded classes, got %d", c.SharedClassCount)
+	}
+	if c.SharedArchiveSize.Value != 8*count.CDSBytesPerClass {
+		t.Errorf("Expected archive size %d, got %d", 8*count.CDSBytesPerClass, c.SharedArchiveSize.Value)
+	}
+}
@@ -0,0 +1,75 @@
+package calculator
+
+import (
+	"fmt"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+)
+
+// MaxSweepSizes is the maximum number of total memory sizes a sweep calculates.
+const MaxSweepSizes = 1_000
+
+// SweepRow is the outcome of the calculation for one total memory size of a sweep.
+type SweepRow struct {
+	// TotalMemory is the total memory the calculation was performed for.
+	TotalMemory calc.Size
+
+	// Regions holds the calculated memory regions. It is empty if Err is set.
+	Regions calc.MemoryRegions
+
+	// Err is the error of a failed calculation.
+	Err error
+
+	// LowHeap reports that the calculated heap is below the sweep's minimum heap.
+	LowHeap bool
+}
+
+// SweepSizes returns the total memory sizes from from to to (inclusive) in steps of step.
+func SweepSizes(from calc.Size, to calc.Size, step calc.Size) ([]calc.Size, error) {
+	if from.Value <= 0 || step.Value <= 0 {
+		return nil, fmt.Errorf("sweep start %s and step %s must be positive", from, step)
+	}
+	if to.Value < from.Value {
+		return nil, fmt.Errorf("sweep end %s must not be smaller than start %s", to, from)
+	}
+	if n := (to.Value-from.Value)/step.Value + 1; n > MaxSweepSizes {
+		return nil, fmt.Errorf("sweep from %s to %s in steps of %s has %d sizes, at most %d are allowed",
+			from, to, step, n, MaxSweepSizes)
+	}
+
+	var sizes []calc.Size
+	for v := from.Value; v <= to.Value; v += step.Value {
+		sizes = append(sizes, calc.Size{Value: v})
+	}
+	return sizes, nil
+}
+
+// Sweep runs the calculation with the same inputs for each of the given total memory sizes. Failed calculations
+// and heaps below minHeap are marked in the rows rather than returned as errors.
+func (m MemoryCalculator) Sweep(sizes []calc.Size, minHeap calc.Size) ([]SweepRow, error) {
+	if len(sizes) > MaxSweepSizes {
+		return nil, fmt.Errorf("sweep has %d sizes, at most %d are allowed", len(sizes), MaxSweepSizes)
+	}
+
+	setup, err := m.Configure()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]SweepRow, 0, len(sizes))
+	for _, s := range sizes {
+		c := setup.Calculator
+		c.TotalMemory = calc.Size{Value: s.Value}
+
+		row := SweepRow{TotalMemory: c.TotalMemory}
+		if r, err := c.Calculate(setup.Options); err != nil {
+			row.Err = err
+		} else {
+			row.Regions = r
+			row.LowHeap = r.Heap.Value < minHeap.Value
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,61 @@
+package calculator
+
+import (
+	"os"
+	"testing"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+)
+
+func TestSweepSizes(t *testing.T) {
+	sizes, err := SweepSizes(
+		calc.Size{Value: 256 * calc.Mebi}, calc.Size{Value: calc.Gibi}, calc.Size{Value: 256 * calc.Mebi})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sizes) != 4 || sizes[0].Value != 256*calc.Mebi || sizes[3].Value != calc.Gibi {
+		t.Errorf("Expected 256M to 1G in 4 steps, got %v", sizes)
+	}
+
+	invalid := [][3]int64{
+		{0, calc.Gibi, calc.Mebi},
+		{calc.Gibi, calc.Gibi, 0},
+		{calc.Gibi, 512 * calc.Mebi, calc.Mebi},
+		{calc.Mebi, 8 * calc.Gibi, calc.Mebi},
+	}
+	for _, i := range invalid {
+		if _, err := SweepSizes(calc.Size{Value: i[0]}, calc.Size{Value: i[1]}, calc.Size{Value: i[2]}); err == nil {
+			t.Errorf("Expected error for sweep %v", i)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	mc := Create(true)
+
+	_ = os.Setenv("BPL_JVM_LOADED_CLASS_COUNT", "10000")
+	defer func() { _ = os.Unsetenv("BPL_JVM_LOADED_CLASS_COUNT") }()
+
+	sizes := []calc.Size{{Value: 256 * calc.Mebi}, {Value: 640 * calc.Mebi}, {Value: 2 * calc.Gibi}}
+	rows, err := mc.Sweep(sizes, calc.Size{Value: 256 * calc.Mebi})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(rows))
+	}
+	if rows[0].Err == nil {
+		t.Error("Expected 256M to fail with default thread count and code cache")
+	}
+	if rows[1].Err != nil || !rows[1].LowHeap {
+		t.Errorf("Expected 640M to succeed with a low heap, got %+v", rows[1])
+	}
+	if rows[2].Err != nil || rows[2].LowHeap {
+		t.Errorf("Expected 2G to succeed, got %+v", rows[2])
+	}
+	if rows[2].Regions.Heap.Value <= rows[1].Regions.Heap.Value {
+		t.Error("Expected heap to grow with total memory")
+	}
+}
@@ -3,19 +3,68 @@ package config
 
 import (
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/count"
+	"github.com/patbaumgartner/memory-calculator/internal/jvm"
+	"github.com/patbaumgartner/memory-calculator/internal/parser"
 	"github.com/patbaumgartner/memory-calculator/pkg/errors"
 )
 
 // Config holds all configuration parameters for the memory calculator.
 type Config struct {
 	// Memory configuration
-	TotalMemory      string
-	ThreadCount      string
-	LoadedClassCount string
-	HeadRoom         string
-	Path             string
+	TotalMemory        string
+	ThreadCount        string
+	VirtualThreadCount string
+	LoadedClassCount   string
+	ClassList          string
+	ClassListMargin    string
+	HeadRoom           string
+	Path               string
+	NestedJarDepth     string
+	ClassCountMode     string
+	ScanWorkers        string
+	CachePath          string
+	CacheHash          bool
+	NoCache            bool
+	LockFile           string
+	TrustLock          bool
+
+	// Java runtime configuration
+	JavaHome           string
+	SegmentedCodeCache bool
+	DirectMemoryRules  string
+	NativeAgentRules   string
+	Degrade            bool
+
+	// Profile configuration. The thread count, class load factor, code cache and direct memory of the profile are
+	// defaults that degrade mode, framework detection and detected libraries may still change, not user
+	// configuration.
+	Profile                 string
+	ProfileThreadCount      string
+	ProfileClassLoadFactor  string
+	ProfileCodeCacheSize    string
+	ProfileDirectMemorySize string
+	ConfigFile              string
+	ClassLoadFactor         string
+	CodeCacheSize           string
+	DirectMemorySize        string
+	InitialHeap             string
+	JVMOptions              string
+
+	// JVM option sources configuration
+	CommandLine    string
+	OutputVariable string
+	MergePolicy    string
+
+	// Metaspace model configuration
+	ClassSize       string
+	ClassOverhead   string
+	LanguageWeights string
 
 	// Output configuration
 	Quiet   bool
@@ -31,21 +80,60 @@ type Config struct {
 // Load returns a configuration loaded from environment variables.
 func Load() *Config {
 	return &Config{
-		ThreadCount:      getEnvOrDefault("BPL_JVM_THREAD_COUNT", "250"),
-		LoadedClassCount: os.Getenv("BPL_JVM_LOADED_CLASS_COUNT"), // No default - should be calculated
-		HeadRoom:         getEnvOrDefault("BPL_JVM_HEAD_ROOM", "0"),
-		Path:             getEnvOrDefault("BPI_APPLICATION_PATH", "/app"),
-		BuildVersion:     "dev",
-		BuildTime:        "unknown",
-		CommitHash:       "unknown",
+		ThreadCount:        os.Getenv("BPL_JVM_THREAD_COUNT"),         // No default - the profile or 250 threads apply
+		VirtualThreadCount: os.Getenv("BPL_JVM_VIRTUAL_THREAD_COUNT"), // No default - no virtual threads
+		LoadedClassCount:   os.Getenv("BPL_JVM_LOADED_CLASS_COUNT"),   // No default - should be calculated
+		ClassList:          os.Getenv("BPL_JVM_CLASS_LIST"),           // No default - classes are counted
+		ClassListMargin:    os.Getenv("BPL_JVM_CLASS_LIST_MARGIN"),    // No default - 10% applies
+		HeadRoom:           getEnvOrDefault("BPL_JVM_HEAD_ROOM", "0"),
+		Path:               getEnvOrDefault("BPI_APPLICATION_PATH", "/app"),
+		NestedJarDepth:     os.Getenv("BPI_NESTED_JAR_DEPTH"),  // No default - count.DefaultNestedJarDepth applies
+		ClassCountMode:     os.Getenv("BPI_CLASS_COUNT_MODE"),  // No default - count.RawMode applies
+		ScanWorkers:        os.Getenv("BPI_SCAN_WORKERS"),      // No default - runtime.GOMAXPROCS applies
+		CachePath:          os.Getenv("BPI_CLASS_COUNT_CACHE"), // No default - count.DefaultCachePath applies
+		CacheHash:          getEnvBool("BPI_CLASS_COUNT_CACHE_HASH"),
+		NoCache:            getEnvBool("BPI_CLASS_COUNT_NO_CACHE"),
+		LockFile:           os.Getenv("BPI_CLASS_COUNT_LOCK"), // No default - <path>/.memory-calculator.json applies
+		TrustLock:          getEnvBool("BPI_CLASS_COUNT_LOCK_TRUST"),
+		JavaHome:           os.Getenv("JAVA_HOME"), // No default - version detection is skipped
+		SegmentedCodeCache: getEnvBool("BPL_JVM_SEGMENTED_CODE_CACHE"),
+		DirectMemoryRules:  os.Getenv("BPL_JVM_DIRECT_MEMORY_RULES"), // No default - built-in rules apply
+		NativeAgentRules:   os.Getenv("BPL_JVM_NATIVE_AGENT_RULES"),  // No default - built-in rules apply
+		Degrade:            getEnvBool("BPL_JVM_DEGRADE"),
+		Profile:            os.Getenv("BPL_JVM_PROFILE"),
+		ConfigFile:         os.Getenv("BPL_JVM_CONFIG_FILE"),
+		ClassLoadFactor:    os.Getenv("BPL_JVM_CLASS_LOAD_FACTOR"),
+		CodeCacheSize:      os.Getenv("BPL_JVM_CODE_CACHE_SIZE"),
+		DirectMemorySize:   os.Getenv("BPL_JVM_DIRECT_MEMORY_SIZE"),
+		InitialHeap:        os.Getenv("BPL_JVM_INITIAL_HEAP"),
+		JVMOptions:         os.Getenv("BPL_JVM_EXTRA_OPTIONS"),
+		CommandLine:        os.Getenv("BPL_JVM_COMMAND_LINE"),
+		OutputVariable:     os.Getenv("BPL_JVM_OUTPUT_VARIABLE"), // No default - JAVA_TOOL_OPTIONS applies
+		MergePolicy:        os.Getenv("BPL_JVM_MERGE_POLICY"),    // No default - jvm.RespectUser applies
+		ClassSize:          os.Getenv("BPL_JVM_CLASS_SIZE"),
+		ClassOverhead:      os.Getenv("BPL_JVM_CLASS_OVERHEAD"),
+		LanguageWeights:    os.Getenv("BPL_JVM_LANGUAGE_WEIGHTS"),
+		BuildVersion:       "dev",
+		BuildTime:          "unknown",
+		CommitHash:         "unknown",
 	}
 }
 
 // Validate checks if the configuration is valid.
 func (c *Config) Validate() error {
-	// Validate thread count
-	if threadCount, err := strconv.Atoi(c.ThreadCount); err != nil || threadCount < 1 {
-		return errors.NewConfigurationError("thread-count", c.ThreadCount, "must be a positive integer")
+	// Validate thread count (only if provided)
+	if c.ThreadCount != "" {
+		if threadCount, err := strconv.Atoi(c.ThreadCount); err != nil || threadCount < 1 {
+			return errors.NewConfigurationError("thread-count", c.ThreadCount, "must be a positive integer")
+		}
+	}
+
+	// Validate virtual thread count (only if provided)
+	if c.VirtualThreadCount != "" {
+		if virtualThreads, err := strconv.Atoi(c.VirtualThreadCount); err != nil || virtualThreads < 0 {
+			return errors.NewConfigurationError(
+				"virtual-thread-count", c.VirtualThreadCount, "must be a non-negative integer")
+		}
 	}
 
 	// Validate loaded class count (only if provided)
@@ -55,9 +143,70 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate class list margin (only if provided)
+	if c.ClassListMargin != "" {
+		if margin, err := strconv.Atoi(c.ClassListMargin); err != nil || margin < 0 {
+			return errors.NewConfigurationError(
+				"class-list-margin", c.ClassListMargin, "must be a non-negative percentage")
+		}
+	}
+
+	// Validate nested jar depth (only if provided)
+	if c.NestedJarDepth != "" {
+		if depth, err := strconv.Atoi(c.NestedJarDepth); err != nil || depth < 1 {
+			return errors.NewConfigurationError("nested-jar-depth", c.NestedJarDepth, "must be a positive integer")
+		}
+	}
+
+	// Validate scan workers (only if provided)
+	if c.ScanWorkers != "" {
+		if workers, err := strconv.Atoi(c.ScanWorkers); err != nil || workers < 1 {
+			return errors.NewConfigurationError("scan-workers", c.ScanWorkers, "must be a positive integer")
+		}
+	}
+
+	// Validate class count mode (only if provided)
+	if c.ClassCountMode != "" && c.ClassCountMode != count.RawMode && c.ClassCountMode != count.UniqueMode {
+		return errors.NewConfigurationError("class-count-mode", c.ClassCountMode, "must be raw or unique")
+	}
+
 	// Validate head room
-	if headRoom, err := strconv.Atoi(c.HeadRoom); err != nil || headRoom < 0 || headRoom > 100 {
-		return errors.NewConfigurationError("head-room", c.HeadRoom, "must be an integer between 0 and 100")
+	if _, err := calc.ParseHeadRoom(c.HeadRoom); err != nil {
+		return errors.NewConfigurationError(
+			"head-room", c.HeadRoom, "must be a percentage between 0 and 100 or a size, optionally with min and max bounds")
+	}
+
+	// Validate direct memory rule overrides (only if provided)
+	if c.DirectMemoryRules != "" {
+		if _, err := calc.ParseDirectMemoryRules(c.DirectMemoryRules); err != nil {
+			return errors.NewConfigurationError(
+				"direct-memory-rules", c.DirectMemoryRules, "must be a list of <library>=<size> rules separated by ';'")
+		}
+	}
+
+	// Validate native agent rule overrides (only if provided)
+	if c.NativeAgentRules != "" {
+		if _, err := calc.ParseNativeAgentRules(c.NativeAgentRules); err != nil {
+			return errors.NewConfigurationError(
+				"native-agent-rules", c.NativeAgentRules, "must be a list of <agent>=<size> rules separated by ';'")
+		}
+	}
+
+	// Validate output variable (only if provided)
+	if c.OutputVariable != "" && !slices.Contains(jvm.OptionVariables, c.OutputVariable) {
+		return errors.NewConfigurationError(
+			"output-variable", c.OutputVariable, "must be one of "+strings.Join(jvm.OptionVariables, ", "))
+	}
+
+	// Validate merge policy (only if provided)
+	if c.MergePolicy != "" && !slices.Contains(jvm.MergePolicies, c.MergePolicy) {
+		return errors.NewConfigurationError(
+			"merge-policy", c.MergePolicy, "must be one of "+strings.Join(jvm.MergePolicies, ", "))
+	}
+
+	// Validate profile settings (only if provided)
+	if err := c.validateProfileSettings(); err != nil {
+		return err
 	}
 
 	// Validate path (basic validation - path should not be empty)
@@ -68,16 +217,150 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// validateProfileSettings validates the settings a profile can provide.
+func (c *Config) validateProfileSettings() error {
+	if c.ClassLoadFactor != "" {
+		if f, err := strconv.ParseFloat(c.ClassLoadFactor, 64); err != nil || f <= 0 || f > 1 {
+			return errors.NewConfigurationError(
+				"class-load-factor", c.ClassLoadFactor, "must be a number greater than 0 and at most 1")
+		}
+	}
+	if c.ProfileClassLoadFactor != "" {
+		if f, err := strconv.ParseFloat(c.ProfileClassLoadFactor, 64); err != nil || f <= 0 || f > 1 {
+			return errors.NewConfigurationError(
+				"profile", c.Profile, "class load factor must be a number greater than 0 and at most 1")
+		}
+	}
+
+	for name, value := range map[string]string{
+		"code-cache-size":    c.CodeCacheSize,
+		"direct-memory-size": c.DirectMemorySize,
+		"class-size":         c.ClassSize,
+		"class-overhead":     c.ClassOverhead,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := calc.ParseSize(value); err != nil {
+			return errors.NewConfigurationError(name, value, "must be a memory size such as 128M")
+		}
+	}
+	for name, value := range map[string]string{
+		"code cache size":    c.ProfileCodeCacheSize,
+		"direct memory size": c.ProfileDirectMemorySize,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := calc.ParseSize(value); err != nil {
+			return errors.NewConfigurationError("profile", c.Profile, name+" must be a memory size such as 128M")
+		}
+	}
+
+	if c.LanguageWeights != "" {
+		if _, err := count.ParseLanguageWeights(c.LanguageWeights); err != nil {
+			return errors.NewConfigurationError("language-weights", c.LanguageWeights, err.Error())
+		}
+	}
+
+	if c.InitialHeap != "" {
+		if _, err := calc.ParseInitialHeapPolicy(c.InitialHeap); err != nil {
+			return errors.NewConfigurationError(
+				"initial-heap", c.InitialHeap, "must be 'max' or a share of the heap such as 25%")
+		}
+	}
+
+	if c.JVMOptions != "" {
+		if _, err := parser.ParseFlags(c.JVMOptions); err != nil {
+			return errors.NewConfigurationError("jvm-options", c.JVMOptions, "must be valid JVM options")
+		}
+	}
+
+	return nil
+}
+
 // SetEnvironmentVariables sets buildpack environment variables from the config.
 func (c *Config) SetEnvironmentVariables() {
-	_ = os.Setenv("BPL_JVM_THREAD_COUNT", c.ThreadCount)
+	if c.ThreadCount != "" {
+		_ = os.Setenv("BPL_JVM_THREAD_COUNT", c.ThreadCount)
+	}
+	if c.VirtualThreadCount != "" {
+		_ = os.Setenv("BPL_JVM_VIRTUAL_THREAD_COUNT", c.VirtualThreadCount)
+	}
 	if c.LoadedClassCount != "" {
 		_ = os.Setenv("BPL_JVM_LOADED_CLASS_COUNT", c.LoadedClassCount)
 	}
+	if c.ClassList != "" {
+		_ = os.Setenv("BPL_JVM_CLASS_LIST", c.ClassList)
+	}
+	if c.ClassListMargin != "" {
+		_ = os.Setenv("BPL_JVM_CLASS_LIST_MARGIN", c.ClassListMargin)
+	}
 	_ = os.Setenv("BPL_JVM_HEAD_ROOM", c.HeadRoom)
 	if c.Path != "" {
 		_ = os.Setenv("BPI_APPLICATION_PATH", c.Path)
 	}
+	if c.NestedJarDepth != "" {
+		_ = os.Setenv("BPI_NESTED_JAR_DEPTH", c.NestedJarDepth)
+	}
+	if c.ClassCountMode != "" {
+		_ = os.Setenv("BPI_CLASS_COUNT_MODE", c.ClassCountMode)
+	}
+	if c.ScanWorkers != "" {
+		_ = os.Setenv("BPI_SCAN_WORKERS", c.ScanWorkers)
+	}
+	if c.CachePath != "" {
+		_ = os.Setenv("BPI_CLASS_COUNT_CACHE", c.CachePath)
+	}
+	if c.CacheHash {
+		_ = os.Setenv("BPI_CLASS_COUNT_CACHE_HASH", "true")
+	}
+	if c.NoCache {
+		_ = os.Setenv("BPI_CLASS_COUNT_NO_CACHE", "true")
+	}
+	if c.LockFile != "" {
+		_ = os.Setenv("BPI_CLASS_COUNT_LOCK", c.LockFile)
+	}
+	if c.TrustLock {
+		_ = os.Setenv("BPI_CLASS_COUNT_LOCK_TRUST", "true")
+	}
+	if c.JavaHome != "" {
+		_ = os.Setenv("JAVA_HOME", c.JavaHome)
+	}
+	if c.SegmentedCodeCache {
+		_ = os.Setenv("BPL_JVM_SEGMENTED_CODE_CACHE", "true")
+	}
+	if c.Degrade {
+		_ = os.Setenv("BPL_JVM_DEGRADE", "true")
+	}
+	if c.DirectMemoryRules != "" {
+		_ = os.Setenv("BPL_JVM_DIRECT_MEMORY_RULES", c.DirectMemoryRules)
+	}
+	if c.NativeAgentRules != "" {
+		_ = os.Setenv("BPL_JVM_NATIVE_AGENT_RULES", c.NativeAgentRules)
+	}
+	for env, value := range map[string]string{
+		"BPL_JVM_PROFILE":                    c.Profile,
+		"BPL_JVM_PROFILE_THREAD_COUNT":       c.ProfileThreadCount,
+		"BPL_JVM_PROFILE_CLASS_LOAD_FACTOR":  c.ProfileClassLoadFactor,
+		"BPL_JVM_PROFILE_CODE_CACHE_SIZE":    c.ProfileCodeCacheSize,
+		"BPL_JVM_PROFILE_DIRECT_MEMORY_SIZE": c.ProfileDirectMemorySize,
+		"BPL_JVM_CLASS_LOAD_FACTOR":          c.ClassLoadFactor,
+		"BPL_JVM_CODE_CACHE_SIZE":            c.CodeCacheSize,
+		"BPL_JVM_DIRECT_MEMORY_SIZE":         c.DirectMemorySize,
+		"BPL_JVM_INITIAL_HEAP":               c.InitialHeap,
+		"BPL_JVM_EXTRA_OPTIONS":              c.JVMOptions,
+		"BPL_JVM_CLASS_SIZE":                 c.ClassSize,
+		"BPL_JVM_CLASS_OVERHEAD":             c.ClassOverhead,
+		"BPL_JVM_LANGUAGE_WEIGHTS":           c.LanguageWeights,
+		"BPL_JVM_COMMAND_LINE":               c.CommandLine,
+		"BPL_JVM_OUTPUT_VARIABLE":            c.OutputVariable,
+		"BPL_JVM_MERGE_POLICY":               c.MergePolicy,
+	} {
+		if value != "" {
+			_ = os.Setenv(env, value)
+		}
+	}
 }
 
 // SetTotalMemory sets the total memory environment variable if memory is specified.
@@ -87,6 +370,12 @@ func (c *Config) SetTotalMemory(totalMemory int64) {
 	}
 }
 
+// getEnvBool returns the environment variable value as a boolean, false if unset or invalid.
+func getEnvBool(key string) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	return err == nil && value
+}
+
 // getEnvOrDefault returns the environment variable value or a default value.
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -16,8 +16,8 @@ func TestLoad(t *testing.T) {
 	cfg := Load()
 
 	// Test default values
-	if cfg.ThreadCount != "250" {
-		t.Errorf("Expected thread count '250', got '%s'", cfg.ThreadCount)
+	if cfg.ThreadCount != "" {
+		t.Errorf("Expected empty thread count (profile or calculator default applies), got '%s'", cfg.ThreadCount)
 	}
 
 	if cfg.LoadedClassCount != "" {
@@ -96,6 +96,137 @@ func TestConfigValidation(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "Valid nested jar depth",
+			config: &Config{
+				ThreadCount:    "250",
+				HeadRoom:       "0",
+				Path:           "/app",
+				NestedJarDepth: "2",
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid nested jar depth - zero",
+			config: &Config{
+				ThreadCount:    "250",
+				HeadRoom:       "0",
+				Path:           "/app",
+				NestedJarDepth: "0",
+			},
+			expectError: true,
+		},
+		{
+			name: "Invalid scan workers",
+			config: &Config{
+				ThreadCount: "250",
+				HeadRoom:    "0",
+				Path:        "/app",
+				ScanWorkers: "0",
+			},
+			expectError: true,
+		},
+		{
+			name: "Valid class count mode",
+			config: &Config{
+				ThreadCount:    "250",
+				HeadRoom:       "0",
+				Path:           "/app",
+				ClassCountMode: "unique",
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid class count mode",
+			config: &Config{
+				ThreadCount:    "250",
+				HeadRoom:       "0",
+				Path:           "/app",
+				ClassCountMode: "exact",
+			},
+			expectError: true,
+		},
+		{
+			name: "Invalid native agent rules",
+			config: &Config{
+				ThreadCount:      "250",
+				HeadRoom:         "0",
+				Path:             "/app",
+				NativeAgentRules: "dynatrace",
+			},
+			expectError: true,
+		},
+		{
+			name: "Valid output variable",
+			config: &Config{
+				ThreadCount:    "250",
+				HeadRoom:       "0",
+				Path:           "/app",
+				OutputVariable: "JAVA_OPTS",
+				CommandLine:    "java -Xss512K -jar app.jar",
+			},
+			expectError: false,
+		},
+		{
+			name: "Valid merge policy",
+			config: &Config{
+				ThreadCount: "250",
+				HeadRoom:    "0",
+				Path:        "/app",
+				MergePolicy: "fail-on-conflict",
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid merge policy",
+			config: &Config{
+				ThreadCount: "250",
+				HeadRoom:    "0",
+				Path:        "/app",
+				MergePolicy: "merge",
+			},
+			expectError: true,
+		},
+		{
+			name: "Invalid output variable",
+			config: &Config{
+				ThreadCount:    "250",
+				HeadRoom:       "0",
+				Path:           "/app",
+				OutputVariable: "CATALINA_OPTS",
+			},
+			expectError: true,
+		},
+		{
+			name: "Valid class list margin",
+			config: &Config{
+				ThreadCount:     "250",
+				HeadRoom:        "0",
+				Path:            "/app",
+				ClassList:       "/tmp/classes.log",
+				ClassListMargin: "0",
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid class list margin",
+			config: &Config{
+				ThreadCount:     "250",
+				HeadRoom:        "0",
+				Path:            "/app",
+				ClassListMargin: "-5",
+			},
+			expectError: true,
+		},
+		{
+			name: "Thread count not configured",
+			config: &Config{
+				LoadedClassCount: "1000",
+				HeadRoom:         "0",
+				Path:             "/app",
+			},
+			expectError: false,
+		},
 		{
 			name: "Invalid thread count - negative",
 			config: &Config{
@@ -146,6 +277,73 @@ func TestConfigValidation(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "Valid head room - absolute size",
+			config: &Config{
+				ThreadCount: "250",
+				HeadRoom:    "300M",
+				Path:        "/app",
+			},
+			expectError: false,
+		},
+		{
+			name: "Valid head room - bounded percentage",
+			config: &Config{
+				ThreadCount: "250",
+				HeadRoom:    "10%,min=128M,max=2G",
+				Path:        "/app",
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid head room - min above max",
+			config: &Config{
+				ThreadCount: "250",
+				HeadRoom:    "10%,min=2G,max=128M",
+				Path:        "/app",
+			},
+			expectError: true,
+		},
+		{
+			name: "Valid virtual thread count",
+			config: &Config{
+				ThreadCount:        "50",
+				VirtualThreadCount: "10000",
+				HeadRoom:           "0",
+				Path:               "/app",
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid virtual thread count - negative",
+			config: &Config{
+				ThreadCount:        "250",
+				VirtualThreadCount: "-5",
+				HeadRoom:           "0",
+				Path:               "/app",
+			},
+			expectError: true,
+		},
+		{
+			name: "Valid direct memory rules",
+			config: &Config{
+				ThreadCount:       "250",
+				HeadRoom:          "0",
+				Path:              "/app",
+				DirectMemoryRules: "netty-buffer=15%,min=128M;kafka-clients=64M",
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid direct memory rules",
+			config: &Config{
+				ThreadCount:       "250",
+				HeadRoom:          "0",
+				Path:              "/app",
+				DirectMemoryRules: "netty-buffer",
+			},
+			expectError: true,
+		},
 		{
 			name: "Invalid path - empty",
 			config: &Config{
@@ -226,3 +424,35 @@ func TestSetTotalMemory(t *testing.T) {
 		t.Errorf("Expected BPL_JVM_TOTAL_MEMORY to be unset, got %s", os.Getenv("BPL_JVM_TOTAL_MEMORY"))
 	}
 }
+
+func TestValidateProfileSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"empty", Config{}, false},
+		{"valid", Config{
+			ClassLoadFactor: "0.5", CodeCacheSize: "128M", DirectMemorySize: "64M",
+			InitialHeap: "25%", JVMOptions: "-XX:+ExitOnOutOfMemoryError",
+		}, false},
+		{"class load factor too large", Config{ClassLoadFactor: "1.5"}, true},
+		{"class load factor not a number", Config{ClassLoadFactor: "half"}, true},
+		{"profile class load factor too large", Config{Profile: "custom", ProfileClassLoadFactor: "2"}, true},
+		{"invalid code cache size", Config{CodeCacheSize: "lots"}, true},
+		{"invalid direct memory size", Config{DirectMemorySize: "64Q"}, true},
+		{"invalid profile code cache size", Config{Profile: "custom", ProfileCodeCacheSize: "lots"}, true},
+		{"invalid profile direct memory size", Config{Profile: "custom", ProfileDirectMemorySize: "64Q"}, true},
+		{"invalid initial heap", Config{InitialHeap: "min"}, true},
+		{"documented metaspace defaults", Config{ClassSize: "5800", ClassOverhead: "14000000"}, false},
+		{"class size with byte unit", Config{ClassSize: "5800B"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.validateProfileSettings(); (err != nil) != tt.wantErr {
+				t.Errorf("validateProfileSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/patbaumgartner/memory-calculator/internal/parser"
+	"github.com/patbaumgartner/memory-calculator/internal/profile"
+)
+
+// File is the content of a JSON configuration file.
+//
+//	{
+//	  "metaspace": {"classSize": "8K", "classOverhead": "20M", "languageWeights": {"groovy": 3}},
+//	  "profiles": {
+//	    "orders-service": {"threadCount": 400, "headRoom": "10%,min=128M", "options": ["-XX:+ExitOnOutOfMemoryError"]}
+//	  }
+//	}
+type File struct {
+	// Metaspace holds the coefficients of the metaspace model.
+	Metaspace *Metaspace `json:"metaspace,omitempty"`
+
+	// Profiles holds user defined profiles keyed by name. They take precedence over built-in profiles.
+	Profiles map[string]profile.Profile `json:"profiles,omitempty"`
+}
+
+// Metaspace holds the coefficients of the metaspace model: metaspace = class overhead + loaded classes × class size,
+// where loaded classes = counted classes × class load factor. Empty fields leave the defaults in place.
+type Metaspace struct {
+	// ClassSize is the metaspace per loaded class, in bytes or with a unit, e.g. "5800" or "8K".
+	ClassSize string `json:"classSize,omitempty"`
+
+	// ClassOverhead is the metaspace of the JVM independent of the loaded classes, e.g. "14M".
+	ClassOverhead string `json:"classOverhead,omitempty"`
+
+	// ClassLoadFactor is the share of the counted classes that are expected to be loaded.
+	ClassLoadFactor float64 `json:"classLoadFactor,omitempty"`
+
+	// LanguageWeights holds the number of classes a counted script entry stands for, keyed by language
+	// ("groovy", "clj" or "kts").
+	LanguageWeights map[string]float64 `json:"languageWeights,omitempty"`
+}
+
+// LoadFile reads and parses the configuration file at path. Unknown keys are rejected to catch typos.
+func LoadFile(path string) (*File, error) {
+	// #nosec G304 - the configuration file is chosen by the user on purpose
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read configuration file %s\n%w", path, err)
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+
+	f := &File{}
+	if err := d.Decode(f); err != nil {
+		return nil, fmt.Errorf("unable to parse configuration file %s\n%w", path, err)
+	}
+
+	return f, nil
+}
+
+// ResolveProfile returns the selected profile, looking up user defined profiles from the configuration file first.
+// It returns nil if no profile is selected.
+func (c *Config) ResolveProfile() (*profile.Profile, error) {
+	if c.Profile == "" {
+		return nil, nil
+	}
+
+	var custom map[string]profile.Profile
+	if c.ConfigFile != "" {
+		f, err := LoadFile(c.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		custom = f.Profiles
+	}
+
+	p, err := profile.Lookup(c.Profile, custom)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ApplyProfile applies the settings of the profile that were neither given as command line flag nor as environment
+// variable. flags holds the names of the command line flags that were set explicitly. The thread count, class load
+// factor, code cache and direct memory of the profile are defaults rather than user configuration and are kept apart
+// as ProfileThreadCount, ProfileClassLoadFactor, ProfileCodeCacheSize and ProfileDirectMemorySize.
+func (c *Config) ApplyProfile(p profile.Profile, flags map[string]bool) {
+	unset := unsetFunc(flags)
+
+	if p.ThreadCount > 0 && unset("thread-count", "BPL_JVM_THREAD_COUNT") {
+		c.ProfileThreadCount = fmt.Sprint(p.ThreadCount)
+	}
+	if p.ClassLoadFactor > 0 && unset("class-load-factor", "BPL_JVM_CLASS_LOAD_FACTOR") {
+		c.ProfileClassLoadFactor = fmt.Sprint(p.ClassLoadFactor)
+	}
+	if p.CodeCacheSize != "" && unset("code-cache-size", "BPL_JVM_CODE_CACHE_SIZE") {
+		c.ProfileCodeCacheSize = p.CodeCacheSize
+	}
+	if p.DirectMemorySize != "" && unset("direct-memory-size", "BPL_JVM_DIRECT_MEMORY_SIZE") {
+		c.ProfileDirectMemorySize = p.DirectMemorySize
+	}
+	if p.HeadRoom != "" && unset("head-room", "BPL_JVM_HEAD_ROOM") {
+		c.HeadRoom = p.HeadRoom
+	}
+	if p.InitialHeap != "" && unset("initial-heap", "BPL_JVM_INITIAL_HEAP") {
+		c.InitialHeap = p.InitialHeap
+	}
+	if len(p.Options) > 0 && unset("jvm-options", "BPL_JVM_EXTRA_OPTIONS") {
+		c.JVMOptions = parser.FormatFlags(p.Options)
+	}
+}
+
+// ApplyConfigFile applies the metaspace settings of the configuration file that were neither given as command line
+// flag nor as environment variable. They take precedence over the profile. flags holds the names of the command line
+// flags that were set explicitly.
+func (c *Config) ApplyConfigFile(flags map[string]bool) error {
+	if c.ConfigFile == "" {
+		return nil
+	}
+
+	f, err := LoadFile(c.ConfigFile)
+	if err != nil {
+		return err
+	}
+	if f.Metaspace == nil {
+		return nil
+	}
+
+	unset := unsetFunc(flags)
+	ms := f.Metaspace
+	if ms.ClassSize != "" && unset("class-size", "BPL_JVM_CLASS_SIZE") {
+		c.ClassSize = ms.ClassSize
+	}
+	if ms.ClassOverhead != "" && unset("class-overhead", "BPL_JVM_CLASS_OVERHEAD") {
+		c.ClassOverhead = ms.ClassOverhead
+	}
+	if ms.ClassLoadFactor > 0 && unset("class-load-factor", "BPL_JVM_CLASS_LOAD_FACTOR") {
+		c.ClassLoadFactor = fmt.Sprint(ms.ClassLoadFactor)
+	}
+	if len(ms.LanguageWeights) > 0 && unset("language-weights", "BPL_JVM_LANGUAGE_WEIGHTS") {
+		var weights []string
+		for l, w := range ms.LanguageWeights {
+			weights = append(weights, fmt.Sprintf("%s=%g", l, w))
+		}
+		sort.Strings(weights)
+		c.LanguageWeights = strings.Join(weights, ",")
+	}
+
+	return nil
+}
+
+// unsetFunc returns a function that reports whether a setting was neither given as command line flag nor as
+// environment variable.
+func unsetFunc(flags map[string]bool) func(flag, env string) bool {
+	return func(flag, env string) bool {
+		if flags[flag] {
+			return false
+		}
+		_, ok := os.LookupEnv(env)
+		return !ok
+	}
+}
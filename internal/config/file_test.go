@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/patbaumgartner/memory-calculator/internal/profile"
+)
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory-calculator.json")
+	content := `{"profiles": {"orders": {"threadCount": 400, "options": ["-XX:+ExitOnOutOfMemoryError"]}}}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p := f.Profiles["orders"]; p.ThreadCount != 400 || len(p.Options) != 1 {
+		t.Errorf("Expected orders profile with 400 threads and one option, got %+v", p)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"profiles": {"orders": {"threads": 400}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("Expected error for unknown key")
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestResolveProfile(t *testing.T) {
+	cfg := &Config{}
+	if p, err := cfg.ResolveProfile(); err != nil || p != nil {
+		t.Errorf("Expected no profile without selection, got %+v, %v", p, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "memory-calculator.json")
+	if err := os.WriteFile(path, []byte(`{"profiles": {"orders": {"threadCount": 400}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = &Config{Profile: "orders", ConfigFile: path}
+	p, err := cfg.ResolveProfile()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p.Name != "orders" || p.ThreadCount != 400 {
+		t.Errorf("Expected custom profile orders, got %+v", p)
+	}
+
+	cfg = &Config{Profile: "unknown"}
+	if _, err := cfg.ResolveProfile(); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	_ = os.Unsetenv("BPL_JVM_THREAD_COUNT")
+	_ = os.Setenv("BPL_JVM_HEAD_ROOM", "3")
+	defer func() { _ = os.Unsetenv("BPL_JVM_HEAD_ROOM") }()
+
+	cfg := &Config{HeadRoom: "3", CodeCacheSize: "64M"}
+	cfg.ApplyProfile(profile.Builtin["low-latency"], map[string]bool{"code-cache-size": true})
+
+	if cfg.ProfileThreadCount != "250" || cfg.ProfileDirectMemorySize != "128M" || cfg.InitialHeap != "max" {
+		t.Errorf("Expected profile settings to apply, got %+v", cfg)
+	}
+	if cfg.DirectMemorySize != "" {
+		t.Errorf("Expected the profile direct memory to be kept apart from the user's, got %s", cfg.DirectMemorySize)
+	}
+	if cfg.ProfileClassLoadFactor != "0.35" || cfg.ClassLoadFactor != "" {
+		t.Errorf("Expected profile class load factor 0.35, got %s and %s", cfg.ProfileClassLoadFactor, cfg.ClassLoadFactor)
+	}
+	if cfg.JVMOptions != "-XX:+ExitOnOutOfMemoryError -XX:+AlwaysPreTouch" {
+		t.Errorf("Expected profile JVM options, got %s", cfg.JVMOptions)
+	}
+
+	// Explicit flags and environment variables take precedence
+	if cfg.ThreadCount != "" {
+		t.Errorf("Expected the profile thread count to be kept apart from the user's, got %s", cfg.ThreadCount)
+	}
+	if cfg.CodeCacheSize != "64M" || cfg.ProfileCodeCacheSize != "" {
+		t.Errorf("Expected code cache size flag to take precedence, got %s and %s",
+			cfg.CodeCacheSize, cfg.ProfileCodeCacheSize)
+	}
+	if cfg.HeadRoom != "3" {
+		t.Errorf("Expected $BPL_JVM_HEAD_ROOM to take precedence, got %s", cfg.HeadRoom)
+	}
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory-calculator.json")
+	content := `{"metaspace": {"classSize": "8K", "classOverhead": "20M", "classLoadFactor": 0.5,
+		"languageWeights": {"kts": 2, "groovy": 3}}}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = os.Setenv("BPL_JVM_CLASS_OVERHEAD", "16M")
+	defer func() { _ = os.Unsetenv("BPL_JVM_CLASS_OVERHEAD") }()
+
+	cfg := &Config{ConfigFile: path, ClassOverhead: "16M", ClassLoadFactor: "0.3"}
+	if err := cfg.ApplyConfigFile(map[string]bool{"class-load-factor": true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if cfg.ClassSize != "8K" || cfg.LanguageWeights != "groovy=3,kts=2" {
+		t.Errorf("Expected class size and language weights from file, got %+v", cfg)
+	}
+	if cfg.ClassOverhead != "16M" || cfg.ClassLoadFactor != "0.3" {
+		t.Errorf("Expected environment variable and flag to take precedence, got %+v", cfg)
+	}
+
+	if err := (&Config{}).ApplyConfigFile(nil); err != nil {
+		t.Errorf("Expected no error without configuration file, got %v", err)
+	}
+}
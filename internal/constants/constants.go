@@ -26,6 +26,8 @@ const (
 	EnvTotalMemory = "BPL_JVM_TOTAL_MEMORY"
 	// EnvThreadCount is the environment variable for thread count.
 	EnvThreadCount = "BPL_JVM_THREAD_COUNT"
+	// EnvVirtualThreadCount is the environment variable for the expected virtual thread count.
+	EnvVirtualThreadCount = "BPL_JVM_VIRTUAL_THREAD_COUNT"
 	// EnvLoadedClassCount is the environment variable for loaded class count.
 	EnvLoadedClassCount = "BPL_JVM_LOADED_CLASS_COUNT"
 	// EnvHeadRoom is the environment variable for head room.
@@ -34,6 +36,16 @@ const (
 	EnvApplicationPath = "BPI_APPLICATION_PATH"
 	// EnvJVMClassCount is the environment variable for JVM class count.
 	EnvJVMClassCount = "BPI_JVM_CLASS_COUNT"
+	// EnvJavaHome is the environment variable for the Java home used for version detection.
+	EnvJavaHome = "JAVA_HOME"
+	// EnvSegmentedCodeCache is the environment variable for emitting code heap sizes.
+	EnvSegmentedCodeCache = "BPL_JVM_SEGMENTED_CODE_CACHE"
+	// EnvDirectMemoryRules is the environment variable for overriding direct memory rules of detected libraries.
+	EnvDirectMemoryRules = "BPL_JVM_DIRECT_MEMORY_RULES"
+	// EnvDegrade is the environment variable for enabling degrade mode.
+	EnvDegrade = "BPL_JVM_DEGRADE"
+	// EnvDegradeMinHeap is the environment variable for the minimum heap degrade mode makes room for.
+	EnvDegradeMinHeap = "BPL_JVM_DEGRADE_MIN_HEAP"
 	// EnvQuiet is the environment variable for quiet mode.
 	EnvQuiet = "QUIET"
 
@@ -0,0 +1,24 @@
+package count
+
+// AppServer describes an application server installation whose libraries and deployments are counted in addition
+// to the application path.
+type AppServer struct {
+	// Name identifies the application server, e.g. "tomcat".
+	Name string
+
+	// HomeEnv is the environment variable pointing to the installation, e.g. "CATALINA_HOME".
+	HomeEnv string
+
+	// Dirs lists the directories below the installation that hold the server's own libraries and the deployed
+	// applications.
+	Dirs []string
+}
+
+// AppServers lists the application servers detected by their environment variables.
+var AppServers = []AppServer{
+	{Name: "tomcat", HomeEnv: "CATALINA_HOME", Dirs: []string{"lib", "webapps"}},
+	{Name: "tomcat", HomeEnv: "CATALINA_BASE", Dirs: []string{"lib", "webapps"}},
+	{Name: "wildfly", HomeEnv: "JBOSS_HOME", Dirs: []string{"modules", "standalone/deployments"}},
+	{Name: "liberty", HomeEnv: "WLP_INSTALL_DIR", Dirs: []string{"lib"}},
+	{Name: "liberty", HomeEnv: "WLP_USER_DIR", Dirs: []string{"servers", "shared/resources"}},
+}
@@ -0,0 +1,100 @@
+package count
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScanWebArchive(t *testing.T) {
+	dir := t.TempDir()
+	lib := buildJar(t, jarEntry{name: "org/example/Lib.class"}, jarEntry{name: "org/example/Util.class"})
+	war := buildJar(t,
+		jarEntry{name: "WEB-INF/classes/com/example/Servlet.class"},
+		jarEntry{name: "WEB-INF/lib/lib.jar", content: lib},
+		jarEntry{name: "index.html"},
+	)
+	if err := os.WriteFile(filepath.Join(dir, "app.war"), war, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := JarClasses(dir); err != nil || c != 3 {
+		t.Errorf("Expected 3 classes in web archive, got %d, %v", c, err)
+	}
+}
+
+func TestScanEnterpriseArchive(t *testing.T) {
+	dir := t.TempDir()
+	lib := buildJar(t, jarEntry{name: "org/example/Lib.class"})
+	war := buildJar(t, jarEntry{name: "WEB-INF/classes/com/example/Servlet.class"},
+		jarEntry{name: "WEB-INF/lib/lib.jar", content: lib})
+	ejb := buildJar(t, jarEntry{name: "com/example/Bean.class"})
+	ear := buildJar(t, jarEntry{name: "web.war", content: war}, jarEntry{name: "ejb.jar", content: ejb},
+		jarEntry{name: "lib/lib.jar", content: lib})
+	if err := os.WriteFile(filepath.Join(dir, "app.ear"), ear, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := JarClasses(dir); err != nil || c != 4 {
+		t.Errorf("Expected 4 classes in enterprise archive, got %d, %v", c, err)
+	}
+}
+
+func TestScanSkipsExtractedWebArchive(t *testing.T) {
+	dir := t.TempDir()
+	war := buildJar(t, jarEntry{name: "WEB-INF/classes/com/example/Servlet.class"})
+	if err := os.WriteFile(filepath.Join(dir, "app.war"), war, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	exploded := filepath.Join(dir, "app", "WEB-INF", "classes", "com", "example")
+	if err := os.MkdirAll(exploded, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(exploded, "Servlet.class"), []byte("class"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := JarClasses(dir); err != nil || c != 1 {
+		t.Errorf("Expected the web archive to be counted once, got %d, %v", c, err)
+	}
+}
+
+func TestResultMerge(t *testing.T) {
+	a := Result{Classes: 10, Libraries: []string{"netty-buffer"}, Languages: map[string]int{"groovy": 2}}
+	b := Result{Classes: 5, Libraries: []string{"lucene", "netty-buffer"}, Frameworks: []string{"jakarta-ee"},
+		Languages: map[string]int{"groovy": 1, "kts": 1}}
+
+	expected := Result{
+		Classes:    15,
+		Libraries:  []string{"lucene", "netty-buffer"},
+		Languages:  map[string]int{"groovy": 3, "kts": 1},
+		Frameworks: []string{"jakarta-ee"},
+	}
+	if m := a.Merge(b); !reflect.DeepEqual(m, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, m)
+	}
+}
+
+func TestResultMergeUnique(t *testing.T) {
+	app, server := t.TempDir(), t.TempDir()
+	writeJar(t, filepath.Join(app, "app.jar"), "com/example/App.class", "com/example/Shared.class", "app.groovy")
+	writeJar(t, filepath.Join(server, "lib.jar"), "com/example/Shared.class", "org/server/Server.class")
+
+	a, err := ScanWith(app, Options{Unique: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ScanWith(server, Options{Unique: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := a.Merge(b)
+	if m.Classes != 5 || m.UniqueClasses != 4 {
+		t.Errorf("Expected 5 class files and 4 unique classes, got %d and %d", m.Classes, m.UniqueClasses)
+	}
+	if e := map[string]int{"groovy": 1}; !reflect.DeepEqual(m.Languages, e) {
+		t.Errorf("Expected languages %v, got %v", e, m.Languages)
+	}
+}
@@ -0,0 +1,191 @@
+package count
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheVersion is the version of the cache file format. Cache files of other versions are ignored.
+const CacheVersion = 1
+
+// DefaultCacheFile is the name of the cache file in the temporary directory if no cache path is configured.
+const DefaultCacheFile = "memory-calculator-class-count.json"
+
+// ErrCorruptCache is returned by OpenCache if the cache file cannot be used.
+var ErrCorruptCache = errors.New("corrupt class count cache")
+
+// Cache holds the counts of previously scanned archives, keyed by path, so that only changed archives are scanned.
+// An archive is unchanged if its size and modification time, and its content digest if hashing is enabled, are the
+// same as when it was counted. A Cache is safe for concurrent use.
+type Cache struct {
+	path string
+	hash bool
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	used    map[string]cacheEntry
+	changed bool
+}
+
+// cacheFile is the format of the cache file. Checksum is the SHA-256 digest of Entries to detect corruption.
+type cacheFile struct {
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"`
+	Entries  json.RawMessage `json:"entries"`
+}
+
+// cacheEntry is the count of a single archive.
+type cacheEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"`
+	Digest  string `json:"digest,omitempty"`
+
+	// Depth and Unique are the options the archive was counted with.
+	Depth  int  `json:"depth"`
+	Unique bool `json:"unique,omitempty"`
+
+	Classes    int               `json:"classes"`
+	Libraries  []string          `json:"libraries,omitempty"`
+	Frameworks []string          `json:"frameworks,omitempty"`
+	Languages  map[string]int    `json:"languages,omitempty"`
+	Names      map[string]string `json:"names,omitempty"`
+}
+
+// DefaultCachePath returns the cache path used if none is configured.
+func DefaultCachePath() string {
+	return filepath.Join(os.TempDir(), DefaultCacheFile)
+}
+
+// OpenCache reads the cache at path, hashing the content of archives if hash is true. A missing cache file yields an
+// empty cache. A cache file of another version or failing its checksum yields an empty cache together with an error
+// wrapping ErrCorruptCache, so that all archives are scanned again.
+func OpenCache(path string, hash bool) (*Cache, error) {
+	c := &Cache{path: path, hash: hash, entries: map[string]cacheEntry{}, used: map[string]cacheEntry{}}
+
+	// #nosec G304 - the cache path is chosen by the user
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return c, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	var f cacheFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return c, fmt.Errorf("%w %s\n%w", ErrCorruptCache, path, err)
+	}
+	if f.Version != CacheVersion {
+		return c, fmt.Errorf("%w %s: version %d, expected %d", ErrCorruptCache, path, f.Version, CacheVersion)
+	}
+	if f.Checksum != checksum(f.Entries) {
+		return c, fmt.Errorf("%w %s: checksum mismatch", ErrCorruptCache, path)
+	}
+
+	entries := map[string]cacheEntry{}
+	if err := json.Unmarshal(f.Entries, &entries); err != nil {
+		return c, fmt.Errorf("%w %s\n%w", ErrCorruptCache, path, err)
+	}
+	c.entries = entries
+	return c, nil
+}
+
+// Save writes the entries of the archives counted since the cache was opened, dropping those of archives that were
+// not seen. The file is replaced atomically and only written if an archive was counted again or was not seen.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.changed && len(c.used) == len(c.entries) {
+		return nil
+	}
+
+	entries, err := json.Marshal(c.used)
+	if err != nil {
+		return fmt.Errorf("unable to encode class count cache\n%w", err)
+	}
+	b, err := json.Marshal(cacheFile{Version: CacheVersion, Checksum: checksum(entries), Entries: entries})
+	if err != nil {
+		return fmt.Errorf("unable to encode class count cache\n%w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to create %s\n%w", c.path, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := io.Copy(tmp, bytes.NewReader(b)); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("unable to write %s\n%w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write %s\n%w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("unable to replace %s\n%w", c.path, err)
+	}
+	return nil
+}
+
+// lookup returns the entry of the archive if it is unchanged and was counted with the given options. The key holds
+// the size, modification time and digest of the archive as it is now, for storing a new count.
+func (c *Cache) lookup(a archive, depth int, unique bool) (cacheEntry, cacheEntry, bool, error) {
+	key := cacheEntry{Size: a.size, ModTime: a.modTime, Depth: depth, Unique: unique}
+	if c.hash {
+		d, err := digest(a.path)
+		if err != nil {
+			return cacheEntry{}, cacheEntry{}, false, err
+		}
+		key.Digest = d
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[a.path]
+	if !ok || e.Size != key.Size || e.ModTime != key.ModTime || e.Digest != key.Digest ||
+		e.Depth != key.Depth || e.Unique != key.Unique {
+		return cacheEntry{}, key, false, nil
+	}
+	c.used[a.path] = e
+	return e, key, true, nil
+}
+
+// store records the count of an archive.
+func (c *Cache) store(path string, e cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.used[path] = e
+	c.changed = true
+}
+
+// digest returns the SHA-256 digest of the content of the file at path.
+func digest(path string) (string, error) {
+	// #nosec G304 - the path is found while walking the application path chosen by the user
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("unable to hash %s\n%w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksum returns the hex encoded SHA-256 digest of b.
+func checksum(b []byte) string {
+	s := sha256.Sum256(b)
+	return hex.EncodeToString(s[:])
+}
@@ -0,0 +1,159 @@
+package count
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCachedJars writes two jars to a new directory and returns it.
+func writeCachedJars(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	jars := map[string][]byte{
+		"a.jar": buildJar(t, jarEntry{name: "com/example/A.class"}, jarEntry{name: "com/example/B.class"}),
+		"b.jar": buildJar(t, jarEntry{name: "io/netty/buffer/PooledByteBufAllocator.class"}),
+	}
+	for name, b := range jars {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCacheReusesUnchangedArchives(t *testing.T) {
+	dir := writeCachedJars(t)
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	c, err := OpenCache(path, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected, err := ScanWith(dir, Options{Cache: c})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Replace a jar with different content of the same size and modification time, the cached count is used
+	info, _ := os.Stat(filepath.Join(dir, "a.jar"))
+	fake := make([]byte, info.Size())
+	if err := os.WriteFile(filepath.Join(dir, "a.jar"), fake, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dir, "a.jar"), info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err = OpenCache(path, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r, err := ScanWith(dir, Options{Cache: c})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if r.Classes != expected.Classes || len(r.Libraries) != 1 {
+		t.Errorf("Expected cached result %+v, got %+v", expected, r)
+	}
+
+	// Comparing digests detects the change
+	if c, err = OpenCache(path, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if r, _ = ScanWith(dir, Options{Cache: c}); r.Classes != 1 {
+		t.Errorf("Expected changed jar to be scanned again, got %d classes", r.Classes)
+	}
+}
+
+func TestCacheDetectsModifiedArchives(t *testing.T) {
+	dir := writeCachedJars(t)
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	c, _ := OpenCache(path, false)
+	if _, err := ScanWith(dir, Options{Cache: c}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	jar := buildJar(t, jarEntry{name: "com/example/A.class"})
+	if err := os.WriteFile(filepath.Join(dir, "a.jar"), jar, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "a.jar"), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ = OpenCache(path, false)
+	if r, _ := ScanWith(dir, Options{Cache: c}); r.Classes != 2 {
+		t.Errorf("Expected modified jar to be scanned again, got %d classes", r.Classes)
+	}
+
+	// Counts of other options are not reused
+	if r, _ := ScanWith(dir, Options{Cache: c, Unique: true}); r.UniqueClasses != 2 {
+		t.Errorf("Expected unique classes to be counted, got %d", r.UniqueClasses)
+	}
+}
+
+func TestCacheSavePrunesUnseenArchives(t *testing.T) {
+	dir := writeCachedJars(t)
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	c, _ := OpenCache(path, false)
+	_, _ = ScanWith(dir, Options{Cache: c})
+	_ = c.Save()
+
+	if err := os.Remove(filepath.Join(dir, "b.jar")); err != nil {
+		t.Fatal(err)
+	}
+	c, _ = OpenCache(path, false)
+	_, _ = ScanWith(dir, Options{Cache: c})
+	if err := c.Save(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	c, _ = OpenCache(path, false)
+	if len(c.entries) != 1 {
+		t.Errorf("Expected 1 cache entry after removing a jar, got %d", len(c.entries))
+	}
+}
+
+func TestOpenCacheCorrupt(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid json", "{not json"},
+		{"other version", `{"version":0,"checksum":"","entries":{}}`},
+		{"checksum mismatch", `{"version":1,"checksum":"00","entries":{"/app/a.jar":{"size":1,"classes":9}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := OpenCache(path, false)
+			if !errors.Is(err, ErrCorruptCache) {
+				t.Errorf("Expected ErrCorruptCache, got %v", err)
+			}
+			if c == nil || len(c.entries) != 0 {
+				t.Errorf("Expected an empty cache, got %+v", c)
+			}
+		})
+	}
+
+	if c, err := OpenCache(filepath.Join(t.TempDir(), "missing.json"), false); err != nil || c == nil {
+		t.Errorf("Expected an empty cache for a missing file, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package count
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCDSArchive writes a CDS archive of the given size starting with magic in the given byte order.
+func writeCDSArchive(t *testing.T, path string, order binary.ByteOrder, magic uint32, size int) {
+	t.Helper()
+
+	b := make([]byte, size)
+	order.PutUint32(b, magic)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadSharedArchive(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "classes.jsa")
+	writeCDSArchive(t, path, binary.LittleEndian, CDSMagic, 25*CDSBytesPerClass)
+
+	a, err := ReadSharedArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Dynamic || a.Size != 25*CDSBytesPerClass || a.Classes != 25 || a.Classlist != "" {
+		t.Errorf("Expected a static archive with an estimated 25 classes, got %+v", a)
+	}
+
+	classlist := filepath.Join(dir, "classlist")
+	content := strings.Join([]string{
+		"# NOTE: Do not modify this file.",
+		"java/lang/Object",
+		"java/lang/String id: 1",
+		"@lambda-proxy java/lang/Runnable run ()Ljava/lang/Runnable;",
+		"",
+		"java/util/List",
+	}, "\n")
+	if err := os.WriteFile(classlist, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err = ReadSharedArchive(path, filepath.Join(dir, "missing.classlist"), classlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Classes != 3 || a.Classlist != classlist {
+		t.Errorf("Expected 3 classes listed in %s, got %+v", classlist, a)
+	}
+}
+
+func TestReadSharedArchiveDynamic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "application.jsa")
+	writeCDSArchive(t, path, binary.BigEndian, CDSDynamicMagic, 64)
+
+	a, err := ReadSharedArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Dynamic {
+		t.Errorf("Expected a dynamic archive, got %+v", a)
+	}
+}
+
+func TestReadSharedArchiveInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "classes.jsa")
+	writeCDSArchive(t, path, binary.LittleEndian, JImageMagic, 64)
+
+	if _, err := ReadSharedArchive(path); !errors.Is(err, ErrNotCDSArchive) {
+		t.Errorf("Expected %v, got %v", ErrNotCDSArchive, err)
+	}
+	if err := os.WriteFile(path, []byte{0xA2}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSharedArchive(path); !errors.Is(err, ErrNotCDSArchive) {
+		t.Errorf("Expected %v for a truncated archive, got %v", ErrNotCDSArchive, err)
+	}
+}
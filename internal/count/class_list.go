@@ -0,0 +1,62 @@
+package count
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadedClasses counts the classes an application loaded in a previous run, read from the class list at path. The
+// class list is either the output of -Xlog:class+load (or -verbose:class of Java 8), which lists every class loaded,
+// or a classlist written by -XX:DumpLoadedClassList. Classes loaded more than once, e.g. by different class loaders,
+// are counted for every load, as each load allocates metaspace.
+func LoadedClasses(path string) (int, error) {
+	// #nosec G304 - the class list is chosen by the user
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	logged, listed := 0, 0
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		switch {
+		case isClassLoadLog(l):
+			logged++
+		case isClasslistEntry(l) && !strings.HasPrefix(l, "["):
+			listed++
+		}
+	}
+	if err := s.Err(); err != nil {
+		return 0, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	if logged > 0 {
+		return logged, nil
+	} else if listed > 0 {
+		return listed, nil
+	}
+	return 0, fmt.Errorf("no loaded classes found in %s", path)
+}
+
+// isClassLoadLog returns true if the line logs a loaded class, like
+// "[0.012s][info][class,load] java.lang.Object source: shared objects file" of -Xlog:class+load, with any
+// decorators, or "[Loaded java.lang.Object from /usr/lib/jvm/jre/lib/rt.jar]" of -verbose:class.
+func isClassLoadLog(l string) bool {
+	if strings.HasPrefix(l, "[Loaded ") {
+		return true
+	}
+	for strings.HasPrefix(l, "[") {
+		i := strings.Index(l, "]")
+		if i < 0 {
+			return false
+		}
+		l = strings.TrimSpace(l[i+1:])
+	}
+	name, _, ok := strings.Cut(l, " source: ")
+	return ok && name != "" && !strings.Contains(name, " ")
+}
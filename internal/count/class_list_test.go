@@ -0,0 +1,89 @@
+package count
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadedClasses(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected int
+	}{
+		{
+			name: "class load log",
+			lines: []string{
+				"[0.005s][info][class,load] opened: /usr/lib/jvm/lib/modules",
+				"[0.010s][info][class,load] java.lang.Object source: shared objects file",
+				"[0.011s][info][class,load] java.lang.String source: jrt:/java.base",
+				"[0.012s][debug][class,load] klass: 0x0000000800001000 super: 0x0000000800000e28",
+				"[0.300s][info][class,load] com.example.App source: file:/app/BOOT-INF/classes/",
+				"[0.301s][info][gc] Using G1",
+				"[0.310s][info][class,load] java.lang.invoke.LambdaForm$MH/0x0000000801004400 source: __JVM_LookupDefineClass__",
+			},
+			expected: 4,
+		},
+		{
+			name: "class load log without decorators",
+			lines: []string{
+				"java.lang.Object source: shared objects file",
+				"com.example.App source: file:/app/BOOT-INF/classes/",
+			},
+			expected: 2,
+		},
+		{
+			name: "verbose class output",
+			lines: []string{
+				"[Opened /usr/lib/jvm/jre/lib/rt.jar]",
+				"[Loaded java.lang.Object from /usr/lib/jvm/jre/lib/rt.jar]",
+				"[Loaded com.example.App from file:/app/]",
+			},
+			expected: 2,
+		},
+		{
+			name: "classlist",
+			lines: []string{
+				"# NOTE: Do not modify this file.",
+				"java/lang/Object id: 0",
+				"java/lang/String id: 1",
+				"@lambda-proxy java/lang/Runnable run ()Ljava/lang/Runnable;",
+				"com/example/App id: 2",
+			},
+			expected: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "classes.log")
+			if err := os.WriteFile(path, []byte(strings.Join(tt.lines, "\n")), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			n, err := LoadedClasses(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.expected {
+				t.Errorf("Expected %d loaded classes, got %d", tt.expected, n)
+			}
+		})
+	}
+}
+
+func TestLoadedClassesEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "classes.log")
+	if err := os.WriteFile(path, []byte("[0.301s][info][gc] Using G1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadedClasses(path); err == nil {
+		t.Error("Expected an error for a log without loaded classes")
+	}
+	if _, err := LoadedClasses(filepath.Join(t.TempDir(), "missing.log")); err == nil {
+		t.Error("Expected an error for a missing class list")
+	}
+}
@@ -0,0 +1,83 @@
+package count
+
+import (
+	"path"
+	"strings"
+)
+
+// ClassExtensions lists the file extensions considered as class files.
+var ClassExtensions = []string{".class", ".classdata", ".clj", ".groovy", ".kts"}
+
+// ArchiveExtensions lists the file extensions of archives whose class files are counted: jars, web archives
+// (classes in WEB-INF/classes/ and WEB-INF/lib/*.jar) and enterprise archives (nested web archives and jars).
+var ArchiveExtensions = []string{".jar", ".war", ".ear"}
+
+// archive is an archive found while walking.
+type archive struct {
+	path    string
+	size    int64
+	modTime int64
+}
+
+// ClassRoots lists the directories of archives that class names are relative to, e.g. the classes of a web
+// archive are in WEB-INF/classes/.
+var ClassRoots = []string{"BOOT-INF/classes/", "WEB-INF/classes/"}
+
+// VersionsDir is the directory of a multi-release jar holding the class variants for later Java versions.
+const VersionsDir = "META-INF/versions/"
+
+// className returns the fully-qualified name of the class stored at the given path, e.g. "com/example/Foo" for
+// "BOOT-INF/classes/META-INF/versions/17/com/example/Foo.class", and false if the entry is not a loadable class
+// such as module-info.class or package-info.class.
+func className(name string) (string, bool) {
+	n := strings.ReplaceAll(name, "\\", "/")
+	for _, e := range ClassExtensions {
+		if strings.HasSuffix(n, e) {
+			n = strings.TrimSuffix(n, e)
+			break
+		}
+	}
+
+	switch path.Base(n) {
+	case "module-info", "package-info":
+		return "", false
+	}
+
+	for _, r := range ClassRoots {
+		if i := strings.LastIndex(n, r); i >= 0 {
+			n = n[i+len(r):]
+		}
+	}
+
+	if strings.HasPrefix(n, VersionsDir) {
+		if i := strings.Index(n[len(VersionsDir):], "/"); i >= 0 {
+			n = n[len(VersionsDir)+i+1:]
+		}
+	}
+	return n, true
+}
+
+// classNames collects the distinct fully-qualified class names found while scanning, mapped to the path of the first
+// entry found for each.
+type classNames map[string]string
+
+// add records the class stored at the given path unless its name was recorded before.
+func (c classNames) add(name string) {
+	n, ok := className(name)
+	if !ok {
+		return
+	}
+	if _, ok := c[n]; !ok {
+		c[n] = name
+	}
+}
+
+// isArchive returns true if name has one of the ArchiveExtensions.
+func isArchive(name string) bool {
+	for _, e := range ArchiveExtensions {
+		if strings.HasSuffix(name, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package count
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{"com/example/Foo.class", "com/example/Foo", true},
+		{"com/example/Foo$Bar.class", "com/example/Foo$Bar", true},
+		{"META-INF/versions/17/com/example/Foo.class", "com/example/Foo", true},
+		{"BOOT-INF/classes/com/example/Foo.class", "com/example/Foo", true},
+		{"application/BOOT-INF/classes/META-INF/versions/21/com/example/Foo.class", "com/example/Foo", true},
+		{"WEB-INF/classes/com/example/Servlet.class", "com/example/Servlet", true},
+		{"scripts/build.groovy", "scripts/build", true},
+		{"module-info.class", "", false},
+		{"META-INF/versions/9/module-info.class", "", false},
+		{"com/example/package-info.class", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, ok := className(tt.name)
+			if n != tt.expected || ok != tt.ok {
+				t.Errorf("Expected %q, %t, got %q, %t", tt.expected, tt.ok, n, ok)
+			}
+		})
+	}
+}
+
+func TestScanUnique(t *testing.T) {
+	dir := t.TempDir()
+	app := buildJar(t,
+		jarEntry{name: "module-info.class"},
+		jarEntry{name: "com/example/Foo.class"},
+		jarEntry{name: "com/example/package-info.class"},
+		jarEntry{name: "META-INF/versions/11/com/example/Foo.class"},
+		jarEntry{name: "META-INF/versions/17/com/example/Foo.class"},
+		jarEntry{name: "com/example/Bar.class"},
+	)
+	// A duplicate dependency version and a shaded copy with relocated packages
+	dup := buildJar(t, jarEntry{name: "com/example/Bar.class"}, jarEntry{name: "shaded/com/example/Bar.class"})
+	for name, b := range map[string][]byte{"app.jar": app, "dup.jar": dup} {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := ScanWith(dir, Options{Unique: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if r.Classes != 8 {
+		t.Errorf("Expected 8 class files, got %d", r.Classes)
+	}
+	if r.UniqueClasses != 3 {
+		t.Errorf("Expected 3 unique classes, got %d", r.UniqueClasses)
+	}
+
+	if r, _ = ScanWith(dir, Options{}); r.UniqueClasses != 0 {
+		t.Errorf("Expected no unique count without the unique option, got %d", r.UniqueClasses)
+	}
+}
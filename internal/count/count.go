@@ -13,28 +13,52 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
-// ClassExtensions lists the file extensions considered as class files.
-var ClassExtensions = []string{".class", ".classdata", ".clj", ".groovy", ".kts"}
-
 // Classes counts class files in the given path. It first checks for a modules file (Java 9+)
 // and falls back to counting JAR files for older Java versions.
 func Classes(path string) (int, error) {
+	r, err := Scan(path)
+	if err != nil {
+		return 0, err
+	}
+	return r.Classes, nil
+}
+
+// Scan counts class files in the given path like Classes and additionally reports the off-heap
+// heavy libraries found along the way.
+func Scan(path string) (Result, error) {
+	return ScanWith(path, Options{})
+}
+
+// ScanWith scans the given path like Scan with the given options.
+func ScanWith(path string, o Options) (Result, error) {
 	file := filepath.Join(path, "lib", "modules")
 	_, err := os.Stat(file)
 	if err != nil && !os.IsNotExist(err) {
-		return 0, fmt.Errorf("unable to stat %s\n%w", file, err)
+		return Result{}, fmt.Errorf("unable to stat %s\n%w", file, err)
 	} else if os.IsNotExist(err) {
-		return JarClasses(path)
+		return scanJars(path, o)
+	}
+	modules, err := ModuleClasses(file)
+	if err != nil {
+		// Fall back to an estimate based on the size of the modules file if it cannot be read as a jimage
+		c, err := estimateModuleClasses(file)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Classes: c}, nil
 	}
-	// For Java 9+ with modules, we'll use a simple estimate based on typical module sizes
-	// since implementing the full module reader would be complex
-	return estimateModuleClasses(file)
+
+	c := 0
+	for _, n := range modules {
+		c += n
+	}
+	return Result{Classes: c}, nil
 }
 
-// estimateModuleClasses provides an estimate of classes in a modules file
-// This is a simplified version - in a real implementation, you'd parse the modules file
+// estimateModuleClasses provides an estimate of classes in a modules file that cannot be read as a jimage
 func estimateModuleClasses(modulesFile string) (int, error) {
 	info, err := os.Stat(modulesFile)
 	if err != nil {
@@ -53,58 +77,339 @@ func estimateModuleClasses(modulesFile string) (int, error) {
 
 // JarClasses counts class files in JAR files and directories recursively
 func JarClasses(path string) (int, error) {
-	count := 0
+	r, err := scanJars(path, Options{})
+	if err != nil {
+		return 0, err
+	}
+	return r.Classes, nil
+}
 
-	if err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// scanner accumulates class counts, script counts, detected libraries and detected frameworks while walking a path.
+type scanner struct {
+	classes    int
+	libraries  detector
+	languages  languages
+	frameworks frameworks
+
+	// names collects the distinct class names when counting unique classes, nil otherwise.
+	names classNames
+
+	// root is the scanned path that class file paths are relative to.
+	root string
+
+	// depth is the number of levels of nested jars to descend into.
+	depth int
+
+	// application is the Spring Boot application extracted into the scanned path, nil if there is none.
+	application *explodedApplication
+
+	// archives lists the archives found while walking, counted after the walk.
+	archives []archive
+
+	// cache holds the counts of previously scanned archives, nil if archives are always scanned.
+	cache *Cache
+}
+
+// scanJars counts class files in JAR files and directories recursively and detects libraries and frameworks.
+// Archives are read concurrently by up to Options.Workers workers.
+func scanJars(path string, o Options) (Result, error) {
+	a, err := readExplodedApplication(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to read Spring Boot index of %s\n%w", path, err)
+	}
+
+	s := newScanner(o.Unique, o.nestedJarDepth())
+	s.root = path
+	s.application = a
+	s.cache = o.Cache
+
+	if err := filepath.Walk(path, s.visit); err != nil {
+		return Result{}, fmt.Errorf("unable to walk %s\n%w", path, err)
+	}
+	if err := s.scanArchives(o.workers()); err != nil {
+		return Result{}, err
+	}
+
+	return s.result(), nil
+}
+
+// newScanner returns an empty scanner descending depth levels into nested jars.
+func newScanner(unique bool, depth int) *scanner {
+	s := &scanner{
+		libraries:  detector{},
+		languages:  languages{},
+		frameworks: frameworks{},
+		depth:      depth,
+	}
+	if unique {
+		s.names = classNames{}
+	}
+	return s
+}
+
+// result returns the result of the scan. Scripts are tallied per unique class name when counting unique classes.
+func (s *scanner) result() Result {
+	for _, n := range s.names {
+		s.languages.entry(n)
+	}
+	return Result{
+		Classes:       s.classes,
+		UniqueClasses: len(s.names),
+		Libraries:     s.libraries.names(),
+		Languages:     s.languages.counts(),
+		Frameworks:    s.frameworks.names(),
+		names:         s.names,
+	}
+}
+
+// merge adds the counts of o, e.g. of a single archive, to the scan.
+func (s *scanner) merge(o *scanner) {
+	s.classes += o.classes
+	for n := range o.libraries {
+		s.libraries[n] = true
+	}
+	for n := range o.frameworks {
+		s.frameworks[n] = true
+	}
+	for l, c := range o.languages {
+		s.languages[l] += c
+	}
+	for n, e := range o.names {
+		if _, ok := s.names[n]; !ok {
+			s.names[n] = e
 		}
+	}
+}
+
+// scanArchives counts the archives found while walking with the given number of workers. The archives are merged in
+// the order they were found so that the result does not depend on scheduling. Errors are collected for all archives.
+func (s *scanner) scanArchives(workers int) error {
+	scans := make([]*scanner, len(s.archives))
+	errs := make([]error, len(s.archives))
 
-		// Count class files directly on filesystem
-		for _, e := range ClassExtensions {
-			if strings.HasSuffix(path, e) {
-				count++
-				return nil
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(s.archives)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				scans[i], errs[i] = s.scanArchive(s.archives[i])
 			}
+		}()
+	}
+	for i := range s.archives {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, a := range scans {
+		if errs[i] == nil {
+			s.merge(a)
 		}
+	}
+	return errors.Join(errs...)
+}
+
+// scanArchive returns the scan of a single archive, taken from the cache if the archive did not change.
+func (s *scanner) scanArchive(a archive) (*scanner, error) {
+	o := newScanner(s.names != nil, s.depth)
+	if s.cache == nil {
+		return o, o.archive(a)
+	}
+
+	e, key, ok, err := s.cache.lookup(a, s.depth, s.names != nil)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		o.restore(e)
+		return o, nil
+	}
+
+	if err := o.archive(a); err != nil {
+		return nil, err
+	}
+	s.cache.store(a.path, o.cacheEntry(key))
+	return o, nil
+}
+
+// cacheEntry returns the cache entry of the scan of a single archive, completing the given key.
+func (s *scanner) cacheEntry(key cacheEntry) cacheEntry {
+	key.Classes = s.classes
+	key.Libraries = s.libraries.names()
+	key.Frameworks = s.frameworks.names()
+	key.Languages = s.languages.counts()
+	if len(s.names) > 0 {
+		key.Names = s.names
+	}
+	return key
+}
+
+// restore sets the scan of a single archive from its cache entry.
+func (s *scanner) restore(e cacheEntry) {
+	s.classes = e.Classes
+	for _, n := range e.Libraries {
+		s.libraries[n] = true
+	}
+	for _, n := range e.Frameworks {
+		s.frameworks[n] = true
+	}
+	for l, c := range e.Languages {
+		s.languages[l] = c
+	}
+	for n, p := range e.Names {
+		s.names[n] = p
+	}
+}
+
+// archive counts the class files of an archive found while walking.
+func (s *scanner) archive(a archive) error {
+	// #nosec G304 - the path is found while walking the application path chosen by the user
+	f, err := os.Open(a.path)
+	if err != nil {
+		return fmt.Errorf("unable to open Jar %s\n%w", a.path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	s.libraries.jar(a.path)
+	if err := s.jar(f, a.size, 0); err != nil {
+		return fmt.Errorf("unable to count Jar %s\n%w", a.path, err)
+	}
+	return nil
+}
+
+// entry records a counted class entry. When counting unique classes, scripts are tallied by name once the scan is
+// complete.
+func (s *scanner) entry(name string) {
+	s.classes++
+	s.libraries.class(name)
+	s.frameworks.class(name)
+	if s.names == nil {
+		s.languages.entry(name)
+	} else {
+		s.names.add(name)
+	}
+}
+
+// manifest records the frameworks identified by the attributes of a jar manifest
+func (s *scanner) manifest(f *zip.File) error {
+	if f.Name != ManifestPath {
+		return nil
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("unable to open manifest\n%w", err)
+	}
+	defer func() { _ = r.Close() }()
+
+	// Manifests are small, anything beyond 1MB is not read
+	b, err := io.ReadAll(io.LimitReader(r, 1024*1024))
+	if err != nil {
+		return fmt.Errorf("unable to read manifest\n%w", err)
+	}
+	s.frameworks.manifest(string(b))
+	return nil
+}
 
-		if !strings.HasSuffix(path, ".jar") || info.IsDir() {
+// visit counts a single file found while walking
+func (s *scanner) visit(path string, info os.FileInfo, err error) error {
+	if err != nil {
+		return err
+	}
+
+	// Count class files directly on filesystem
+	for _, e := range ClassExtensions {
+		if strings.HasSuffix(path, e) {
+			if rel, err := filepath.Rel(s.root, path); err == nil && rel != "." {
+				path = filepath.ToSlash(rel)
+			}
+			s.entry(path)
 			return nil
 		}
+	}
 
-		// Check for zero byte JAR files with name containing 'none' - these can not be unzipped
-		// examples of these were found in the JDK, e.g. svm-none.jar
-		if info.Size() == 0 && strings.Contains(info.Name(), "none") {
+	if !isArchive(path) || info.IsDir() {
+		return nil
+	}
+
+	// Skip archives that an application server extracted next to themselves, the extracted copy is counted
+	if ext := filepath.Ext(path); ext != ".jar" {
+		if d, err := os.Stat(strings.TrimSuffix(path, ext)); err == nil && d.IsDir() {
 			return nil
 		}
+	}
 
-		z, err := zip.OpenReader(path)
-		if err != nil {
-			if !(errors.Is(err, zip.ErrFormat)) {
-				return fmt.Errorf("unable to open Jar %s\n%w", path, err)
-			} else {
-				return nil
-			}
+	// Check for zero byte JAR files with name containing 'none' - these can not be unzipped
+	// examples of these were found in the JDK, e.g. svm-none.jar
+	if info.Size() == 0 && strings.Contains(info.Name(), "none") {
+		return nil
+	}
+
+	// Skip jars of an extracted Spring Boot application that are not on its classpath
+	if !s.application.allows(path) {
+		return nil
+	}
+
+	s.archives = append(s.archives, archive{path: path, size: info.Size(), modTime: info.ModTime().UnixNano()})
+	return nil
+}
+
+// jar counts the class files of a jar at the given nesting depth, descending into nested jars up to the depth limit.
+// Nested jars of a Spring Boot executable jar are only counted if they are on its classpath index.
+func (s *scanner) jar(r io.ReaderAt, size int64, depth int) error {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		if errors.Is(err, zip.ErrFormat) {
+			return nil
 		}
-		defer func() { _ = z.Close() }()
+		return err
+	}
 
-		for _, f := range z.File {
-			if strings.HasSuffix(f.FileInfo().Name(), ".jar") {
-				c, err := nestedJarContents(f)
-				if err != nil {
-					return fmt.Errorf("unable to count nested jar\n%w", err)
-				}
-				count += c
-			}
-			count += jarContents(f)
+	cp, err := jarClasspath(z)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range z.File {
+		if jarContents(f) > 0 {
+			s.entry(f.Name)
+		}
+		if err := s.manifest(f); err != nil {
+			return err
 		}
 
-		return nil
-	}); err != nil {
-		return 0, fmt.Errorf("unable to walk %s\n%w", path, err)
+		if !isArchive(f.Name) || !cp.allows(f.Name) {
+			continue
+		}
+		s.libraries.jar(f.Name)
+		if depth >= s.depth {
+			continue
+		}
+		if err := s.nestedJar(r, f, depth+1); err != nil {
+			return fmt.Errorf("unable to count nested jar %s\n%w", f.Name, err)
+		}
 	}
+	return nil
+}
 
-	return count, nil
+// jarClasspath returns the classpath index of a Spring Boot executable jar, nil if the jar has none.
+func jarClasspath(z *zip.Reader) (classpath, error) {
+	for _, f := range z.File {
+		if f.Name != ClasspathIndex {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %s\n%w", ClasspathIndex, err)
+		}
+		defer func() { _ = r.Close() }()
+		return parseClasspathIndex(r)
+	}
+	return nil, nil
 }
 
 // JarClassesFrom counts classes from multiple JAR files, returning count and number of skipped paths
@@ -136,13 +441,21 @@ func jarContents(file *zip.File) int {
 	return count
 }
 
-// nestedJarContents counts class files in nested JAR files
-func nestedJarContents(jarFile *zip.File) (int, error) {
-	count := 0
+// nestedJar counts the class files of a jar nested in the jar read from parent. Stored nested jars, as in Spring Boot
+// executable jars, are read in place. Compressed nested jars are decompressed into memory.
+func (s *scanner) nestedJar(parent io.ReaderAt, f *zip.File, depth int) error {
+	if f.Method == zip.Store {
+		offset, err := f.DataOffset()
+		if err != nil {
+			return fmt.Errorf("unable to locate nested jar\n%w", err)
+		}
+		size := int64(f.UncompressedSize64)
+		return s.jar(io.NewSectionReader(parent, offset, size), size, depth)
+	}
 
-	reader, err := jarFile.Open()
+	reader, err := f.Open()
 	if err != nil {
-		return 0, fmt.Errorf("unable to open nested jar\n%w", err)
+		return fmt.Errorf("unable to open nested jar\n%w", err)
 	}
 	defer func() { _ = reader.Close() }()
 
@@ -152,21 +465,10 @@ func nestedJarContents(jarFile *zip.File) (int, error) {
 	limitedReader := io.LimitReader(reader, maxDecompressSize)
 	size, err := io.Copy(&b, limitedReader)
 	if err != nil {
-		return 0, fmt.Errorf("error copying nested Jar \n%w", err)
+		return fmt.Errorf("error copying nested Jar \n%w", err)
 	}
 	if size >= maxDecompressSize {
-		return 0, fmt.Errorf("nested JAR file too large, potential decompression bomb")
-	}
-	br := bytes.NewReader(b.Bytes())
-	nj, err := zip.NewReader(br, size)
-	if err != nil {
-		if !(errors.Is(err, zip.ErrFormat)) {
-			return 0, fmt.Errorf("error reading nested Jar contents\n%w", err)
-		}
-		return 0, nil
-	}
-	for _, nestedJar := range nj.File {
-		count += jarContents(nestedJar)
+		return fmt.Errorf("nested JAR file too large, potential decompression bomb")
 	}
-	return count, nil
+	return s.jar(bytes.NewReader(b.Bytes()), size, depth)
 }
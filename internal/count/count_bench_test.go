@@ -0,0 +1,82 @@
+//go:build !minimal
+
+package count
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// benchmarkJars writes 400 jars of 200 classes each to a temporary directory.
+func benchmarkJars(b *testing.B) string {
+	dir := b.TempDir()
+	entries := make([]jarEntry, 200)
+	for i := range entries {
+		entries[i] = jarEntry{name: fmt.Sprintf("com/example/C%d.class", i)}
+	}
+	jar := buildJar(b, entries...)
+	for i := range 400 {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("lib-%03d.jar", i)), jar, 0o600); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// benchmarkScan scans the benchmark jars with the given number of workers.
+func benchmarkScan(b *testing.B, workers int) {
+	dir := benchmarkJars(b)
+
+	b.ResetTimer()
+	for range b.N {
+		if _, err := ScanWith(dir, Options{Workers: workers}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// scanSequential scans path like the scanner before archives were read concurrently: every archive is counted into
+// the scanner of the walk, one after the other, without workers and without merging per-archive scans.
+func scanSequential(path string) (Result, error) {
+	s := newScanner(false, DefaultNestedJarDepth)
+	s.root = path
+
+	if err := filepath.Walk(path, s.visit); err != nil {
+		return Result{}, err
+	}
+	for _, a := range s.archives {
+		if err := s.archive(a); err != nil {
+			return Result{}, err
+		}
+	}
+	return s.result(), nil
+}
+
+func BenchmarkScanSequentialBaseline(b *testing.B) {
+	dir := benchmarkJars(b)
+
+	expected, err := ScanWith(dir, Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if r, err := scanSequential(dir); err != nil || r.Classes != expected.Classes {
+		b.Fatalf("Expected the baseline to count %d classes, got %d, %v", expected.Classes, r.Classes, err)
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		if _, err := scanSequential(dir); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScanSerial(b *testing.B) {
+	benchmarkScan(b, 1)
+}
+
+func BenchmarkScanParallel(b *testing.B) {
+	benchmarkScan(b, 0)
+}
@@ -62,6 +62,41 @@ func Classes(dirPath string) (int, error) {
 	return classCount, nil
 }
 
+// Scan counts class files like Classes; the minimal build does not detect libraries
+func Scan(dirPath string) (Result, error) {
+	return ScanWith(dirPath, Options{})
+}
+
+// ScanWith counts class files like Scan; the minimal build does not descend into jars, so only Options.Unique has an
+// effect
+func ScanWith(dirPath string, o Options) (Result, error) {
+	c, err := Classes(dirPath)
+	if err != nil {
+		return Result{}, err
+	}
+	if !o.Unique {
+		return Result{Classes: c}, nil
+	}
+
+	names := classNames{}
+	err = filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip errors in minimal mode
+		}
+
+		if strings.HasSuffix(strings.ToLower(info.Name()), ".class") {
+			if rel, err := filepath.Rel(dirPath, path); err == nil {
+				names.add(filepath.ToSlash(rel))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to walk %s\n%w", dirPath, err)
+	}
+	return Result{Classes: c, UniqueClasses: len(names), names: names}, nil
+}
+
 // JarClasses estimates class count based on file size (minimal implementation)
 func JarClasses(path string) (int, error) {
 	fileInfo, err := os.Stat(path)
@@ -102,6 +137,11 @@ func estimateModuleClasses(modulesFile string) (int, error) {
 	return int(size / 100), nil // 10 classes per 100 bytes
 }
 
+// jmodClasses is not supported in the minimal build, which has no ZIP support
+func jmodClasses(path string) (int, error) {
+	return 0, fmt.Errorf("unable to count classes of %s: jmod files are not supported in the minimal build", path)
+}
+
 // jarContents is a minimal placeholder for tests (always returns 0)
 func jarContents(interface{}) int {
 	return 0 // Minimal implementation doesn't process ZIP contents
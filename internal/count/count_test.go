@@ -2,8 +2,12 @@ package count
 
 import (
 	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -513,3 +517,83 @@ func TestEdgeCases(t *testing.T) {
 		}
 	})
 }
+
+// corruptJar returns a jar holding a compressed nested jar that fails its checksum when read.
+func corruptJar(t testing.TB) []byte {
+	t.Helper()
+
+	var c bytes.Buffer
+	w, _ := flate.NewWriter(&c, flate.DefaultCompression)
+	_, _ = w.Write([]byte("not a jar"))
+	_ = w.Close()
+
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+	h := &zip.FileHeader{
+		Name: "lib.jar", Method: zip.Deflate, CRC32: 1,
+		CompressedSize64: uint64(c.Len()), UncompressedSize64: uint64(len("not a jar")),
+	}
+	r, err := z.CreateRaw(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = r.Write(c.Bytes())
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestScanWorkersDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 20 {
+		jar := buildJar(t,
+			jarEntry{name: fmt.Sprintf("com/example/C%d.class", i)},
+			jarEntry{name: "com/example/Shared.class"},
+			jarEntry{name: fmt.Sprintf("scripts/s%d.groovy", i%3)},
+		)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("lib-%02d.jar", i)), jar, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ScanWith(dir, Options{Workers: 1, Unique: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected.Classes != 60 || expected.UniqueClasses != 24 {
+		t.Errorf("Expected 60 class files and 24 unique classes, got %d and %d", expected.Classes, expected.UniqueClasses)
+	}
+
+	for _, workers := range []int{2, 8, 64} {
+		r, err := ScanWith(dir, Options{Workers: workers, Unique: true})
+		if err != nil {
+			t.Fatalf("Unexpected error with %d workers: %v", workers, err)
+		}
+		if !reflect.DeepEqual(r, expected) {
+			t.Errorf("Expected %+v with %d workers, got %+v", expected, workers, r)
+		}
+	}
+}
+
+func TestScanAggregatesArchiveErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.jar", "c.jar"} {
+		if err := os.WriteFile(filepath.Join(dir, name), corruptJar(t), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.jar"), buildJar(t, jarEntry{name: "B.class"}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ScanWith(dir, Options{Workers: 2})
+	if err == nil {
+		t.Fatal("Expected error for corrupt jars")
+	}
+	for _, name := range []string{"a.jar", "c.jar"} {
+		if !strings.Contains(err.Error(), filepath.Join(dir, name)) {
+			t.Errorf("Expected error to name %s, got %v", name, err)
+		}
+	}
+}
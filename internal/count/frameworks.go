@@ -0,0 +1,106 @@
+package count
+
+import (
+	"sort"
+	"strings"
+)
+
+// ManifestPath is the path of the manifest inside a jar.
+const ManifestPath = "META-INF/MANIFEST.MF"
+
+// Framework describes a framework that changes how many of the scanned classes are loaded at runtime.
+type Framework struct {
+	// Name identifies the framework, e.g. "spring-boot".
+	Name string
+
+	// Markers are class files that identify the framework, e.g. its main entry point.
+	Markers []string
+
+	// Attribute is a manifest attribute that identifies the framework, e.g. "Spring-Boot-Version". Empty if the
+	// framework is only detected by its marker classes.
+	Attribute string
+
+	// AttributePrefix restricts Attribute to values with this prefix. Empty matches any value.
+	AttributePrefix string
+}
+
+// Frameworks lists the frameworks detected while scanning.
+var Frameworks = []Framework{
+	{
+		Name:      "spring-boot",
+		Markers:   []string{"org/springframework/boot/SpringApplication.class"},
+		Attribute: "Spring-Boot-Version",
+	},
+	{
+		Name:    "quarkus",
+		Markers: []string{"io/quarkus/runtime/Quarkus.class", "io/quarkus/runtime/Application.class"},
+	},
+	{
+		Name:    "micronaut",
+		Markers: []string{"io/micronaut/runtime/Micronaut.class"},
+	},
+	{
+		Name: "jakarta-ee",
+		Markers: []string{
+			"jakarta/enterprise/inject/spi/CDI.class", "jakarta/ejb/Stateless.class", "javax/ejb/Stateless.class",
+		},
+	},
+	{
+		Name:            "hibernate",
+		Markers:         []string{"org/hibernate/SessionFactory.class"},
+		Attribute:       "Automatic-Module-Name",
+		AttributePrefix: "org.hibernate.orm",
+	},
+	{
+		Name:            "groovy",
+		Markers:         []string{"groovy/lang/GroovyObject.class"},
+		Attribute:       "Automatic-Module-Name",
+		AttributePrefix: "org.codehaus.groovy",
+	},
+}
+
+// frameworks collects the frameworks found while scanning.
+type frameworks map[string]bool
+
+// class records the framework a class file identifies, judged by the class file's path. Prefixes such as
+// BOOT-INF/classes/ or relocation packages of shaded copies are ignored.
+func (d frameworks) class(name string) {
+	n := strings.ReplaceAll(name, "\\", "/")
+	for _, f := range Frameworks {
+		for _, m := range f.Markers {
+			if n == m || strings.HasSuffix(n, "/"+m) {
+				d[f.Name] = true
+			}
+		}
+	}
+}
+
+// manifest records the frameworks identified by the attributes of a manifest.
+func (d frameworks) manifest(content string) {
+	for _, line := range strings.Split(content, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		for _, f := range Frameworks {
+			if f.Attribute != "" && strings.EqualFold(name, f.Attribute) && strings.HasPrefix(value, f.AttributePrefix) {
+				d[f.Name] = true
+			}
+		}
+	}
+}
+
+// names returns the detected framework names in sorted order.
+func (d frameworks) names() []string {
+	if len(d) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(d))
+	for n := range d {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
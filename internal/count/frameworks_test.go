@@ -0,0 +1,67 @@
+package count
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScanDetectsFrameworksByMarkerClass(t *testing.T) {
+	dir := t.TempDir()
+	writeJar(t, filepath.Join(dir, "app.jar"),
+		"BOOT-INF/classes/com/example/App.class",
+		"BOOT-INF/lib/org/springframework/boot/SpringApplication.class",
+		"org/hibernate/SessionFactory.class")
+
+	r, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"hibernate", "spring-boot"}
+	if !reflect.DeepEqual(r.Frameworks, expected) {
+		t.Errorf("Expected frameworks %v, got %v", expected, r.Frameworks)
+	}
+}
+
+func TestScanDetectsFrameworksByManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	//nolint:gosec // Safe in tests
+	f, err := os.Create(filepath.Join(dir, "app.jar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	w, err := z.Create(ManifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("Manifest-Version: 1.0\r\nSpring-Boot-Version: 3.2.0\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	r, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Frameworks, []string{"spring-boot"}) {
+		t.Errorf("Expected spring-boot from manifest, got %v", r.Frameworks)
+	}
+}
+
+func TestFrameworksManifest(t *testing.T) {
+	d := frameworks{}
+	d.manifest("Automatic-Module-Name: org.hibernate.orm.core\nImplementation-Title: other\n")
+	d.manifest("Automatic-Module-Name: org.apache.commons.lang3\n")
+
+	if !reflect.DeepEqual(d.names(), []string{"hibernate"}) {
+		t.Errorf("Expected hibernate from module name, got %v", d.names())
+	}
+}
@@ -0,0 +1,177 @@
+package count
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// JImageMagic identifies a jimage file, the lib/modules file of a Java 9+ runtime or jlink image. It is written in
+// the byte order of the platform the image was built for.
+const JImageMagic = 0xCAFEDADA
+
+// JImageMajorVersion is the major version of the jimage format that can be read.
+const JImageMajorVersion = 1
+
+// jimageHeaderSize is the size of the jimage header: magic, version, flags, resource count, table length, locations
+// size and strings size, each a 32-bit integer.
+const jimageHeaderSize = 7 * 4
+
+// Kinds of the attributes of a jimage location, in the order defined by jdk.internal.jimage.ImageLocation.
+const (
+	attributeEnd = iota
+	attributeModule
+	attributeParent
+	attributeBase
+	attributeExtension
+	attributeCount = 8
+)
+
+// JmodMagic starts every jmod file, followed by a zip archive holding the classes of the module in classes/.
+var JmodMagic = []byte{'J', 'M', 1, 0}
+
+// ErrNotJImage is returned by ModuleClasses if the file does not start with JImageMagic.
+var ErrNotJImage = errors.New("not a jimage file")
+
+// ModuleClasses returns the number of classes per module in the jimage at path. Only the index of the jimage is read,
+// module-info classes are not counted.
+func ModuleClasses(path string) (map[string]int, error) {
+	// #nosec G304 - the path is in the Java home chosen by the user
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("unable to stat %s\n%w", path, err)
+	}
+
+	h := make([]byte, jimageHeaderSize)
+	if _, err := io.ReadFull(f, h); err != nil {
+		return nil, fmt.Errorf("%w: %s is too small", ErrNotJImage, path)
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(h) != JImageMagic {
+		order = binary.BigEndian
+		if order.Uint32(h) != JImageMagic {
+			return nil, fmt.Errorf("%w: %s", ErrNotJImage, path)
+		}
+	}
+	if major := order.Uint32(h[4:]) >> 16; major != JImageMajorVersion {
+		return nil, fmt.Errorf("unsupported jimage version %d of %s, expected %d", major, path, JImageMajorVersion)
+	}
+
+	tableLength := int64(order.Uint32(h[16:]))
+	locationsSize := int64(order.Uint32(h[20:]))
+	stringsSize := int64(order.Uint32(h[24:]))
+	if jimageHeaderSize+2*4*tableLength+locationsSize+stringsSize > info.Size() {
+		return nil, fmt.Errorf("jimage index of %s exceeds its size", path)
+	}
+
+	// The redirect table is followed by the offsets table, the locations and the strings
+	index := make([]byte, 4*tableLength+locationsSize+stringsSize)
+	if _, err := f.ReadAt(index, jimageHeaderSize+4*tableLength); err != nil {
+		return nil, fmt.Errorf("unable to read jimage index of %s\n%w", path, err)
+	}
+	offsets := index[:4*tableLength]
+	locations := index[4*tableLength : 4*tableLength+locationsSize]
+	strs := index[4*tableLength+locationsSize:]
+
+	modules := map[string]int{}
+	for i := int64(0); i < tableLength; i++ {
+		offset := int64(order.Uint32(offsets[4*i:]))
+		if offset >= locationsSize {
+			return nil, fmt.Errorf("jimage location %d of %s is out of bounds", i, path)
+		}
+
+		a, err := locationAttributes(locations[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode jimage location %d of %s\n%w", i, path, err)
+		}
+		if jimageString(strs, a[attributeExtension]) != "class" ||
+			jimageString(strs, a[attributeBase]) == "module-info" {
+			continue
+		}
+		modules[jimageString(strs, a[attributeModule])]++
+	}
+	return modules, nil
+}
+
+// locationAttributes decodes the attributes of a jimage location. Each attribute starts with a byte holding the kind
+// in the upper five bits and the length of the value minus one in the lower three bits, followed by the value in big
+// endian order. The attributes end with a byte of kind attributeEnd.
+func locationAttributes(b []byte) ([attributeCount]uint64, error) {
+	var a [attributeCount]uint64
+	for i := 0; i < len(b); i++ {
+		kind := int(b[i] >> 3)
+		if kind == attributeEnd {
+			return a, nil
+		}
+		if kind >= attributeCount {
+			return a, fmt.Errorf("invalid attribute kind %d", kind)
+		}
+
+		length := int(b[i]&0x7) + 1
+		if i+length >= len(b) {
+			return a, fmt.Errorf("attribute exceeds locations")
+		}
+		var v uint64
+		for j := 0; j < length; j++ {
+			i++
+			v = v<<8 | uint64(b[i])
+		}
+		a[kind] = v
+	}
+	return a, fmt.Errorf("missing end of attributes")
+}
+
+// jimageString returns the zero terminated string at offset of the jimage strings.
+func jimageString(strs []byte, offset uint64) string {
+	if offset >= uint64(len(strs)) {
+		return ""
+	}
+	s := strs[offset:]
+	if i := bytes.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return string(s)
+}
+
+// JVMClasses counts the classes of the Java runtime at javaHome: the classes in lib/modules of a Java 9+ runtime or
+// jlink image, or those in the jmod files of jmods/ if there is no lib/modules. The error wraps fs.ErrNotExist if the
+// runtime has neither, e.g. a Java 8 runtime.
+func JVMClasses(javaHome string) (int, error) {
+	modules, err := ModuleClasses(filepath.Join(javaHome, "lib", "modules"))
+	if err == nil {
+		n := 0
+		for _, c := range modules {
+			n += c
+		}
+		return n, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+
+	jmods, _ := filepath.Glob(filepath.Join(javaHome, "jmods", "*.jmod"))
+	if len(jmods) == 0 {
+		return 0, err
+	}
+
+	n := 0
+	for _, j := range jmods {
+		c, err := jmodClasses(j)
+		if err != nil {
+			return 0, err
+		}
+		n += c
+	}
+	return n, nil
+}
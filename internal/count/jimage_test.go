@@ -0,0 +1,183 @@
+package count
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildJImage returns a jimage in the given byte order holding the given resources, named like
+// "/java.base/java/lang/Object.class".
+func buildJImage(t *testing.T, order binary.ByteOrder, resources ...string) []byte {
+	t.Helper()
+
+	strs := []byte{0}
+	offsets := map[string]uint64{"": 0}
+	str := func(s string) uint64 {
+		if o, ok := offsets[s]; ok {
+			return o
+		}
+		offsets[s] = uint64(len(strs))
+		strs = append(append(strs, s...), 0)
+		return offsets[s]
+	}
+
+	var locations []byte
+	var table []uint32
+	for _, r := range resources {
+		module, rest, _ := strings.Cut(strings.TrimPrefix(r, "/"), "/")
+		parent, name := "", rest
+		if i := strings.LastIndex(rest, "/"); i >= 0 {
+			parent, name = rest[:i], rest[i+1:]
+		}
+		base, ext := name, ""
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			base, ext = name[:i], name[i+1:]
+		}
+
+		table = append(table, uint32(len(locations)))
+		for kind, v := range []string{attributeModule: module, attributeParent: parent, attributeBase: base,
+			attributeExtension: ext} {
+			if kind == attributeEnd || v == "" {
+				continue
+			}
+			// Two byte values, big endian
+			o := str(v)
+			locations = append(locations, byte(kind<<3|1), byte(o>>8), byte(o))
+		}
+		locations = append(locations, attributeEnd)
+	}
+
+	var b bytes.Buffer
+	for _, v := range []uint32{
+		JImageMagic, JImageMajorVersion << 16, 0, uint32(len(table)), uint32(len(table)),
+		uint32(len(locations)), uint32(len(strs)),
+	} {
+		_ = binary.Write(&b, order, v)
+	}
+	for range table {
+		_ = binary.Write(&b, order, int32(0))
+	}
+	for _, o := range table {
+		_ = binary.Write(&b, order, o)
+	}
+	b.Write(locations)
+	b.Write(strs)
+	// Resource content follows the index
+	b.Write(make([]byte, 64))
+	return b.Bytes()
+}
+
+func TestModuleClasses(t *testing.T) {
+	resources := []string{
+		"/java.base/java/lang/Object.class",
+		"/java.base/java/lang/String.class",
+		"/java.base/module-info.class",
+		"/java.base/java/lang/uniName.dat",
+		"/java.logging/java/util/logging/Logger.class",
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "modules")
+			if err := os.WriteFile(path, buildJImage(t, order, resources...), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			modules, err := ModuleClasses(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expected := map[string]int{"java.base": 2, "java.logging": 1}
+			if !reflect.DeepEqual(modules, expected) {
+				t.Errorf("Expected %v, got %v", expected, modules)
+			}
+		})
+	}
+}
+
+func TestModuleClassesInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	zeros := filepath.Join(dir, "zeros")
+	if err := os.WriteFile(zeros, make([]byte, 1000), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ModuleClasses(zeros); !errors.Is(err, ErrNotJImage) {
+		t.Errorf("Expected ErrNotJImage, got %v", err)
+	}
+
+	truncated := filepath.Join(dir, "truncated")
+	b := buildJImage(t, binary.LittleEndian, "/java.base/java/lang/Object.class")
+	if err := os.WriteFile(truncated, b[:40], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ModuleClasses(truncated); err == nil {
+		t.Error("Expected error for truncated jimage")
+	}
+
+	version := filepath.Join(dir, "version")
+	binary.LittleEndian.PutUint32(b[4:], 2<<16)
+	if err := os.WriteFile(version, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ModuleClasses(version); err == nil || errors.Is(err, ErrNotJImage) {
+		t.Errorf("Expected unsupported version error, got %v", err)
+	}
+}
+
+func TestScanJImage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "lib"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	b := buildJImage(t, binary.LittleEndian, "/java.base/java/lang/Object.class", "/java.sql/java/sql/Driver.class")
+	if err := os.WriteFile(filepath.Join(dir, "lib", "modules"), b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := Classes(dir); err != nil || c != 2 {
+		t.Errorf("Expected 2 classes of the jimage, got %d, %v", c, err)
+	}
+	if c, err := JVMClasses(dir); err != nil || c != 2 {
+		t.Errorf("Expected 2 JVM classes, got %d, %v", c, err)
+	}
+}
+
+func TestJVMClassesFromJmods(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "jmods"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	b.Write(JmodMagic)
+	z := zip.NewWriter(&b)
+	for _, name := range []string{"classes/module-info.class", "classes/java/lang/Object.class",
+		"classes/java/lang/String.class", "lib/libjava.so", "conf/security/java.policy"} {
+		if _, err := z.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "jmods", "java.base.jmod"), b.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := JVMClasses(dir); err != nil || c != 2 {
+		t.Errorf("Expected 2 classes of the jmod, got %d, %v", c, err)
+	}
+
+	if _, err := JVMClasses(t.TempDir()); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist for a runtime without modules, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build !minimal
+
+package count
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// jmodClasses counts the classes of a jmod file, without module-info.
+func jmodClasses(path string) (int, error) {
+	// #nosec G304 - the path is in the Java home chosen by the user
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("unable to stat %s\n%w", path, err)
+	}
+
+	magic := make([]byte, len(JmodMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || !bytes.Equal(magic, JmodMagic) {
+		return 0, fmt.Errorf("%s is not a jmod file", path)
+	}
+
+	size := info.Size() - int64(len(JmodMagic))
+	z, err := zip.NewReader(io.NewSectionReader(f, int64(len(JmodMagic)), size), size)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	n := 0
+	for _, e := range z.File {
+		if strings.HasPrefix(e.Name, "classes/") && strings.HasSuffix(e.Name, ".class") &&
+			e.Name != "classes/module-info.class" {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,102 @@
+package count
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Languages lists the script languages whose entries are counted as classes, keyed by file extension. Each script
+// usually compiles into several classes at runtime, which LanguageWeights can account for.
+var Languages = map[string]string{
+	".clj":    "clj",
+	".groovy": "groovy",
+	".kts":    "kts",
+}
+
+// LanguageWeights holds the number of classes a single script entry counts as, keyed by language. Languages
+// without a weight count as one class per entry.
+type LanguageWeights map[string]float64
+
+// ParseLanguageWeights parses a comma-separated list of language weights, e.g. "groovy=3,clj=4,kts=2".
+func ParseLanguageWeights(s string) (LanguageWeights, error) {
+	w := LanguageWeights{}
+	for _, e := range strings.Split(s, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(e, "=")
+		if !ok {
+			return nil, fmt.Errorf("language weight %q must have the form <language>=<weight>", e)
+		}
+
+		name = strings.TrimPrefix(strings.TrimSpace(name), ".")
+		if !isLanguage(name) {
+			return nil, fmt.Errorf("unknown language %q, supported languages: %s", name, strings.Join(languageNames(), ", "))
+		}
+
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || f < 0 {
+			return nil, fmt.Errorf("weight of language %s must be a non-negative number, got %q", name, value)
+		}
+		w[name] = f
+	}
+	return w, nil
+}
+
+// Weighted returns the number of classes of the result, the unique classes if counted, with script entries counted
+// according to the weights.
+func (r Result) Weighted(w LanguageWeights) float64 {
+	classes := float64(r.Classes)
+	if r.UniqueClasses > 0 {
+		classes = float64(r.UniqueClasses)
+	}
+	for l, c := range r.Languages {
+		if f, ok := w[l]; ok {
+			classes += (f - 1) * float64(c)
+		}
+	}
+	return classes
+}
+
+// languages counts the script entries found while scanning, keyed by language.
+type languages map[string]int
+
+// entry records a counted entry if it is a script.
+func (l languages) entry(name string) {
+	if lang, ok := Languages[path.Ext(name)]; ok {
+		l[lang]++
+	}
+}
+
+// counts returns the script counts, or nil if no scripts were found.
+func (l languages) counts() map[string]int {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// isLanguage returns true if name is a supported script language.
+func isLanguage(name string) bool {
+	for _, l := range Languages {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
+
+// languageNames returns the supported script languages in sorted order.
+func languageNames() []string {
+	names := make([]string, 0, len(Languages))
+	for _, l := range Languages {
+		names = append(names, l)
+	}
+	sort.Strings(names)
+	return names
+}
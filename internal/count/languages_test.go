@@ -0,0 +1,43 @@
+package count
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLanguageWeights(t *testing.T) {
+	w, err := ParseLanguageWeights("groovy=3, .clj=4,kts=2.5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if w["groovy"] != 3 || w["clj"] != 4 || w["kts"] != 2.5 {
+		t.Errorf("Expected weights for groovy, clj and kts, got %v", w)
+	}
+
+	for _, s := range []string{"groovy", "scala=2", "groovy=-1", "groovy=lots"} {
+		if _, err := ParseLanguageWeights(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestScanCountsLanguages(t *testing.T) {
+	dir := t.TempDir()
+	writeJar(t, filepath.Join(dir, "app.jar"),
+		"com/example/App.class", "scripts/build.groovy", "scripts/deploy.groovy", "core.clj")
+
+	r, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Classes != 4 || r.Languages["groovy"] != 2 || r.Languages["clj"] != 1 {
+		t.Errorf("Expected 4 classes with 2 groovy and 1 clj entries, got %+v", r)
+	}
+
+	if c := r.Weighted(LanguageWeights{"groovy": 3}); c != 8 {
+		t.Errorf("Expected 8 weighted classes, got %g", c)
+	}
+	if c := r.Weighted(nil); c != 4 {
+		t.Errorf("Expected 4 classes without weights, got %g", c)
+	}
+}
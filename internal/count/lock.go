@@ -0,0 +1,121 @@
+package count
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LockFile is the name of the lock file in the application path that the class counts are read from at runtime.
+const LockFile = ".memory-calculator.json"
+
+// LockVersion is the version of the lock file format. Lock files of other versions are ignored.
+const LockVersion = 1
+
+// Lock records the class counts of an application computed ahead of time, e.g. when building a container image, so
+// that the application does not need to be scanned when the container starts.
+type Lock struct {
+	Version int `json:"version"`
+
+	// Fingerprint identifies the scanned files by path, size and modification time, see Fingerprint.
+	Fingerprint string `json:"fingerprint"`
+
+	// NestedJarDepth and Unique are the options the application was scanned with.
+	NestedJarDepth int  `json:"nestedJarDepth"`
+	Unique         bool `json:"unique,omitempty"`
+
+	// Application holds the classes counted in the application path and application server deployments.
+	Application Result `json:"application"`
+
+	// Agents is the number of classes in agent jars.
+	Agents int `json:"agents"`
+
+	// JVM is the number of classes of the JVM.
+	JVM int `json:"jvm"`
+}
+
+// ReadLock reads the lock file at path, returning nil if there is none.
+func ReadLock(path string) (*Lock, error) {
+	// #nosec G304 - the lock path is chosen by the user
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	var l Lock
+	if err := json.Unmarshal(b, &l); err != nil {
+		return nil, fmt.Errorf("unable to decode %s\n%w", path, err)
+	}
+	if l.Version != LockVersion {
+		return nil, fmt.Errorf("unsupported version %d of %s, expected %d", l.Version, path, LockVersion)
+	}
+	return &l, nil
+}
+
+// Write writes the lock file to path.
+func (l Lock) Write(path string) error {
+	l.Version = LockVersion
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode class counts\n%w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil { // #nosec G306 - read by the application user
+		return fmt.Errorf("unable to write %s\n%w", path, err)
+	}
+	return nil
+}
+
+// Fingerprint returns a digest of the path, size and modification time of the class files and archives in the given
+// paths, which may be directories or files. Paths that do not exist are part of the fingerprint as missing. File
+// contents are not read, so computing the fingerprint is much cheaper than scanning. Lock files are ignored.
+func Fingerprint(paths ...string) (string, error) {
+	var files []string
+	for _, p := range paths {
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			files = append(files, fmt.Sprintf("%s missing", p))
+			continue
+		}
+
+		err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || info.Name() == LockFile || !isCounted(path) {
+				return nil
+			}
+			files = append(files, fmt.Sprintf("%s %d %d", path, info.Size(), info.ModTime().UnixNano()))
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("unable to walk %s\n%w", p, err)
+		}
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, f := range files {
+		_, _ = fmt.Fprintln(h, f)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isCounted returns true if the file at path is a class file, an archive or a Spring Boot index.
+func isCounted(path string) bool {
+	p := filepath.ToSlash(path)
+	if isArchive(p) || strings.HasSuffix(p, ClasspathIndex) || strings.HasSuffix(p, LayersIndex) {
+		return true
+	}
+	for _, e := range ClassExtensions {
+		if strings.HasSuffix(p, e) {
+			return true
+		}
+	}
+	return false
+}
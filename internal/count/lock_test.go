@@ -0,0 +1,95 @@
+package count
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLockWriteAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFile)
+	expected := Lock{
+		Version:        LockVersion,
+		Fingerprint:    "abc",
+		NestedJarDepth: 2,
+		Application:    Result{Classes: 1200, Frameworks: []string{"spring-boot"}},
+		Agents:         300,
+		JVM:            1000,
+	}
+	if err := expected.Write(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	l, err := ReadLock(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*l, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, *l)
+	}
+}
+
+func TestReadLockInvalid(t *testing.T) {
+	if l, err := ReadLock(filepath.Join(t.TempDir(), LockFile)); l != nil || err != nil {
+		t.Errorf("Expected no lock for a missing file, got %+v, %v", l, err)
+	}
+
+	for _, content := range []string{"{not json", `{"version":99}`} {
+		path := filepath.Join(t.TempDir(), LockFile)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadLock(path); err == nil {
+			t.Errorf("Expected error for %q", content)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	jar := filepath.Join(dir, "lib", "a.jar")
+	if err := os.MkdirAll(filepath.Dir(jar), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{jar, filepath.Join(dir, "App.class"), filepath.Join(dir, "README.md")} {
+		if err := os.WriteFile(f, []byte("content"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	agent := filepath.Join(t.TempDir(), "agent.jar")
+
+	f, err := Fingerprint(dir, agent)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Files that are not counted and the lock file itself do not change the fingerprint
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("changed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := (Lock{Fingerprint: f}).Write(filepath.Join(dir, LockFile)); err != nil {
+		t.Fatal(err)
+	}
+	if g, _ := Fingerprint(dir, agent); g != f {
+		t.Error("Expected fingerprint to ignore files that are not counted")
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(jar, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if g, _ := Fingerprint(dir, agent); g == f {
+		t.Error("Expected fingerprint to change with a modified jar")
+	}
+
+	// A missing path that appears changes the fingerprint
+	f, _ = Fingerprint(dir, agent)
+	if err := os.WriteFile(agent, []byte("agent"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if g, _ := Fingerprint(dir, agent); g == f {
+		t.Error("Expected fingerprint to change with an added agent")
+	}
+}
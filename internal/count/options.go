@@ -0,0 +1,50 @@
+package count
+
+import "runtime"
+
+// Class count modes selectable in the configuration.
+const (
+	// RawMode counts every class file found.
+	RawMode = "raw"
+
+	// UniqueMode additionally counts the distinct fully-qualified class names found.
+	UniqueMode = "unique"
+)
+
+// DefaultNestedJarDepth is the default number of levels of nested jars a scan descends into.
+const DefaultNestedJarDepth = 3
+
+// Options configures a scan.
+type Options struct {
+	// NestedJarDepth is the number of levels of nested jars to descend into, e.g. 1 to count the jars in
+	// BOOT-INF/lib/ of a Spring Boot executable jar but not jars nested in those. Default: DefaultNestedJarDepth
+	// when zero.
+	NestedJarDepth int
+
+	// Workers is the number of archives read concurrently. Default: runtime.GOMAXPROCS when zero. Since Go 1.25,
+	// which go.mod requires, GOMAXPROCS follows the CPU quota of the container unless GODEBUG=containermaxprocs=0;
+	// older toolchains use the number of host CPUs.
+	Workers int
+
+	// Cache holds the counts of previously scanned archives, nil to scan all archives.
+	Cache *Cache
+
+	// Unique counts each fully-qualified class name once, see Result.UniqueClasses.
+	Unique bool
+}
+
+// nestedJarDepth returns the nested jar depth, falling back to DefaultNestedJarDepth.
+func (o Options) nestedJarDepth() int {
+	if o.NestedJarDepth > 0 {
+		return o.NestedJarDepth
+	}
+	return DefaultNestedJarDepth
+}
+
+// workers returns the number of archives read concurrently, falling back to runtime.GOMAXPROCS.
+func (o Options) workers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
@@ -0,0 +1,142 @@
+package count
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Result holds the outcome of scanning a path for classes.
+type Result struct {
+	// Classes is the number of class files found.
+	Classes int `json:"classes"`
+
+	// UniqueClasses is the number of distinct fully-qualified class names found when scanning with Options.Unique,
+	// zero otherwise. Multi-release variants and copies of a class in several jars count once, module-info and
+	// package-info are not counted.
+	UniqueClasses int `json:"uniqueClasses,omitempty"`
+
+	// Libraries lists the off-heap heavy libraries detected while scanning, sorted by name.
+	Libraries []string `json:"libraries,omitempty"`
+
+	// Languages holds the number of counted script entries per language, e.g. "groovy" for .groovy
+	// entries. Classes already includes them. It is nil if no scripts were found.
+	Languages map[string]int `json:"languages,omitempty"`
+
+	// Frameworks lists the frameworks detected while scanning, sorted by name.
+	Frameworks []string `json:"frameworks,omitempty"`
+
+	// names holds the distinct class names found when scanning with Options.Unique, so that merged results count a
+	// class found in both once.
+	names classNames
+}
+
+// Library describes a well-known library that allocates significant direct (off-heap) memory.
+type Library struct {
+	// Name identifies the library, e.g. "netty-buffer".
+	Name string
+
+	// JarPrefix is the file name prefix of the library's jar, e.g. "netty-buffer-".
+	JarPrefix string
+
+	// Marker is a class file that identifies the library when it is shaded or exploded.
+	Marker string
+}
+
+// OffHeapLibraries lists the libraries detected while scanning because they need more direct memory than the JVM
+// default allows for.
+var OffHeapLibraries = []Library{
+	{Name: "netty-buffer", JarPrefix: "netty-buffer-", Marker: "io/netty/buffer/PooledByteBufAllocator.class"},
+	{Name: "grpc-netty", JarPrefix: "grpc-netty-", Marker: "io/grpc/netty/NettyServerBuilder.class"},
+	{
+		Name: "kafka-clients", JarPrefix: "kafka-clients-",
+		Marker: "org/apache/kafka/clients/producer/KafkaProducer.class",
+	},
+	{Name: "lucene", JarPrefix: "lucene-core-", Marker: "org/apache/lucene/store/MMapDirectory.class"},
+	{Name: "chronicle", JarPrefix: "chronicle-", Marker: "net/openhft/chronicle/bytes/Bytes.class"},
+}
+
+// detector collects the off-heap libraries found while scanning.
+type detector map[string]bool
+
+// jar records the library a jar belongs to, judged by the jar's file name.
+func (d detector) jar(name string) {
+	base := path.Base(strings.ReplaceAll(name, "\\", "/"))
+	for _, l := range OffHeapLibraries {
+		if strings.HasPrefix(base, l.JarPrefix) {
+			d[l.Name] = true
+		}
+	}
+}
+
+// class records the library a class file belongs to, judged by the class file's path. Prefixes such as
+// BOOT-INF/classes/ or relocation packages of shaded copies are ignored.
+func (d detector) class(name string) {
+	n := strings.ReplaceAll(name, "\\", "/")
+	for _, l := range OffHeapLibraries {
+		if n == l.Marker || strings.HasSuffix(n, "/"+l.Marker) {
+			d[l.Name] = true
+		}
+	}
+}
+
+// names returns the detected library names in sorted order.
+func (d detector) names() []string {
+	if len(d) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(d))
+	for n := range d {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Merge returns the combined result of r and o, e.g. of an application and the application server it is
+// deployed to. If both were scanned with Options.Unique, a class found in both counts as one unique class.
+func (r Result) Merge(o Result) Result {
+	m := Result{Classes: r.Classes + o.Classes, UniqueClasses: r.UniqueClasses + o.UniqueClasses}
+
+	m.Libraries = mergeNames(r.Libraries, o.Libraries)
+	m.Frameworks = mergeNames(r.Frameworks, o.Frameworks)
+
+	if r.names != nil && o.names != nil {
+		m.names = classNames{}
+		for _, c := range []classNames{r.names, o.names} {
+			for n, p := range c {
+				if _, ok := m.names[n]; !ok {
+					m.names[n] = p
+				}
+			}
+		}
+		m.UniqueClasses = len(m.names)
+
+		l := languages{}
+		for _, p := range m.names {
+			l.entry(p)
+		}
+		m.Languages = l.counts()
+		return m
+	}
+
+	for _, l := range []map[string]int{r.Languages, o.Languages} {
+		for k, v := range l {
+			if m.Languages == nil {
+				m.Languages = map[string]int{}
+			}
+			m.Languages[k] += v
+		}
+	}
+	return m
+}
+
+// mergeNames returns the sorted union of the given names, or nil if there are none.
+func mergeNames(a, b []string) []string {
+	d := detector{}
+	for _, n := range append(append([]string{}, a...), b...) {
+		d[n] = true
+	}
+	return d.names()
+}
@@ -0,0 +1,132 @@
+package count
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// jarEntry is an entry of a jar built by buildJar.
+type jarEntry struct {
+	name    string
+	content []byte
+}
+
+// buildJar returns a jar with the given entries. Nested jars are stored uncompressed like in Spring Boot
+// executable jars.
+func buildJar(t testing.TB, entries ...jarEntry) []byte {
+	t.Helper()
+
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+	for _, e := range entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if strings.HasSuffix(e.name, ".jar") {
+			h.Method = zip.Store
+		}
+		w, err := z.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := e.content
+		if content == nil {
+			content = []byte("fake class content")
+		}
+		if _, err := w.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// writeJar writes a jar with entries of the given names to path.
+func writeJar(t testing.TB, path string, names ...string) {
+	t.Helper()
+
+	entries := make([]jarEntry, 0, len(names))
+	for _, n := range names {
+		entries = append(entries, jarEntry{name: n})
+	}
+	if err := os.WriteFile(path, buildJar(t, entries...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanDetectsLibrariesByJarName(t *testing.T) {
+	dir := t.TempDir()
+	writeJar(t, filepath.Join(dir, "netty-buffer-4.1.100.Final.jar"), "io/netty/buffer/ByteBuf.class")
+	writeJar(t, filepath.Join(dir, "kafka-clients-3.6.0.jar"), "org/apache/kafka/common/Node.class")
+	writeJar(t, filepath.Join(dir, "commons-lang3-3.14.0.jar"), "org/apache/commons/lang3/StringUtils.class")
+
+	r, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Classes != 3 {
+		t.Errorf("Expected 3 classes, got %d", r.Classes)
+	}
+	if expected := []string{"kafka-clients", "netty-buffer"}; !reflect.DeepEqual(r.Libraries, expected) {
+		t.Errorf("Expected libraries %v, got %v", expected, r.Libraries)
+	}
+}
+
+func TestScanDetectsShadedLibrariesByMarkerClass(t *testing.T) {
+	dir := t.TempDir()
+	writeJar(t, filepath.Join(dir, "app.jar"),
+		"com/example/App.class",
+		"com/example/shaded/io/grpc/netty/NettyServerBuilder.class")
+
+	r, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"grpc-netty"}; !reflect.DeepEqual(r.Libraries, expected) {
+		t.Errorf("Expected libraries %v, got %v", expected, r.Libraries)
+	}
+}
+
+func TestScanDetectsExplodedLibraries(t *testing.T) {
+	dir := t.TempDir()
+	classes := filepath.Join(dir, "BOOT-INF", "classes", "org", "apache", "lucene", "store")
+	if err := os.MkdirAll(classes, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(classes, "MMapDirectory.class"), []byte{}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Classes != 1 {
+		t.Errorf("Expected 1 class, got %d", r.Classes)
+	}
+	if expected := []string{"lucene"}; !reflect.DeepEqual(r.Libraries, expected) {
+		t.Errorf("Expected libraries %v, got %v", expected, r.Libraries)
+	}
+}
+
+func TestScanWithoutLibraries(t *testing.T) {
+	dir := t.TempDir()
+	writeJar(t, filepath.Join(dir, "app.jar"), "com/example/App.class")
+
+	r, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Libraries != nil {
+		t.Errorf("Expected no libraries, got %v", r.Libraries)
+	}
+}
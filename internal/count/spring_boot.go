@@ -0,0 +1,160 @@
+package count
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ClasspathIndex is the path of the classpath index of a Spring Boot executable jar. It lists the nested jars
+	// that are on the classpath.
+	ClasspathIndex = "BOOT-INF/classpath.idx"
+
+	// LayersIndex is the path of the layers index of a Spring Boot layered jar. It maps the content of the jar
+	// to the layers it is extracted into.
+	LayersIndex = "BOOT-INF/layers.idx"
+
+	// BootLib is the directory of the nested jars of a Spring Boot executable jar.
+	BootLib = "BOOT-INF/lib/"
+)
+
+// DefaultLayers lists the layers Spring Boot extracts a layered jar into if the jar has no layers index.
+var DefaultLayers = []string{"dependencies", "spring-boot-loader", "snapshot-dependencies", "application"}
+
+// classpath holds the nested jars listed in a classpath index. A nil classpath allows all jars.
+type classpath map[string]bool
+
+// allows returns true if the jar at the given path, relative to the root of the Spring Boot application, is on the
+// classpath. Jars outside BOOT-INF/lib/ are not governed by the classpath index.
+func (c classpath) allows(name string) bool {
+	if c == nil || !strings.HasPrefix(name, BootLib) {
+		return true
+	}
+	return c[name]
+}
+
+// parseClasspathIndex parses the entries of a classpath index, e.g. - "BOOT-INF/lib/spring-core-6.1.1.jar".
+func parseClasspathIndex(r io.Reader) (classpath, error) {
+	c := classpath{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if e := indexEntry(s.Text()); e != "" {
+			c[e] = true
+		}
+	}
+	return c, s.Err()
+}
+
+// parseLayersIndex parses the layer names of a layers index, e.g. - "dependencies": followed by the layer's content
+// indented as - "BOOT-INF/lib/".
+func parseLayersIndex(r io.Reader) ([]string, error) {
+	var layers []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "- ") && strings.HasSuffix(strings.TrimSpace(line), ":") {
+			layers = append(layers, indexEntry(strings.TrimSuffix(strings.TrimSpace(line), ":")))
+		}
+	}
+	return layers, s.Err()
+}
+
+// indexEntry returns the quoted value of an index line, or an empty string if the line has none.
+func indexEntry(line string) string {
+	l := strings.TrimSpace(line)
+	if !strings.HasPrefix(l, "- ") {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(strings.TrimPrefix(l, "- ")), `"`)
+}
+
+// explodedApplication describes a Spring Boot application extracted into a directory, either as exploded jar or
+// as layers.
+type explodedApplication struct {
+	// root is the directory the application was extracted into.
+	root string
+
+	// layers lists the layer directories below root. It is empty for an exploded jar.
+	layers []string
+
+	// classpath holds the nested jars on the classpath, nil if there is no classpath index.
+	classpath classpath
+}
+
+// readExplodedApplication reads the classpath and layers index of a Spring Boot application extracted into root.
+// It returns nil if root contains no Spring Boot application.
+func readExplodedApplication(root string) (*explodedApplication, error) {
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		// A missing root is reported while walking it
+		return nil, nil
+	}
+
+	layers := DefaultLayers
+	for _, p := range []string{filepath.Join(root, LayersIndex), filepath.Join(root, "application", LayersIndex)} {
+		l, err := readIndex(p, parseLayersIndex)
+		if err != nil {
+			return nil, err
+		} else if l != nil {
+			layers = l
+			break
+		}
+	}
+
+	candidates := []string{filepath.Join(root, ClasspathIndex)}
+	for _, l := range layers {
+		candidates = append(candidates, filepath.Join(root, l, ClasspathIndex))
+	}
+	for _, p := range candidates {
+		c, err := readIndex(p, parseClasspathIndex)
+		if err != nil {
+			return nil, err
+		} else if c != nil {
+			a := &explodedApplication{root: root, classpath: c}
+			if filepath.Dir(filepath.Dir(p)) != root {
+				a.layers = layers
+			}
+			return a, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// allows returns true if the jar at path is on the classpath of the application.
+func (a *explodedApplication) allows(path string) bool {
+	if a == nil {
+		return true
+	}
+
+	rel, err := filepath.Rel(a.root, path)
+	if err != nil {
+		return true
+	}
+	rel = filepath.ToSlash(rel)
+	for _, l := range a.layers {
+		if strings.HasPrefix(rel, l+"/") {
+			rel = strings.TrimPrefix(rel, l+"/")
+			break
+		}
+	}
+	return a.classpath.allows(rel)
+}
+
+// readIndex parses the index file at path, returning the zero value if it does not exist.
+func readIndex[T any](path string, parse func(io.Reader) (T, error)) (T, error) {
+	var zero T
+
+	// #nosec G304 - the path is derived from the application path chosen by the user
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return zero, nil
+	} else if err != nil {
+		return zero, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return parse(f)
+}
@@ -0,0 +1,123 @@
+package count
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanSpringBootClasspathIndex(t *testing.T) {
+	dir := t.TempDir()
+	lib := buildJar(t, jarEntry{name: "org/example/Lib.class"})
+	app := buildJar(t,
+		jarEntry{name: "org/springframework/boot/loader/launch/JarLauncher.class"},
+		jarEntry{name: "BOOT-INF/classes/com/example/App.class"},
+		jarEntry{name: "BOOT-INF/lib/used.jar", content: lib},
+		jarEntry{name: "BOOT-INF/lib/spring-boot-jarmode-tools.jar", content: lib},
+		jarEntry{name: ClasspathIndex, content: []byte("- \"BOOT-INF/lib/used.jar\"\n")},
+	)
+	if err := os.WriteFile(filepath.Join(dir, "app.jar"), app, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Classes != 3 {
+		t.Errorf("Expected 3 classes without the jar missing from the classpath index, got %d", r.Classes)
+	}
+}
+
+func TestScanNestedJarDepth(t *testing.T) {
+	dir := t.TempDir()
+	inner := buildJar(t, jarEntry{name: "org/example/Inner.class"})
+	middle := buildJar(t, jarEntry{name: "org/example/Middle.class"}, jarEntry{name: "lib/inner.jar", content: inner})
+	outer := buildJar(t, jarEntry{name: "org/example/Outer.class"}, jarEntry{name: "lib/middle.jar", content: middle})
+	if err := os.WriteFile(filepath.Join(dir, "outer.jar"), outer, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for depth, expected := range map[int]int{0: 3, 1: 2, 2: 3} {
+		r, err := ScanWith(dir, Options{NestedJarDepth: depth})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Classes != expected {
+			t.Errorf("Expected %d classes with nested jar depth %d, got %d", expected, depth, r.Classes)
+		}
+	}
+}
+
+func TestScanCompressedNestedJar(t *testing.T) {
+	dir := t.TempDir()
+	inner := buildJar(t, jarEntry{name: "org/example/Inner.class"})
+
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+	w, err := z.CreateHeader(&zip.FileHeader{Name: "lib/inner.jar", Method: zip.Deflate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(inner); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "outer.jar"), b.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := JarClasses(dir); err != nil || c != 1 {
+		t.Errorf("Expected 1 class from compressed nested jar, got %d, %v", c, err)
+	}
+}
+
+func TestScanExtractedLayers(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"dependencies/BOOT-INF/lib/used.jar":             buildJar(t, jarEntry{name: "org/example/Used.class"}),
+		"dependencies/BOOT-INF/lib/unused.jar":           buildJar(t, jarEntry{name: "org/example/Unused.class"}),
+		"spring-boot-loader/org/springframework/L.class": []byte("loader"),
+		"application/BOOT-INF/classes/com/App.class":     []byte("app"),
+		"application/BOOT-INF/classpath.idx":             []byte("- \"BOOT-INF/lib/used.jar\"\n"),
+		"application/BOOT-INF/layers.idx": []byte("- \"dependencies\":\n  - \"BOOT-INF/lib/\"\n" +
+			"- \"spring-boot-loader\":\n  - \"org/\"\n- \"application\":\n  - \"BOOT-INF/classes/\"\n"),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Classes != 3 {
+		t.Errorf("Expected 3 classes without the jar missing from the classpath index, got %d", r.Classes)
+	}
+}
+
+func TestParseLayersIndex(t *testing.T) {
+	index := "- \"dependencies\":\n  - \"BOOT-INF/lib/\"\n- \"snapshot-dependencies\":\n- \"application\":\n" +
+		"  - \"BOOT-INF/classes/\"\n"
+
+	layers, err := parseLayersIndex(strings.NewReader(index))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"dependencies", "snapshot-dependencies", "application"}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("Expected layers %v, got %v", expected, layers)
+	}
+}
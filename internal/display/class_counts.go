@@ -0,0 +1,43 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patbaumgartner/memory-calculator/internal/count"
+)
+
+// DisplayClassCounts shows the class counts recorded ahead of time and the lock file they were written to, if any.
+func (f *Formatter) DisplayClassCounts(l *count.Lock, path string) {
+	fmt.Println("\n" + strings.Repeat("=", 50))
+	fmt.Println("Class Counts")
+	fmt.Println(strings.Repeat("=", 50))
+
+	a := l.Application
+	if a.UniqueClasses > 0 {
+		fmt.Printf("Application:      %d class files (%d unique classes)\n", a.Classes, a.UniqueClasses)
+	} else {
+		fmt.Printf("Application:      %d class files\n", a.Classes)
+	}
+	if len(a.Frameworks) > 0 {
+		fmt.Printf("Frameworks:       %s\n", strings.Join(a.Frameworks, ", "))
+	}
+	if len(a.Libraries) > 0 {
+		fmt.Printf("Libraries:        %s\n", strings.Join(a.Libraries, ", "))
+	}
+	fmt.Printf("Agents:           %d classes\n", l.Agents)
+	fmt.Printf("JVM:              %d classes\n", l.JVM)
+	fmt.Printf("Fingerprint:      %s\n", l.Fingerprint)
+	if path != "" {
+		fmt.Printf("Written To:       %s\n", path)
+	}
+}
+
+// DisplayQuietClassCounts shows only the number of application classes, the unique classes if counted.
+func (f *Formatter) DisplayQuietClassCounts(l *count.Lock) {
+	if l.Application.UniqueClasses > 0 {
+		fmt.Print(l.Application.UniqueClasses)
+	} else {
+		fmt.Print(l.Application.Classes)
+	}
+}
@@ -0,0 +1,59 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/calculator"
+)
+
+// DisplayContainerSize shows the container memory required for a target heap with the per-region breakdown.
+func (f *Formatter) DisplayContainerSize(size *calculator.ContainerSize) {
+	fmt.Println("\n" + strings.Repeat("=", 50))
+	fmt.Println("Container Memory Sizing")
+	fmt.Println(strings.Repeat("=", 50))
+
+	fmt.Printf("Target Heap:      %s\n", f.parser.FormatMemory(size.Heap.Value))
+	fmt.Printf("Minimum Memory:   %s\n", f.parser.FormatMemory(size.Minimum.Value))
+	fmt.Printf("Recommended:      %s (%s)\n", size.Quantity(), f.parser.FormatMemory(size.Recommended.Value))
+
+	fmt.Printf("\nRegions at %s:\n", size.Quantity())
+	fmt.Println(strings.Repeat("-", 30))
+
+	r := size.Regions
+	threads := r.ThreadCount
+	f.displayRegion("Heap:", size.Heap.Value)
+	if r.Metaspace != nil {
+		f.displayRegion("Metaspace:", r.Metaspace.Value)
+	}
+	if r.SharedArchive.Value > 0 {
+		f.displayRegion("CDS Archives:", r.SharedArchive.Value)
+	}
+	f.displayRegion("Code Cache:", r.ReservedCodeCache.Value)
+	f.displayRegion(fmt.Sprintf("Thread Stacks (%d):", threads), r.Stack.Value*int64(threads))
+	f.displayRegion("Direct Memory:", r.DirectMemory.Value)
+	if r.NativeAgents.Value > 0 {
+		f.displayRegion("Native Agents:", r.NativeAgents.Value)
+	}
+	if r.HeadRoom != nil && r.HeadRoom.Value > 0 {
+		f.displayRegion("Head Room:", r.HeadRoom.Value)
+	}
+	if all, err := r.AllRegionsSize(threads); err == nil && size.Recommended.Value > all.Value {
+		f.displayRegion("Unallocated:", size.Recommended.Value-all.Value)
+	}
+
+	fmt.Println("\nJVM Options:")
+	fmt.Println(strings.Repeat("-", 30))
+	fmt.Printf("%s=%s\n", size.Output, size.Options)
+}
+
+// DisplayQuietContainerSize shows only the recommended Kubernetes memory quantity.
+func (f *Formatter) DisplayQuietContainerSize(size *calculator.ContainerSize) {
+	fmt.Print(size.Quantity())
+}
+
+// displayRegion shows a single memory region with its size.
+func (f *Formatter) displayRegion(label string, value int64) {
+	fmt.Printf("%-23s%-10s %s\n", label, calc.Size{Value: value}, f.parser.FormatMemory(value))
+}
@@ -3,9 +3,14 @@ package display
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/calculator"
 	"github.com/patbaumgartner/memory-calculator/internal/config"
+	"github.com/patbaumgartner/memory-calculator/internal/jvm"
 	"github.com/patbaumgartner/memory-calculator/internal/memory"
 )
 
@@ -23,28 +28,57 @@ func CreateFormatter() *Formatter {
 
 // DisplayResults shows the calculated JVM settings in a formatted way.
 func (f *Formatter) DisplayResults(props map[string]string, totalMemory int64, cfg *config.Config) {
+	f.displayResults(props, totalMemory, cfg, nil)
+}
+
+// DisplayCalculation shows the calculated JVM settings together with the details of the calculation.
+func (f *Formatter) DisplayCalculation(result *calculator.Result, cfg *config.Config) {
+	f.displayResults(result.Properties, result.Calculator.TotalMemory.Value, cfg, result)
+}
+
+// displayResults shows the calculated JVM settings, including calculation details if a result is given.
+func (f *Formatter) displayResults(
+	props map[string]string, totalMemory int64, cfg *config.Config, result *calculator.Result,
+) {
 	fmt.Println("\n" + strings.Repeat("=", 50))
 	fmt.Println("JVM Memory Configuration")
 	fmt.Println(strings.Repeat("=", 50))
 
 	fmt.Printf("Total Memory:     %s\n", f.parser.FormatMemory(totalMemory))
-	fmt.Printf("Thread Count:     %s\n", cfg.ThreadCount)
+	if cfg.Profile != "" {
+		fmt.Printf("Profile:          %s\n", cfg.Profile)
+	}
+	fmt.Printf("Thread Count:     %s\n", threadCount(cfg, result))
+	if cfg.VirtualThreadCount != "" {
+		fmt.Printf("Virtual Threads:  %s\n", cfg.VirtualThreadCount)
+	}
 
 	// Show loaded classes with helpful message if not set
 	if cfg.LoadedClassCount != "" {
 		fmt.Printf("Loaded Classes:   %s\n", cfg.LoadedClassCount)
+	} else if cfg.ClassList != "" {
+		fmt.Printf("Loaded Classes:   measured in %s\n", cfg.ClassList)
 	} else {
 		fmt.Printf("Loaded Classes:   auto-calculated from %s\n", cfg.Path)
 	}
 
-	fmt.Printf("Head Room:        %s%%\n", cfg.HeadRoom)
+	if _, err := strconv.Atoi(cfg.HeadRoom); err == nil {
+		fmt.Printf("Head Room:        %s%%\n", cfg.HeadRoom)
+	} else {
+		fmt.Printf("Head Room:        %s\n", cfg.HeadRoom)
+	}
 	fmt.Printf("Application Path: %s\n", cfg.Path)
 
+	if result != nil {
+		f.displayDetails(result)
+	}
+
 	fmt.Println("\nCalculated JVM Arguments:")
 	fmt.Println(strings.Repeat("-", 30))
 
 	// Extract and display key JVM settings
 	f.displayJVMSetting(props, "-Xmx", "Max Heap Size:         ")
+	f.displayJVMSetting(props, "-Xms", "Initial Heap Size:     ")
 	f.displayJVMSetting(props, "-Xss", "Thread Stack Size:     ")
 	f.displayJVMSetting(props, "-XX:MaxMetaspaceSize", "Max Metaspace Size:    ")
 	f.displayJVMSetting(props, "-XX:ReservedCodeCacheSize", "Code Cache Size:       ")
@@ -54,7 +88,57 @@ func (f *Formatter) DisplayResults(props map[string]string, totalMemory int64, c
 	fmt.Println(strings.Repeat("-", 30))
 
 	javaToolOptions := f.buildJavaToolOptions(props)
-	fmt.Printf("JAVA_TOOL_OPTIONS=%s\n", javaToolOptions)
+	fmt.Printf("%s=%s\n", outputVariable(props), javaToolOptions)
+}
+
+// displayDetails shows what the calculation detected and derived beyond the configured inputs.
+func (f *Formatter) displayDetails(result *calculator.Result) {
+	if result.Release != nil {
+		fmt.Printf("Java Runtime:     %s\n", result.Release)
+	}
+	if len(result.Frameworks) > 0 {
+		fmt.Printf("Frameworks:       %s\n", strings.Join(result.Frameworks, ", "))
+	}
+	if s := result.Scan; s.UniqueClasses > 0 {
+		fmt.Printf("Class Files:      %d (%d unique classes)\n", s.Classes, s.UniqueClasses)
+	} else if s.Classes > 0 {
+		fmt.Printf("Class Files:      %d\n", s.Classes)
+	}
+	for _, u := range result.UserFlags {
+		fmt.Printf("User Flag:        %s\n", u)
+	}
+	for _, c := range result.Conflicts {
+		fmt.Printf("Conflict:         %s\n", c)
+	}
+	if c := result.Calculator; c.LoadedClassCountProvenance == calc.Measured {
+		fmt.Printf("Class Count:      %d (measured)\n", c.LoadedClassCount)
+	}
+	if h := result.Regions.HeadRoom; h != nil && h.Value > 0 {
+		fmt.Printf("Head Room Size:   %s\n", h)
+	}
+	if ms := result.Regions.Metaspace; ms != nil && ms.Provenance != calc.UserConfigured {
+		fmt.Printf("Metaspace Model:  %s\n", result.Calculator.MetaspaceFormula())
+	}
+	if a := result.Regions.SharedArchive; a.Value > 0 {
+		fmt.Printf("CDS Archives:     %d classes, %s mapped\n", result.Calculator.SharedClassCount, a)
+	}
+	fmt.Printf("Compilation Mode: %s\n", result.Regions.CompilationMode)
+	if h := result.Regions.CodeHeaps; h != nil {
+		fmt.Printf("Code Heaps:       non-nmethod %s, profiled %s, non-profiled %s\n",
+			h.NonNMethod, h.Profiled, h.NonProfiled)
+	}
+	if l := result.Regions.DirectMemoryLibrary; l != "" {
+		fmt.Printf("Direct Memory:    %s for detected %s\n", calc.Size(result.Regions.DirectMemory), l)
+	}
+	for _, r := range result.Calculator.NativeAgentRules {
+		fmt.Printf("Native Agent:     %s reserved for detected %s\n", r.Size, r.Agent)
+	}
+	for _, r := range result.Regions.Reductions {
+		fmt.Printf("Degraded:         %s\n", r)
+	}
+	if result.Calculator.VirtualThreadCount > 0 {
+		fmt.Printf("Virtual Stacks:   %s reserved in heap\n", result.Regions.VirtualThreadStacks)
+	}
 }
 
 // DisplayQuietResults shows only the JVM parameters without formatting.
@@ -83,13 +167,54 @@ func (f *Formatter) DisplayHelp(cfg *config.Config) {
 	fmt.Println()
 	fmt.Println("Usage:")
 	fmt.Println("  memory-calculator [flags]")
+	fmt.Println("  memory-calculator size-container --heap <size> [flags]")
+	fmt.Println("  memory-calculator sweep (--from <size> --to <size> [--step <size>] | --sizes <list>) [flags]")
+	fmt.Println("  memory-calculator count-classes [--write <file>] [flags]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  size-container                Calculate the container memory required for a target heap")
+	fmt.Println("  sweep                         Tabulate the calculation across a range of total memory sizes")
+	fmt.Println("  count-classes                 Count classes ahead of time and record them in a lock file")
 	fmt.Println()
 	fmt.Println("Flags:")
 	fmt.Println("  --total-memory string         Total memory (e.g., 2G, 512M, 1024MB)")
-	fmt.Println("  --thread-count string         JVM thread count (default \"250\")")
+	fmt.Println("  --thread-count string         JVM platform thread count (default \"250\")")
+	fmt.Println("  --virtual-thread-count string Expected concurrent virtual threads (Java 21+)")
 	fmt.Println("  --loaded-class-count string   JVM loaded class count (calculated if not set)")
-	fmt.Println("  --head-room string            JVM head room percentage (default \"0\")")
+	fmt.Println("  --class-list string           Class load log or classlist of a previous run to count classes in")
+	fmt.Println("  --class-list-margin string    Percentage added to the classes of the class list (default 10)")
+	fmt.Println("  --head-room string            JVM head room percentage or size (default \"0\")")
+	fmt.Println("                                (e.g., 10, 300M, 10%,min=128M,max=2G)")
 	fmt.Println("  --path string                 Application path for JAR scanning (default \"/app\")")
+	fmt.Println("  --nested-jar-depth string     Levels of nested jars to count classes in (default 3)")
+	fmt.Println("  --class-count-mode string     Count every class file (raw) or each class name once (unique)")
+	fmt.Println("  --scan-workers string         Jars read concurrently when counting classes (default: GOMAXPROCS)")
+	fmt.Println("  --cache-path string           File caching class counts of unchanged jars (default in $TMPDIR)")
+	fmt.Println("  --cache-hash                  Compare jar content digests in addition to size and modification time")
+	fmt.Println("  --no-cache                    Scan all jars instead of using the class count cache")
+	fmt.Println("  --lock-file string            Class counts recorded by count-classes (default in --path)")
+	fmt.Println("  --trust-lock                  Use the lock file without comparing its fingerprint")
+	fmt.Println("  --java-home string            Java home for JVM version detection (default $JAVA_HOME)")
+	fmt.Println("  --segmented-code-cache        Emit explicitly sized code heaps (Java 9+)")
+	fmt.Println("  --direct-memory-rules string  Direct memory rules for detected libraries")
+	fmt.Println("                                (e.g., netty-buffer=15%,min=128M;kafka-clients=64M)")
+	fmt.Println("  --native-agent-rules string   Native memory reserved for detected agents (e.g., dynatrace=256M)")
+	fmt.Println("  --degrade                     Shrink non-configured regions to fit a minimum heap")
+	fmt.Println("  --profile string              Preset of calculation parameters ($BPL_JVM_PROFILE)")
+	fmt.Println("                                (spring-boot, quarkus, micronaut, batch, low-latency)")
+	fmt.Println("  --config-file string          JSON configuration file defining custom profiles")
+	fmt.Println("  --class-load-factor string    Share of the counted classes expected to be loaded (default 0.35)")
+	fmt.Println("  --code-cache-size string      Reserved code cache size (e.g., 128M)")
+	fmt.Println("  --direct-memory-size string   Direct memory size (e.g., 64M)")
+	fmt.Println("  --class-size string           Metaspace per loaded class (default 5800)")
+	fmt.Println("  --class-overhead string       Metaspace of the JVM independent of classes (default 14000000)")
+	fmt.Println("  --language-weights string     Classes per script entry (e.g., groovy=3,clj=4,kts=2)")
+	fmt.Println("  --initial-heap string         Initial heap as 'max' or a share of the heap (e.g., 25%)")
+	fmt.Println("  --jvm-options string          Additional JVM options (e.g., -XX:+ExitOnOutOfMemoryError)")
+	fmt.Println("  --command-line string         Java command line whose JVM options are honored ($BPL_JVM_COMMAND_LINE)")
+	fmt.Println("  --output-variable string      Variable receiving the calculated options (default JAVA_TOOL_OPTIONS)")
+	fmt.Println("  --merge-policy string         Policy for memory flags the user already configured")
+	fmt.Println("                                (respect-user, override, fail-on-conflict; default respect-user)")
 	fmt.Println("  --quiet                       Only output JVM parameters, no formatting")
 	fmt.Println("  --version                     Show version information")
 	fmt.Println("  --help                        Show this help message")
@@ -101,6 +226,10 @@ func (f *Formatter) DisplayHelp(cfg *config.Config) {
 	fmt.Println("  memory-calculator --total-memory=512M")
 	fmt.Println("  memory-calculator --path=/my/app --total-memory=2G")
 	fmt.Println("  memory-calculator --quiet --total-memory=2G  # Only output JVM parameters")
+	fmt.Println("  memory-calculator --profile=quarkus --thread-count=150")
+	fmt.Println("  memory-calculator size-container --heap=3G   # Container memory for a 3G heap")
+	fmt.Println("  memory-calculator sweep --from=256M --to=8G --step=256M")
+	fmt.Println("  memory-calculator count-classes --write=/app/.memory-calculator.json")
 }
 
 // displayJVMSetting extracts and displays a specific JVM setting.
@@ -111,8 +240,8 @@ func (f *Formatter) displayJVMSetting(props map[string]string, flag, label strin
 		return
 	}
 
-	// If not found individually, try to extract from JAVA_TOOL_OPTIONS
-	if javaToolOptions, exists := props["JAVA_TOOL_OPTIONS"]; exists {
+	// If not found individually, try to extract from the output variable
+	if javaToolOptions, exists := props[outputVariable(props)]; exists {
 		value := f.extractJVMFlag(javaToolOptions, flag)
 		if value != "" {
 			fmt.Printf("%s%s\n", label, value)
@@ -143,18 +272,46 @@ func (f *Formatter) extractJVMFlag(javaToolOptions, flag string) string {
 
 // buildJavaToolOptions constructs the JAVA_TOOL_OPTIONS string from properties.
 func (f *Formatter) buildJavaToolOptions(props map[string]string) string {
-	// Display JAVA_TOOL_OPTIONS if it exists
-	if javaToolOptions, exists := props["JAVA_TOOL_OPTIONS"]; exists {
+	// Display the output variable if it exists
+	if javaToolOptions, exists := props[outputVariable(props)]; exists {
 		return javaToolOptions
 	}
 
-	// If JAVA_TOOL_OPTIONS doesn't exist, build it from individual flags
+	// If the output variable doesn't exist, build it from individual flags
 	var options []string
 	for flag, value := range props {
-		if flag != "JAVA_TOOL_OPTIONS" {
+		if !slices.Contains(jvm.OptionVariables, flag) {
 			options = append(options, fmt.Sprintf("%s%s", flag, value))
 		}
 	}
 
 	return strings.Join(options, " ")
 }
+
+// outputVariable returns the environment variable holding the JVM options in props, JAVA_TOOL_OPTIONS if there is
+// none.
+func outputVariable(props map[string]string) string {
+	for _, v := range jvm.OptionVariables {
+		if _, exists := props[v]; exists {
+			return v
+		}
+	}
+	return jvm.JavaToolOptions
+}
+
+// threadCount returns the thread count configured by the user, falling back to the thread count of the profile and
+// the default of the calculator. If degrade mode reduced it, the thread count of the calculation is returned.
+func threadCount(cfg *config.Config, result *calculator.Result) string {
+	configured := strconv.Itoa(calculator.DefaultThreadCount)
+	switch {
+	case cfg.ThreadCount != "":
+		configured = cfg.ThreadCount
+	case cfg.ProfileThreadCount != "":
+		configured = cfg.ProfileThreadCount
+	}
+
+	if result != nil && result.Regions.ThreadCount > 0 && strconv.Itoa(result.Regions.ThreadCount) != configured {
+		return fmt.Sprintf("%d (degraded from %s)", result.Regions.ThreadCount, configured)
+	}
+	return configured
+}
@@ -2,12 +2,16 @@ package display
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/calculator"
 	"github.com/patbaumgartner/memory-calculator/internal/config"
+	"github.com/patbaumgartner/memory-calculator/internal/count"
 )
 
 func TestCreateFormatter(t *testing.T) {
@@ -142,6 +146,227 @@ func TestDisplayResults(t *testing.T) {
 	}
 }
 
+func TestDisplayCalculation(t *testing.T) {
+	formatter := CreateFormatter()
+	cfg := &config.Config{
+		ThreadCount: "250",
+		HeadRoom:    "10%,min=128M",
+		Path:        "/app",
+		ClassList:   "/tmp/classes.log",
+	}
+
+	headRoom := calc.HeadRoom{Value: 205 * calc.Mebi}
+	result := &calculator.Result{
+		Properties: map[string]string{"JAVA_OPTS": "-Xmx1024M -XX:MaxDirectMemorySize=205M"},
+		Calculator: calc.Calculator{
+			TotalMemory:                calc.Size{Value: 2 * calc.Gibi},
+			LoadedClassCount:           2200,
+			LoadedClassCountProvenance: calc.Measured,
+			SharedClassCount:           1300,
+			NativeAgentRules:           calc.DefaultNativeAgentRules[:1],
+		},
+		Regions: calc.MemoryRegions{
+			HeadRoom:            &headRoom,
+			SharedArchive:       calc.SharedArchive{Value: 13 * calc.Mebi},
+			DirectMemory:        calc.DirectMemory{Value: 205 * calc.Mebi},
+			DirectMemoryLibrary: "netty-buffer",
+			ThreadCount:         134,
+		},
+		Frameworks: []string{"hibernate", "spring-boot"},
+		Scan:       count.Result{Classes: 1200, UniqueClasses: 1100},
+		Output:     "JAVA_OPTS",
+		UserFlags:  []calculator.UserFlag{{Flag: "-Xss512K", Source: "command line"}},
+		Conflicts: []calculator.Conflict{{
+			Setting:    "heap",
+			Flags:      []calculator.UserFlag{{Flag: "-XX:MaxRAMPercentage=75", Source: "JAVA_OPTS"}},
+			Resolution: "the calculated heap replaces them",
+		}},
+	}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	formatter.DisplayCalculation(result, cfg)
+
+	_ = w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	_, _ = io.Copy(&buf, r)
+	output := buf.String()
+
+	expectedParts := []string{
+		"Thread Count:     134 (degraded from 250)",
+		"Head Room:        10%,min=128M\n",
+		"Head Room Size:   205M",
+		"Direct Memory:    205M for detected netty-buffer",
+		"Frameworks:       hibernate, spring-boot",
+		"Class Files:      1200 (1100 unique classes)",
+		"CDS Archives:     1300 classes, 13M mapped",
+		"Loaded Classes:   measured in /tmp/classes.log",
+		"Class Count:      2200 (measured)",
+		"Native Agent:     128M reserved for detected dynatrace",
+		"User Flag:        -Xss512K from command line",
+		"Conflict:         heap: -XX:MaxRAMPercentage=75 from JAVA_OPTS; the calculated heap replaces them",
+		"Max Heap Size:         1024M",
+		"JAVA_OPTS=-Xmx1024M -XX:MaxDirectMemorySize=205M",
+	}
+
+	for _, part := range expectedParts {
+		if !strings.Contains(output, part) {
+			t.Errorf("Expected output to contain %q, got:\n%s", part, output)
+		}
+	}
+}
+
+func TestDisplayClassCounts(t *testing.T) {
+	formatter := CreateFormatter()
+
+	l := &count.Lock{
+		Fingerprint: "abc123",
+		Application: count.Result{Classes: 1200, UniqueClasses: 1100, Frameworks: []string{"spring-boot"}},
+		Agents:      300,
+		JVM:         1000,
+	}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	formatter.DisplayClassCounts(l, "/app/.memory-calculator.json")
+
+	_ = w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	_, _ = io.Copy(&buf, r)
+	output := buf.String()
+
+	expectedParts := []string{
+		"Application:      1200 class files (1100 unique classes)",
+		"Frameworks:       spring-boot",
+		"Agents:           300 classes",
+		"JVM:              1000 classes",
+		"Fingerprint:      abc123",
+		"Written To:       /app/.memory-calculator.json",
+	}
+
+	for _, part := range expectedParts {
+		if !strings.Contains(output, part) {
+			t.Errorf("Expected output to contain %q, got:\n%s", part, output)
+		}
+	}
+}
+
+func TestDisplayContainerSize(t *testing.T) {
+	formatter := CreateFormatter()
+
+	heap := calc.Heap{Value: 3 * calc.Gibi}
+	metaspace := calc.Metaspace{Value: 64 * calc.Mebi}
+	headRoom := calc.HeadRoom{}
+	size := &calculator.ContainerSize{
+		Heap:        calc.Size{Value: 3 * calc.Gibi},
+		Minimum:     calc.Size{Value: 3_600 * calc.Mebi},
+		Recommended: calc.Size{Value: 4 * calc.Gibi},
+		Regions: calc.MemoryRegions{
+			Heap:              &heap,
+			Metaspace:         &metaspace,
+			HeadRoom:          &headRoom,
+			ReservedCodeCache: calc.ReservedCodeCache{Value: 240 * calc.Mebi},
+			Stack:             calc.Stack{Value: calc.Mebi},
+			DirectMemory:      calc.DirectMemory{Value: 10 * calc.Mebi},
+			ThreadCount:       250,
+		},
+		Options: "-Xmx3G -Xss1M",
+		Output:  "JAVA_TOOL_OPTIONS",
+	}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	formatter.DisplayContainerSize(size)
+
+	_ = w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	_, _ = io.Copy(&buf, r)
+	output := buf.String()
+
+	expectedParts := []string{
+		"Target Heap:      3.00 GB",
+		"Recommended:      4Gi",
+		"Code Cache:            240M",
+		"Thread Stacks (250):   250M",
+		"Unallocated:           460M",
+		"JAVA_TOOL_OPTIONS=-Xmx3G -Xss1M",
+	}
+
+	for _, part := range expectedParts {
+		if !strings.Contains(output, part) {
+			t.Errorf("Expected output to contain %q, got:\n%s", part, output)
+		}
+	}
+	if strings.Contains(output, "Head Room:") {
+		t.Errorf("Expected no head room without head room, got:\n%s", output)
+	}
+}
+
+func TestDisplaySweep(t *testing.T) {
+	formatter := CreateFormatter()
+
+	heap := calc.Heap{Value: 100 * calc.Mebi}
+	metaspace := calc.Metaspace{Value: 64 * calc.Mebi}
+	headRoom := calc.HeadRoom{}
+	rows := []calculator.SweepRow{
+		{TotalMemory: calc.Size{Value: 256 * calc.Mebi}, Err: fmt.Errorf("fixed memory regions require 500M\ndetails")},
+		{
+			TotalMemory: calc.Size{Value: 512 * calc.Mebi},
+			LowHeap:     true,
+			Regions: calc.MemoryRegions{
+				Heap:              &heap,
+				Metaspace:         &metaspace,
+				HeadRoom:          &headRoom,
+				ReservedCodeCache: calc.ReservedCodeCache{Value: 240 * calc.Mebi},
+				Stack:             calc.Stack{Value: calc.Mebi},
+				DirectMemory:      calc.DirectMemory{Value: 10 * calc.Mebi},
+				ThreadCount:       98,
+			},
+		},
+	}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	formatter.DisplaySweep(rows, calc.Size{Value: 128 * calc.Mebi})
+	formatter.DisplayQuietSweep(rows)
+
+	_ = w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	_, _ = io.Copy(&buf, r)
+	output := buf.String()
+
+	expectedParts := []string{
+		"256M       -          -          -          -          -          -          FAILED",
+		"512M       100M       64M        240M       98M        10M        0          LOW HEAP",
+		"LOW HEAP marks heaps below 128M.",
+		"  256M: fixed memory regions require 500M\n",
+		"Total,Heap,Metaspace,Code Cache,Stacks,Direct,Head Room,Status",
+		"512M,100M,64M,240M,98M,10M,0,LOW HEAP",
+	}
+
+	for _, part := range expectedParts {
+		if !strings.Contains(output, part) {
+			t.Errorf("Expected output to contain %q, got:\n%s", part, output)
+		}
+	}
+}
+
 func TestDisplayResultsWithIndividualProps(t *testing.T) {
 	formatter := CreateFormatter()
 	cfg := &config.Config{
@@ -0,0 +1,100 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/patbaumgartner/memory-calculator/internal/calc"
+	"github.com/patbaumgartner/memory-calculator/internal/calculator"
+)
+
+// sweepColumns are the headers of the sweep table.
+var sweepColumns = []string{"Total", "Heap", "Metaspace", "Code Cache", "Stacks", "Direct", "Head Room", "Status"}
+
+// DisplaySweep shows the outcome of a sweep as a table, marking failed calculations and heaps below minHeap.
+func (f *Formatter) DisplaySweep(rows []calculator.SweepRow, minHeap calc.Size) {
+	fmt.Println("\n" + strings.Repeat("=", 50))
+	fmt.Println("Memory Sweep")
+	fmt.Println(strings.Repeat("=", 50))
+
+	format := "%-10s %-10s %-10s %-10s %-10s %-10s %-10s %s\n"
+	header := make([]any, len(sweepColumns))
+	for i, c := range sweepColumns {
+		header[i] = c
+	}
+	fmt.Printf(format, header...)
+	fmt.Println(strings.Repeat("-", 86))
+
+	var failures []string
+	for _, row := range rows {
+		values := sweepValues(row)
+		cells := make([]any, len(values))
+		for i, v := range values {
+			cells[i] = v
+		}
+		fmt.Printf(format, cells...)
+
+		if row.Err != nil {
+			failures = append(failures, fmt.Sprintf("  %s: %s", row.TotalMemory, firstLine(row.Err.Error())))
+		}
+	}
+
+	if minHeap.Value > 0 {
+		fmt.Printf("\nLOW HEAP marks heaps below %s.\n", minHeap)
+	}
+	if len(failures) > 0 {
+		fmt.Println("\nFailed calculations:")
+		for _, l := range failures {
+			fmt.Println(l)
+		}
+	}
+}
+
+// DisplayQuietSweep shows the outcome of a sweep as comma-separated values for further processing.
+func (f *Formatter) DisplayQuietSweep(rows []calculator.SweepRow) {
+	fmt.Println(strings.Join(sweepColumns, ","))
+	for _, row := range rows {
+		fmt.Println(strings.Join(sweepValues(row), ","))
+	}
+}
+
+// sweepValues returns the table cells of a sweep row.
+func sweepValues(row calculator.SweepRow) []string {
+	if row.Err != nil {
+		return []string{row.TotalMemory.String(), "-", "-", "-", "-", "-", "-", "FAILED"}
+	}
+
+	r := row.Regions
+	status := "ok"
+	if row.LowHeap {
+		status = "LOW HEAP"
+	}
+	if len(r.Reductions) > 0 {
+		status += " (degraded)"
+	}
+
+	metaspace, headRoom := calc.Size{}, calc.Size{}
+	if r.Metaspace != nil {
+		metaspace = calc.Size(*r.Metaspace)
+	}
+	if r.HeadRoom != nil {
+		headRoom = calc.Size(*r.HeadRoom)
+	}
+
+	return []string{
+		row.TotalMemory.String(),
+		calc.Size(*r.Heap).String(),
+		metaspace.String(),
+		calc.Size(r.ReservedCodeCache).String(),
+		calc.Size{Value: r.Stack.Value * int64(r.ThreadCount)}.String(),
+		calc.Size(r.DirectMemory).String(),
+		headRoom.String(),
+		status,
+	}
+}
+
+// firstLine returns the first line of a possibly multi-line message.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,90 @@
+package jvm
+
+import "strings"
+
+// Environment variables holding JVM options.
+const (
+	// JavaToolOptions is read by the JVM itself, before the command line.
+	JavaToolOptions = "JAVA_TOOL_OPTIONS"
+
+	// JDKJavaOptions is read by the java launcher of Java 9+ and prepended to the command line arguments.
+	JDKJavaOptions = "JDK_JAVA_OPTIONS"
+
+	// JavaOpts is not read by the JVM, but passed on the command line by many start scripts.
+	JavaOpts = "JAVA_OPTS"
+
+	// UnderscoreJavaOptions is read by the JVM after the command line, so its options take precedence over all others.
+	UnderscoreJavaOptions = "_JAVA_OPTIONS"
+)
+
+// CommandLine names the options of the java command line as a source of options.
+const CommandLine = "command line"
+
+// OptionVariables lists the environment variables holding JVM options, in the order the JVM applies them. An option
+// applied later overrides the same option applied earlier. The command line is applied after JavaOpts and before
+// UnderscoreJavaOptions.
+var OptionVariables = []string{JavaToolOptions, JDKJavaOptions, JavaOpts, UnderscoreJavaOptions}
+
+// ArgFileVariables lists the environment variables whose options reach the java launcher as arguments, so it
+// expands their @argfiles like on the command line. The JVM reads JavaToolOptions and UnderscoreJavaOptions itself
+// and rejects an @argfile there as an unrecognized option.
+var ArgFileVariables = []string{JDKJavaOptions, JavaOpts}
+
+// OptionSource holds the options of a single source of JVM options.
+type OptionSource struct {
+	// Name is the environment variable holding the options, or CommandLine.
+	Name string
+
+	// Options holds the options as configured in the source.
+	Options string
+}
+
+// optionsWithArgument are the launcher options whose argument is the next command line argument.
+var optionsWithArgument = map[string]bool{
+	"-cp": true, "-classpath": true, "--class-path": true,
+	"-p": true, "--module-path": true, "--upgrade-module-path": true,
+	"--add-modules": true, "--limit-modules": true, "--enable-native-access": true,
+	"--add-reads": true, "--add-exports": true, "--add-opens": true, "--patch-module": true,
+}
+
+// LauncherOptions returns the JVM options of a java command line, e.g. "java -Xmx1G -jar app.jar --server.port=80".
+// The java executable, the options of the launcher like -cp and their arguments, and everything from the main class,
+// -jar or -m on are not JVM options.
+func LauncherOptions(args []string) []string {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = args[1:]
+	}
+
+	var options []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case !strings.HasPrefix(a, "-"), a == "-jar", a == "-m", a == "--module", strings.HasPrefix(a, "--module="):
+			return options
+		case optionsWithArgument[a]:
+			i++
+		case strings.HasPrefix(a, "--"), a == "-version", a == "-help", a == "-?":
+			// Launcher options like --add-opens=... or --show-version
+		default:
+			options = append(options, a)
+		}
+	}
+	return options
+}
+
+// Merge policies decide between the memory flags configured by the user and the calculated flags.
+const (
+	// RespectUser keeps the memory settings configured by the user. Calculated flags only configure the others.
+	RespectUser = "respect-user"
+
+	// Override replaces the memory flags configured by the user with calculated flags, wherever the calculated flags
+	// are applied after them.
+	Override = "override"
+
+	// FailOnConflict keeps the memory settings configured by the user like RespectUser, but fails if any of their
+	// flags conflict.
+	FailOnConflict = "fail-on-conflict"
+)
+
+// MergePolicies lists the supported merge policies.
+var MergePolicies = []string{RespectUser, Override, FailOnConflict}
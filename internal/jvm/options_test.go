@@ -0,0 +1,51 @@
+package jvm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLauncherOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "jar",
+			args:     []string{"java", "-Xmx1G", "-Xss512K", "-jar", "app.jar", "-Xmx2G"},
+			expected: []string{"-Xmx1G", "-Xss512K"},
+		},
+		{
+			name:     "main class",
+			args:     []string{"/opt/java/bin/java", "-cp", "lib/*", "-XX:MaxMetaspaceSize=128M", "com.example.Main"},
+			expected: []string{"-XX:MaxMetaspaceSize=128M"},
+		},
+		{
+			name:     "module",
+			args:     []string{"java", "--add-opens", "java.base/java.lang=ALL-UNNAMED", "-Xmx1G", "-m", "app/app.Main"},
+			expected: []string{"-Xmx1G"},
+		},
+		{
+			name:     "launcher options",
+			args:     []string{"java", "--show-version", "--module-path=mods", "-Dkey=value", "--module=app"},
+			expected: []string{"-Dkey=value"},
+		},
+		{
+			name:     "options only",
+			args:     []string{"-Xmx1G", "-XX:+UseG1GC"},
+			expected: []string{"-Xmx1G", "-XX:+UseG1GC"},
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LauncherOptions(tt.args); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("LauncherOptions(%q) = %q, expected %q", tt.args, got, tt.expected)
+			}
+		})
+	}
+}
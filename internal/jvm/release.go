@@ -0,0 +1,192 @@
+// Package jvm detects the Java runtime that calculated memory options are meant for.
+//
+// The detection reads the release file that every JDK and JRE since Java 8 ships in its home
+// directory. It provides the Java feature version and the vendor, which lets the calculator
+// pick version-appropriate defaults and avoid emitting flags the runtime does not understand.
+package jvm
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReleaseFile is the name of the runtime description file, relative to JAVA_HOME.
+const ReleaseFile = "release"
+
+// Feature identifies a JVM capability that is only available from a certain Java version on.
+type Feature uint8
+
+const (
+	// Metaspace indicates class metadata lives in metaspace (Java 8+) rather than the permanent generation.
+	Metaspace Feature = iota
+
+	// ContainerSupport indicates the JVM reads container limits (Java 10+, backported to 8u191).
+	ContainerSupport
+
+	// SegmentedCodeCache indicates the code cache is split into separate code heaps (Java 9+).
+	SegmentedCodeCache
+
+	// VirtualThreads indicates virtual threads are available without preview flags (Java 21+).
+	VirtualThreads
+
+	// GenerationalZGC indicates the generational mode of ZGC is available (Java 21+).
+	GenerationalZGC
+)
+
+// Release describes a Java runtime as recorded in its release file.
+type Release struct {
+	// Home is the Java home directory the release file was read from.
+	Home string
+
+	// JavaVersion is the raw JAVA_VERSION value, e.g. "17.0.9" or "1.8.0_392".
+	JavaVersion string
+
+	// Major is the Java feature version derived from JavaVersion, e.g. 8, 17 or 21.
+	Major int
+
+	// Update is the update release derived from JavaVersion, e.g. 392 for "1.8.0_392".
+	Update int
+
+	// Implementor is the IMPLEMENTOR value, e.g. "Eclipse Adoptium" or "BellSoft".
+	Implementor string
+}
+
+// ReadRelease reads and parses the release file of the Java runtime at javaHome.
+func ReadRelease(javaHome string) (*Release, error) {
+	file := filepath.Join(javaHome, ReleaseFile)
+
+	// #nosec G304 - the release file location is derived from JAVA_HOME on purpose
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	r, err := ParseRelease(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s\n%w", file, err)
+	}
+	r.Home = javaHome
+
+	return r, nil
+}
+
+// ParseRelease parses the contents of a release file. The file consists of KEY="value" lines; JAVA_VERSION is
+// required, all other keys are optional.
+func ParseRelease(content string) (*Release, error) {
+	values := make(map[string]string)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read release file\n%w", err)
+	}
+
+	version, ok := values["JAVA_VERSION"]
+	if !ok || version == "" {
+		return nil, fmt.Errorf("release file does not contain JAVA_VERSION")
+	}
+
+	major, update, err := ParseVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Release{
+		JavaVersion: version,
+		Major:       major,
+		Update:      update,
+		Implementor: values["IMPLEMENTOR"],
+	}, nil
+}
+
+// ParseVersion returns the feature version and update release of a Java version string. It understands both the
+// legacy scheme ("1.8.0_392") and the JEP 223 scheme ("17.0.9", "21", "21-ea").
+func ParseVersion(version string) (int, int, error) {
+	v := strings.TrimSpace(version)
+
+	// Drop pre-release and build information, e.g. "21-ea+35" or "17.0.9+9"
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+
+	if strings.HasPrefix(v, "1.") {
+		// Legacy scheme: 1.<major>.0_<update>
+		parts := strings.SplitN(v, ".", 3)
+		major, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, 0, fmt.Errorf("unable to parse Java version %q", version)
+		}
+
+		update := 0
+		if len(parts) == 3 {
+			if _, u, ok := strings.Cut(parts[2], "_"); ok {
+				if update, err = strconv.Atoi(u); err != nil {
+					return 0, 0, fmt.Errorf("unable to parse update of Java version %q", version)
+				}
+			}
+		}
+		return major, update, nil
+	}
+
+	// JEP 223 scheme: <feature>.<interim>.<update>.<patch>
+	parts := strings.Split(v, ".")
+	major, err := strconv.Atoi(parts[0])
+	if err != nil || major < 1 {
+		return 0, 0, fmt.Errorf("unable to parse Java version %q", version)
+	}
+
+	update := 0
+	if len(parts) >= 3 {
+		if update, err = strconv.Atoi(parts[2]); err != nil {
+			return 0, 0, fmt.Errorf("unable to parse update of Java version %q", version)
+		}
+	}
+
+	return major, update, nil
+}
+
+// Supports returns true if the runtime provides the given feature. An unknown runtime (nil Release) is assumed to
+// support every feature so that behavior without detection stays unchanged.
+func (r *Release) Supports(f Feature) bool {
+	if r == nil {
+		return true
+	}
+
+	switch f {
+	case Metaspace:
+		return r.Major >= 8
+	case ContainerSupport:
+		return r.Major >= 10 || (r.Major == 8 && r.Update >= 191)
+	case SegmentedCodeCache:
+		return r.Major >= 9
+	case VirtualThreads, GenerationalZGC:
+		return r.Major >= 21
+	default:
+		return false
+	}
+}
+
+func (r *Release) String() string {
+	if r == nil {
+		return "unknown"
+	}
+	if r.Implementor == "" {
+		return r.JavaVersion
+	}
+	return fmt.Sprintf("%s (%s)", r.JavaVersion, r.Implementor)
+}
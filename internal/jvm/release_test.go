@@ -0,0 +1,141 @@
+package jvm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version     string
+		major       int
+		update      int
+		expectError bool
+	}{
+		{version: "1.8.0_392", major: 8, update: 392},
+		{version: "1.8.0", major: 8, update: 0},
+		{version: "1.7.0_80", major: 7, update: 80},
+		{version: "11.0.21", major: 11, update: 21},
+		{version: "17.0.9", major: 17, update: 9},
+		{version: "17.0.9+9", major: 17, update: 9},
+		{version: "21", major: 21, update: 0},
+		{version: "21-ea", major: 21, update: 0},
+		{version: "22.0.1.0.1", major: 22, update: 1},
+		{version: "", expectError: true},
+		{version: "abc", expectError: true},
+		{version: "1.x.0", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			major, update, err := ParseVersion(tt.version)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if major != tt.major || update != tt.update {
+				t.Errorf("ParseVersion(%q) = %d, %d, expected %d, %d", tt.version, major, update, tt.major, tt.update)
+			}
+		})
+	}
+}
+
+func TestParseRelease(t *testing.T) {
+	content := `IMPLEMENTOR="Eclipse Adoptium"
+IMPLEMENTOR_VERSION="Temurin-17.0.9+9"
+JAVA_VERSION="17.0.9"
+JAVA_VERSION_DATE="2023-10-17"
+MODULES="java.base java.logging java.sql"
+OS_ARCH="x86_64"
+`
+
+	r, err := ParseRelease(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.JavaVersion != "17.0.9" || r.Major != 17 || r.Update != 9 {
+		t.Errorf("Unexpected version %q (%d, %d)", r.JavaVersion, r.Major, r.Update)
+	}
+	if r.Implementor != "Eclipse Adoptium" {
+		t.Errorf("Expected implementor 'Eclipse Adoptium', got %q", r.Implementor)
+	}
+	if r.String() != "17.0.9 (Eclipse Adoptium)" {
+		t.Errorf("Unexpected string %q", r.String())
+	}
+}
+
+func TestParseReleaseJava8(t *testing.T) {
+	r, err := ParseRelease("JAVA_VERSION=\"1.8.0_392\"\nOS_NAME=\"Linux\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Major != 8 || r.Update != 392 {
+		t.Errorf("Expected 8u392, got %du%d", r.Major, r.Update)
+	}
+	if r.String() != "1.8.0_392" {
+		t.Errorf("Unexpected string %q", r.String())
+	}
+}
+
+func TestParseReleaseWithoutVersion(t *testing.T) {
+	if _, err := ParseRelease("IMPLEMENTOR=\"BellSoft\"\n"); err == nil {
+		t.Error("Expected error for release file without JAVA_VERSION")
+	}
+}
+
+func TestReadRelease(t *testing.T) {
+	javaHome := t.TempDir()
+	content := "JAVA_VERSION=\"21.0.1\"\nIMPLEMENTOR=\"BellSoft\"\n"
+	if err := os.WriteFile(filepath.Join(javaHome, ReleaseFile), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := ReadRelease(javaHome)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Home != javaHome || r.Major != 21 || r.Implementor != "BellSoft" {
+		t.Errorf("Unexpected release %+v", r)
+	}
+
+	if _, err := ReadRelease(t.TempDir()); err == nil {
+		t.Error("Expected error for missing release file")
+	}
+}
+
+func TestSupports(t *testing.T) {
+	tests := []struct {
+		name     string
+		release  *Release
+		feature  Feature
+		expected bool
+	}{
+		{"unknown runtime supports everything", nil, VirtualThreads, true},
+		{"Java 7 has no metaspace", &Release{Major: 7}, Metaspace, false},
+		{"Java 8 has metaspace", &Release{Major: 8}, Metaspace, true},
+		{"Java 8u181 lacks container support", &Release{Major: 8, Update: 181}, ContainerSupport, false},
+		{"Java 8u191 has container support", &Release{Major: 8, Update: 191}, ContainerSupport, true},
+		{"Java 11 has container support", &Release{Major: 11}, ContainerSupport, true},
+		{"Java 8 has no segmented code cache", &Release{Major: 8}, SegmentedCodeCache, false},
+		{"Java 9 has segmented code cache", &Release{Major: 9}, SegmentedCodeCache, true},
+		{"Java 17 has no virtual threads", &Release{Major: 17}, VirtualThreads, false},
+		{"Java 21 has virtual threads", &Release{Major: 21}, VirtualThreads, true},
+		{"Java 21 has generational ZGC", &Release{Major: 21}, GenerationalZGC, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.release.Supports(tt.feature); actual != tt.expected {
+				t.Errorf("Supports() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
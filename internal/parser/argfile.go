@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DisableArgFiles stops the expansion of argument files in the arguments that follow it, like in the java launcher.
+const DisableArgFiles = "--disable-@files"
+
+// ExpandArgFiles replaces each @argfile argument with the arguments in the file, parsed with ParseArgFile. Unlike
+// the java launcher, argument files may include further argument files; an argument file including itself is an
+// error. An argument starting with @@ is not expanded and passed on with a single @, and no argument following
+// DisableArgFiles is expanded.
+func ExpandArgFiles(args []string) ([]string, error) {
+	return (&argFileExpander{}).expand(args)
+}
+
+// argFileExpander expands the argument files of the java launcher, e.g. @/app/jvm.options.
+type argFileExpander struct {
+	// expanding holds the absolute paths of the argument files being expanded, to detect cycles.
+	expanding []string
+
+	// disabled is set once DisableArgFiles is found.
+	disabled bool
+}
+
+// expand replaces each @argfile argument with the arguments in the file.
+func (e *argFileExpander) expand(args []string) ([]string, error) {
+	var result []string
+	for _, a := range args {
+		switch {
+		case e.disabled:
+			result = append(result, a)
+		case a == DisableArgFiles:
+			e.disabled = true
+			result = append(result, a)
+		case strings.HasPrefix(a, "@@"):
+			result = append(result, a[1:])
+		case len(a) > 1 && a[0] == '@':
+			p, err := e.read(a[1:])
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, p...)
+		default:
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+// read returns the expanded arguments of the argument file at path. Relative paths are resolved against the working
+// directory, like in the java launcher.
+func (e *argFileExpander) read(path string) ([]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve argument file %s\n%w", path, err)
+	}
+	if slices.Contains(e.expanding, abs) {
+		return nil, fmt.Errorf("argument file %s includes itself", path)
+	}
+
+	b, err := os.ReadFile(abs) //nolint:gosec // Argument files are configured by the user
+	if err != nil {
+		return nil, fmt.Errorf("unable to read argument file %s\n%w", path, err)
+	}
+
+	e.expanding = append(e.expanding, abs)
+	defer func() { e.expanding = e.expanding[:len(e.expanding)-1] }()
+	return e.expand(ParseArgFile(string(b)))
+}
+
+// argFileState is the state of the argument file tokenizer.
+type argFileState int
+
+const (
+	findNext argFileState = iota
+	inToken
+	inQuote
+	inEscape
+	inComment
+	skipLeadingSpace
+)
+
+// ParseArgFile returns the arguments in the content of an argument file, following the syntax of the java launcher:
+//   - arguments are separated by spaces, tabs, form feeds and line breaks
+//   - single or double quotes enclose whitespace, but do not span lines
+//   - within quotes, \n, \r, \t and \f are escape sequences, a backslash escapes any other character, and a
+//     backslash at the end of a line continues the quoted text after the leading whitespace of the next line
+//   - outside of quotes, # starts a comment up to the end of the line
+func ParseArgFile(content string) []string {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	token := false
+	state := findNext
+
+	end := func() {
+		if token {
+			args = append(args, current.String())
+		}
+		current.Reset()
+		token = false
+		state = findNext
+	}
+
+	for _, r := range content {
+		switch state {
+		case findNext, skipLeadingSpace:
+			if isArgFileSpace(r) {
+				continue
+			}
+			if state == findNext {
+				state = inToken
+			} else {
+				state = inQuote
+			}
+		case inComment:
+			if r == '\n' || r == '\r' {
+				state = findNext
+			}
+			continue
+		case inEscape:
+			switch r {
+			case '\n', '\r':
+				state = skipLeadingSpace
+				continue
+			case 'n':
+				current.WriteRune('\n')
+			case 'r':
+				current.WriteRune('\r')
+			case 't':
+				current.WriteRune('\t')
+			case 'f':
+				current.WriteRune('\f')
+			default:
+				current.WriteRune(r)
+			}
+			state = inQuote
+			continue
+		}
+
+		switch {
+		case r == '\n' || r == '\r':
+			end()
+		case isArgFileSpace(r) && state == inToken:
+			end()
+		case r == '#' && state == inToken:
+			end()
+			state = inComment
+		case r == '\\' && state == inQuote:
+			state = inEscape
+		case (r == '"' || r == '\'') && state == inToken:
+			quote, token, state = r, true, inQuote
+		case r == quote && state == inQuote:
+			state = inToken
+		default:
+			current.WriteRune(r)
+			token = true
+		}
+	}
+	end()
+
+	return args
+}
+
+// isArgFileSpace returns true if r separates arguments in an argument file.
+func isArgFileSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\f' || r == '\n' || r == '\r'
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseArgFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "Lines and spaces",
+			content:  "-Xmx1G -Xss512K\n\t-XX:MaxMetaspaceSize=128M\r\n",
+			expected: []string{"-Xmx1G", "-Xss512K", "-XX:MaxMetaspaceSize=128M"},
+		},
+		{
+			name:     "Comments",
+			content:  "# Memory settings\n-Xmx1G # heap\n-Dhash='#1'\n",
+			expected: []string{"-Xmx1G", "-Dhash=#1"},
+		},
+		{
+			name:     "Quotes inside arguments",
+			content:  `-Dapp.name="My Application" '-Dquote="a b"' -Dempty=""`,
+			expected: []string{"-Dapp.name=My Application", `-Dquote="a b"`, "-Dempty="},
+		},
+		{
+			name:     "Escapes within quotes",
+			content:  `"-Dtab=a\tb" "-Dpath=C:\\app" -Dliteral=C:\app "-Dquote=\""`,
+			expected: []string{"-Dtab=a\tb", `-Dpath=C:\app`, `-Dliteral=C:\app`, `-Dquote="`},
+		},
+		{
+			name:     "Line continuation",
+			content:  "-cp \"lib/a.jar:\\\n     lib/b.jar\"\n",
+			expected: []string{"-cp", "lib/a.jar:lib/b.jar"},
+		},
+		{
+			name:     "Quotes do not span lines",
+			content:  "\"-Dunclosed=a\n-Xmx1G",
+			expected: []string{"-Dunclosed=a", "-Xmx1G"},
+		},
+		{
+			name:     "Empty",
+			content:  "\n# only a comment\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := ParseArgFile(tt.content); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseArgFile() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseFlagsArgFiles(t *testing.T) {
+	dir := t.TempDir()
+	memory := filepath.Join(dir, "memory.options")
+	jvm := filepath.Join(dir, "jvm.options")
+	if err := os.WriteFile(memory, []byte("-Xmx1G\n-Xss512K\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jvm, []byte("-XX:+UseG1GC\n@"+memory+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseFlags("-Dkey=value @" + jvm + " @@literal --disable-@files @" + memory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"-Dkey=value", "-XX:+UseG1GC", "-Xmx1G", "-Xss512K", "@literal", "--disable-@files", "@" + memory,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("ParseFlags() = %q, want %q", result, expected)
+	}
+}
+
+func TestParseFlagsArgFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.options")
+	b := filepath.Join(dir, "b.options")
+	if err := os.WriteFile(a, []byte("-Xmx1G @"+b), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("-Xss1M @"+a), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseFlags("@" + a); err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+	if _, err := ParseFlags("@" + filepath.Join(dir, "missing.options")); err == nil {
+		t.Error("Expected error for a missing argument file")
+	}
+}
@@ -2,65 +2,118 @@
 package parser
 
 import (
+	"fmt"
 	"strings"
-	"unicode"
 )
 
-// ParseFlags parses JVM flags from a string, handling basic quoting and escaping
-// This replaces the go-shellwords dependency with a simpler, more focused implementation
+// ParseFlags parses JVM flags from a string with SplitFlags and replaces argument files like @/app/jvm.options with
+// the flags they contain, see ExpandArgFiles
 func ParseFlags(input string) ([]string, error) {
-	if input == "" {
-		return nil, nil
+	flags, err := SplitFlags(input)
+	if err != nil {
+		return nil, err
 	}
+	return ExpandArgFiles(flags)
+}
 
+// SplitFlags splits JVM flags like the JVM splits $JAVA_TOOL_OPTIONS: flags are separated by whitespace, and single or
+// double quotes anywhere in a flag enclose whitespace and the other quote character, e.g. -Dname="My App" is the
+// single flag -Dname=My App. Quotes are removed, and there are no escape sequences, so a backslash is a regular
+// character. Like the JVM, SplitFlags fails for a quote that is not closed.
+func SplitFlags(input string) ([]string, error) {
 	var result []string
 	var current strings.Builder
-	var inQuotes bool
-	var quoteChar rune
-	var escaped bool
+	var quote rune
+	token := false
 
-	for i, r := range input {
+	for _, r := range input {
 		switch {
-		case escaped:
-			// Previous character was escape, add this character literally
+		case quote != 0 && r == quote:
+			// End of quoted section
+			quote = 0
+
+		case quote != 0:
+			// Quoted character, including whitespace
 			current.WriteRune(r)
-			escaped = false
-
-		case r == '\\':
-			// Escape character
-			escaped = true
-
-		case !inQuotes && (r == '"' || r == '\''):
-			// Start of quoted section
-			inQuotes = true
-			quoteChar = r
-
-		case inQuotes && r == quoteChar:
-			// End of quoted section - add even if empty
-			result = append(result, current.String())
-			current.Reset()
-			inQuotes = false
-			quoteChar = 0
-
-		case !inQuotes && unicode.IsSpace(r):
-			// Space outside quotes - end current argument
-			if current.Len() > 0 {
+
+		case r == '"' || r == '\'':
+			// Start of quoted section - the flag exists even if the quotes are empty
+			quote = r
+			token = true
+
+		case isSpace(r):
+			// Space outside quotes - end current flag
+			if token {
 				result = append(result, current.String())
 				current.Reset()
+				token = false
 			}
 
 		default:
 			// Regular character
 			current.WriteRune(r)
+			token = true
 		}
+	}
 
-		// Handle end of string
-		if i == len(input)-1 {
-			if current.Len() > 0 {
-				result = append(result, current.String())
-			}
-		}
+	if quote != 0 {
+		return nil, fmt.Errorf("unmatched quote in %s", input)
+	}
+	if token {
+		result = append(result, current.String())
 	}
 
 	return result, nil
 }
+
+// FormatFlags joins JVM flags with spaces, quoting flags so that SplitFlags returns them unchanged. A flag holding
+// whitespace or quotes is quoted after its first = if its name needs no quotes, e.g. -Dname="My App". As there are no
+// escape sequences, a quote character is enclosed in the other quote character.
+func FormatFlags(flags []string) string {
+	values := make([]string, 0, len(flags))
+	for _, f := range flags {
+		values = append(values, quoteFlag(f))
+	}
+	return strings.Join(values, " ")
+}
+
+// quoteFlag quotes a single flag for FormatFlags.
+func quoteFlag(f string) string {
+	if f != "" && !strings.ContainsFunc(f, needsQuotes) {
+		return f
+	}
+
+	var b strings.Builder
+	if name, value, ok := strings.Cut(f, "="); ok && !strings.ContainsFunc(name, needsQuotes) {
+		b.WriteString(name + "=")
+		f = value
+	}
+
+	quote := '"'
+	if strings.ContainsRune(f, '"') && !strings.ContainsRune(f, '\'') {
+		quote = '\''
+	}
+	b.WriteRune(quote)
+	for _, r := range f {
+		if r == quote {
+			// Close the quotes and continue in the other quotes, which enclose this quote character
+			b.WriteRune(quote)
+			quote = '"' + '\'' - quote
+			b.WriteRune(quote)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteRune(quote)
+
+	return b.String()
+}
+
+// needsQuotes returns true if r cannot appear in a flag without quotes.
+func needsQuotes(r rune) bool {
+	return isSpace(r) || r == '"' || r == '\''
+}
+
+// isSpace returns true if r separates flags, like isspace in the C locale the JVM uses.
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\v' || r == '\f' || r == '\r'
+}
@@ -61,9 +61,19 @@ func TestParseFlags(t *testing.T) {
 			},
 		},
 		{
-			name:     "Escaped quotes",
-			input:    `-Dvalue="She said \"Hello\""`,
-			expected: []string{`-Dvalue=She said "Hello"`},
+			name:     "Backslashes are no escape characters",
+			input:    `-Dvalue="She said \"Hello\"" -Dpath=C:\app\logs`,
+			expected: []string{`-Dvalue=She said \Hello\`, `-Dpath=C:\app\logs`},
+		},
+		{
+			name:     "Quotes inside flags",
+			input:    `-Dvalue="a b"c -D'name with space'=value -Dquote='"'`,
+			expected: []string{"-Dvalue=a bc", "-Dname with space=value", `-Dquote="`},
+		},
+		{
+			name:     "Whitespace separators",
+			input:    "-Xmx1G\t-Xss1M\n-Dname=\"a\tb\"",
+			expected: []string{"-Xmx1G", "-Xss1M", "-Dname=a\tb"},
 		},
 		{
 			name:     "Mixed quote types",
@@ -97,11 +107,6 @@ func TestParseFlagsEdgeCases(t *testing.T) {
 			input:    "   ",
 			expected: nil,
 		},
-		{
-			name:     "Unclosed quotes - should still work",
-			input:    `-Dvalue="unclosed quote`,
-			expected: []string{`-Dvalue=unclosed quote`},
-		},
 		{
 			name:     "Empty quoted string",
 			input:    `-Dvalue=""`,
@@ -127,3 +132,67 @@ func TestParseFlagsEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFlagsUnmatchedQuote(t *testing.T) {
+	for _, input := range []string{`-Dvalue="unclosed quote`, `-Xmx1G 'unclosed`, `-Dvalue="a'`} {
+		if _, err := ParseFlags(input); err == nil {
+			t.Errorf("ParseFlags(%q) expected error for unmatched quote", input)
+		}
+	}
+}
+
+func TestFormatFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    []string
+		expected string
+	}{
+		{
+			name:     "No quotes needed",
+			flags:    []string{"-Xmx1G", "-XX:+UseG1GC", `-Dpath=C:\app`, "-Dempty="},
+			expected: `-Xmx1G -XX:+UseG1GC -Dpath=C:\app -Dempty=`,
+		},
+		{
+			name:     "Whitespace in value",
+			flags:    []string{"-Dapp.name=My Application"},
+			expected: `-Dapp.name="My Application"`,
+		},
+		{
+			name:     "Whitespace in name",
+			flags:    []string{"-Dname with space=value"},
+			expected: `"-Dname with space=value"`,
+		},
+		{
+			name:     "Double quotes",
+			flags:    []string{`-Dgreeting=She said "Hello"`},
+			expected: `-Dgreeting='She said "Hello"'`,
+		},
+		{
+			name:     "Both quote characters",
+			flags:    []string{`-Dmixed=it's "quoted"`},
+			expected: `-Dmixed="it's "'"quoted"'`,
+		},
+		{
+			name:     "Empty flag",
+			flags:    []string{""},
+			expected: `""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := FormatFlags(tt.flags)
+			if result != tt.expected {
+				t.Errorf("FormatFlags() = %s, want %s", result, tt.expected)
+			}
+
+			roundTrip, err := SplitFlags(result)
+			if err != nil {
+				t.Fatalf("SplitFlags() error = %v", err)
+			}
+			if !reflect.DeepEqual(roundTrip, tt.flags) {
+				t.Errorf("SplitFlags(FormatFlags()) = %q, want %q", roundTrip, tt.flags)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Package profile provides named presets of memory calculation parameters for common application types.
+//
+// A profile only supplies defaults: every setting it contains can still be overridden by the
+// corresponding command line flag or environment variable. Besides the built-in profiles, users
+// can define their own profiles in a configuration file.
+package profile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile is a named preset of memory calculation parameters. Empty fields leave the regular defaults in place.
+type Profile struct {
+	// Name identifies the profile, e.g. "spring-boot".
+	Name string `json:"name,omitempty"`
+
+	// Description briefly explains what the profile is meant for.
+	Description string `json:"description,omitempty"`
+
+	// ThreadCount is the expected number of platform threads.
+	ThreadCount int `json:"threadCount,omitempty"`
+
+	// ClassLoadFactor is the share of the counted classes that are expected to be loaded.
+	ClassLoadFactor float64 `json:"classLoadFactor,omitempty"`
+
+	// CodeCacheSize is the reserved code cache size, e.g. "128M".
+	CodeCacheSize string `json:"codeCacheSize,omitempty"`
+
+	// DirectMemorySize is the maximum direct memory size, e.g. "64M".
+	DirectMemorySize string `json:"directMemorySize,omitempty"`
+
+	// HeadRoom is the headroom specification, e.g. "5" or "10%,min=128M".
+	HeadRoom string `json:"headRoom,omitempty"`
+
+	// InitialHeap is the initial heap policy, "max" or a share of the heap such as "25%".
+	InitialHeap string `json:"initialHeap,omitempty"`
+
+	// Options are additional JVM options, e.g. "-XX:+ExitOnOutOfMemoryError".
+	Options []string `json:"options,omitempty"`
+}
+
+// exitOnOutOfMemoryError lets the platform restart a JVM that ran out of memory instead of leaving it degraded.
+const exitOnOutOfMemoryError = "-XX:+ExitOnOutOfMemoryError"
+
+// Builtin holds the profiles shipped with the calculator, keyed by name.
+var Builtin = map[string]Profile{
+	"spring-boot": {
+		Name:            "spring-boot",
+		Description:     "Spring Boot web applications with an embedded servlet container",
+		ThreadCount:     250,
+		ClassLoadFactor: 0.5,
+		HeadRoom:        "5",
+		Options:         []string{exitOnOutOfMemoryError},
+	},
+	"quarkus": {
+		Name:             "quarkus",
+		Description:      "Quarkus applications on the JVM with Vert.x event loops",
+		ThreadCount:      100,
+		ClassLoadFactor:  0.3,
+		CodeCacheSize:    "128M",
+		DirectMemorySize: "64M",
+		Options:          []string{exitOnOutOfMemoryError},
+	},
+	"micronaut": {
+		Name:             "micronaut",
+		Description:      "Micronaut applications with the Netty HTTP server",
+		ThreadCount:      100,
+		ClassLoadFactor:  0.3,
+		CodeCacheSize:    "128M",
+		DirectMemorySize: "64M",
+		Options:          []string{exitOnOutOfMemoryError},
+	},
+	"batch": {
+		Name:            "batch",
+		Description:     "Batch jobs with few threads that use the whole heap",
+		ThreadCount:     50,
+		ClassLoadFactor: 0.35,
+		InitialHeap:     "max",
+		Options:         []string{exitOnOutOfMemoryError},
+	},
+	"low-latency": {
+		Name:             "low-latency",
+		Description:      "Latency sensitive services that avoid heap resizing and page faults",
+		ThreadCount:      250,
+		ClassLoadFactor:  0.35,
+		CodeCacheSize:    "256M",
+		DirectMemorySize: "128M",
+		HeadRoom:         "10%,min=128M",
+		InitialHeap:      "max",
+		Options:          []string{exitOnOutOfMemoryError, "-XX:+AlwaysPreTouch"},
+	},
+}
+
+// Lookup returns the profile with the given name. Custom profiles take precedence over built-in profiles of the
+// same name.
+func Lookup(name string, custom map[string]Profile) (Profile, error) {
+	if p, ok := custom[name]; ok {
+		if p.Name == "" {
+			p.Name = name
+		}
+		return p, nil
+	}
+	if p, ok := Builtin[name]; ok {
+		return p, nil
+	}
+
+	return Profile{}, fmt.Errorf("unknown profile %q, available profiles: %s",
+		name, strings.Join(Names(custom), ", "))
+}
+
+// Names returns the names of the built-in and the given custom profiles in sorted order.
+func Names(custom map[string]Profile) []string {
+	var names []string
+	for n := range Builtin {
+		names = append(names, n)
+	}
+	for n := range custom {
+		if _, ok := Builtin[n]; !ok {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
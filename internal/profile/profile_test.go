@@ -0,0 +1,58 @@
+package profile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLookupBuiltin(t *testing.T) {
+	for name := range Builtin {
+		p, err := Lookup(name, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", name, err)
+		}
+		if p.Name != name {
+			t.Errorf("Expected profile name %s, got %s", name, p.Name)
+		}
+		if p.ThreadCount <= 0 || p.ClassLoadFactor <= 0 || p.ClassLoadFactor > 1 {
+			t.Errorf("Expected profile %s to set thread count and class load factor, got %+v", name, p)
+		}
+	}
+}
+
+func TestLookupCustom(t *testing.T) {
+	custom := map[string]Profile{
+		"orders":      {ThreadCount: 400},
+		"spring-boot": {Name: "spring-boot", ThreadCount: 100},
+	}
+
+	p, err := Lookup("orders", custom)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p.Name != "orders" || p.ThreadCount != 400 {
+		t.Errorf("Expected custom profile orders with 400 threads, got %+v", p)
+	}
+
+	if p, _ = Lookup("spring-boot", custom); p.ThreadCount != 100 {
+		t.Errorf("Expected custom profile to take precedence over built-in profile, got %+v", p)
+	}
+}
+
+func TestLookupUnknown(t *testing.T) {
+	_, err := Lookup("jakarta", map[string]Profile{"orders": {}})
+	if err == nil {
+		t.Fatal("Expected error for unknown profile")
+	}
+	if !strings.Contains(err.Error(), "orders") || !strings.Contains(err.Error(), "spring-boot") {
+		t.Errorf("Expected error to list available profiles, got %v", err)
+	}
+}
+
+func TestNames(t *testing.T) {
+	expected := []string{"batch", "low-latency", "micronaut", "orders", "quarkus", "spring-boot"}
+	if names := Names(map[string]Profile{"orders": {}, "batch": {}}); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}